@@ -1,10 +1,14 @@
 package brazier
 
+import "time"
+
 // An Item is a key value pair saved in a bucket.
 type Item struct {
 	Key      string
 	Data     []byte
 	Children []Item
+	// UpdatedAt is the time of the last modification, zero if the backend doesn't record it.
+	UpdatedAt time.Time
 }
 
 // A Bucket manages a collection of items.
@@ -21,6 +25,28 @@ type Bucket interface {
 	Close() error
 }
 
+// An Iterator is a Bucket able to iterate over its items without loading them all in memory.
+type Iterator interface {
+	// Iterate calls fn for every item of the bucket, in the same order as Page.
+	// It stops at the first error returned by fn and returns it.
+	Iterate(fn func(*Item) error) error
+}
+
+// A Batcher is a Bucket able to save several items in a single write transaction.
+type Batcher interface {
+	// SaveBatch saves all the items at once, in order. Either all the items are saved or none.
+	SaveBatch(items []Item) error
+}
+
+// A ConditionalSaver is a Bucket able to save an item only if it wasn't modified
+// in the meantime.
+type ConditionalSaver interface {
+	// SaveIf saves the key value pair if the item was last modified at updatedAt,
+	// a missing item or an item whose modification time isn't recorded being expected
+	// with a zero time. It returns store.ErrConflict otherwise.
+	SaveIf(key string, data []byte, updatedAt time.Time) (*Item, error)
+}
+
 // A Backend is able to create buckets that can be used to store and fetch data.
 type Backend interface {
 	// Get a bucket managing the given path.
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/asdine/brazier/rpc"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// NewAdminCmd creates an "Admin" cli command
+func NewAdminCmd(a *app) *cobra.Command {
+	adminCmd := adminCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "admin",
+		Short: "Administrate a running server",
+		Long: `Administrate a running server.
+The server is reached through the socket, or through --host if set.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "info",
+		Short: "Show the version, uptime and listeners of the server",
+		RunE:  adminCmd.Info,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "Show the number of buckets and items and the size of the databases",
+		RunE:  adminCmd.Stats,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "compact",
+		Short: "Compact the databases of the server",
+		Long: `Compact the databases of the server to reclaim the unused space.
+Requests are blocked until the compaction is over.`,
+		RunE: adminCmd.Compact,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "backup DIR",
+		Short: "Backup the databases of the server",
+		Long: `Backup the databases of the server in the given directory.
+The server keeps accepting writes during the backup.`,
+		Example: "brazier admin backup /var/backups/brazier",
+		RunE:    adminCmd.Backup,
+	})
+
+	return &cmd
+}
+
+type adminCmd struct {
+	App *app
+}
+
+func (s *adminCmd) client() (proto.AdminClient, error) {
+	if s.App.conn == nil {
+		return nil, errors.New("No server is running")
+	}
+
+	return proto.NewAdminClient(s.App.conn), nil
+}
+
+func (s *adminCmd) Info(cmd *cobra.Command, args []string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	info, err := client.Info(context.Background(), new(proto.Empty))
+	if err != nil {
+		return rpc.StoreError(err)
+	}
+
+	fmt.Fprintf(s.App.Out, "Version: %s\n", info.Version)
+	fmt.Fprintf(s.App.Out, "Started: %s\n", time.Unix(info.StartTime, 0).Format(time.RFC3339))
+	fmt.Fprintf(s.App.Out, "Uptime: %s\n", time.Duration(info.Uptime)*time.Second)
+	for _, l := range info.Listeners {
+		fmt.Fprintf(s.App.Out, "Listener %s: %s\n", l.Name, l.Address)
+	}
+
+	return nil
+}
+
+func (s *adminCmd) Stats(cmd *cobra.Command, args []string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	stats, err := client.Stats(context.Background(), new(proto.Empty))
+	if err != nil {
+		return rpc.StoreError(err)
+	}
+
+	fmt.Fprintf(s.App.Out, "Buckets: %d\n", stats.Buckets)
+	fmt.Fprintf(s.App.Out, "Items: %d\n", stats.Items)
+	for _, db := range stats.Databases {
+		fmt.Fprintf(s.App.Out, "Database %s: %s (%d bytes)\n", db.Name, db.Path, db.Size)
+	}
+
+	return nil
+}
+
+func (s *adminCmd) Compact(cmd *cobra.Command, args []string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.Compact(context.Background(), new(proto.Empty))
+	if err != nil {
+		return rpc.StoreError(err)
+	}
+
+	for _, db := range c.Databases {
+		fmt.Fprintf(s.App.Out, "Database %s compacted: %d -> %d bytes\n", db.Name, db.SizeBefore, db.SizeAfter)
+	}
+
+	return nil
+}
+
+func (s *adminCmd) Backup(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("Directory is missing")
+	}
+
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	stats, err := client.Stats(context.Background(), new(proto.Empty))
+	if err != nil {
+		return rpc.StoreError(err)
+	}
+
+	err = os.MkdirAll(args[0], 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, db := range stats.Databases {
+		path := filepath.Join(args[0], filepath.Base(db.Path))
+		n, err := s.backup(client, db.Name, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(s.App.Out, "Database %s saved to %s (%d bytes)\n", db.Name, path, n)
+	}
+
+	return nil
+}
+
+func (s *adminCmd) backup(client proto.AdminClient, name, path string) (int64, error) {
+	stream, err := client.Backup(context.Background(), &proto.BackupRequest{Database: name})
+	if err != nil {
+		return 0, rpc.StoreError(err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n int64
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, rpc.StoreError(err)
+		}
+
+		written, err := f.Write(chunk.Data)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, f.Sync()
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin(t *testing.T) {
+	t.Run("NotRunning", func(t *testing.T) {
+		app, cleanup := testableApp(t)
+		defer cleanup()
+
+		s := adminCmd{App: app}
+		err := s.Info(nil, nil)
+		require.Error(t, err)
+	})
+
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	_, err := app.Store.Put("a/b", []byte(`"data"`))
+	require.NoError(t, err)
+
+	s := adminCmd{App: app}
+
+	t.Run("Info", func(t *testing.T) {
+		app.Out = new(bytes.Buffer)
+		err := s.Info(nil, nil)
+		require.NoError(t, err)
+		out := app.Out.(*bytes.Buffer).String()
+		require.Contains(t, out, "Version: ")
+		require.Contains(t, out, "Listener socket: ")
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		app.Out = new(bytes.Buffer)
+		err := s.Stats(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "Buckets: 1\nItems: 1\n", app.Out.(*bytes.Buffer).String())
+	})
+
+	t.Run("Backup", func(t *testing.T) {
+		app.Out = new(bytes.Buffer)
+		err := s.Backup(nil, nil)
+		require.Error(t, err)
+
+		dir := filepath.Join(app.DataDir, "backup")
+		err = s.Backup(nil, []string{dir})
+		require.NoError(t, err)
+		fi, err := os.Stat(dir)
+		require.NoError(t, err)
+		require.True(t, fi.IsDir())
+	})
+}
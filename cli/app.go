@@ -2,16 +2,17 @@ package cli
 
 import (
 	"errors"
+	"fmt"
 	"io"
-	"net"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/asdine/brazier/client"
 	"github.com/asdine/brazier/config"
-	"github.com/asdine/brazier/rpc/proto"
 	"github.com/asdine/brazier/store"
 	"github.com/asdine/brazier/store/boltdb"
+	"github.com/asdine/brazier/tlsutil"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 )
@@ -21,6 +22,7 @@ const (
 	defaultDataDir    = ".brazier"
 	defaultSocketName = "brazier.sock"
 	registryDB        = "registry.db"
+	profilesFile      = "profiles.yml"
 )
 
 // App is the main cli application
@@ -32,7 +34,57 @@ type app struct {
 	DataDir    string
 	SocketPath string
 	Config     config.Config
-	conn       *grpc.ClientConn
+	Client     clientConfig
+	// Profile is the name of the selected profile.
+	Profile string
+	// ProfilesPath is the path of the profiles file, $HOME/.brazier/profiles.yml by default.
+	ProfilesPath string
+	conn         *grpc.ClientConn
+}
+
+// Environment variables used when the corresponding flags are not set.
+const (
+	envHost     = "BRAZIER_HOST"
+	envToken    = "BRAZIER_TOKEN"
+	envProfile  = "BRAZIER_PROFILE"
+	envProfiles = "BRAZIER_PROFILES"
+)
+
+// defaultDialTimeout is the maximum duration of the connection to a remote server
+// when no timeout is set.
+const defaultDialTimeout = 10 * time.Second
+
+// clientConfig holds the settings used to connect to a server.
+type clientConfig struct {
+	// Socket is the socket of a local server, used instead of the one of the data directory.
+	Socket     string
+	Host       string
+	TLS        bool
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	Token      string
+	Timeout    time.Duration
+
+	// hostFromEnv is true if the host was read from the environment.
+	hostFromEnv bool
+}
+
+// loadEnv reads the settings that were not set from the environment.
+func (c *clientConfig) loadEnv() {
+	if c.Host == "" {
+		c.Host = os.Getenv(envHost)
+		c.hostFromEnv = c.Host != ""
+	}
+
+	if c.Token == "" {
+		c.Token = os.Getenv(envToken)
+	}
+}
+
+// tlsEnabled returns true if the connection to the host must use TLS.
+func (c *clientConfig) tlsEnabled() bool {
+	return c.TLS || c.CACert != "" || c.ClientCert != ""
 }
 
 // Run runs the root command
@@ -42,40 +94,52 @@ func (a *app) Run(cmd *cobra.Command, args []string) {
 
 // PreRun runs the root command
 func (a *app) PreRun(cmd *cobra.Command, args []string) error {
+	// completion requests only open the store when paths are completed, see completePath
+	if cmd != nil && (cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd) {
+		return nil
+	}
+
 	err := a.initConfig()
 	if err != nil {
 		return err
 	}
 
-	err = a.initDataDir()
+	a.Client.loadEnv()
+	err = a.applyProfile()
 	if err != nil {
 		return err
 	}
 
+	if a.Client.Host != "" {
+		return a.initRPCCli()
+	}
+
+	if a.Client.Socket != "" {
+		a.SocketPath = a.Client.Socket
+		return a.initRPCCli()
+	}
+
+	err = a.initDataDir()
+	if err != nil {
+		return fmt.Errorf("No server or data directory available (%s), use --host or $%s to connect to a remote server or --data-dir to select a data directory", err, envHost)
+	}
+
 	a.SocketPath = filepath.Join(a.DataDir, defaultSocketName)
 
 	if a.serverIsLaunched() {
-		client, err := a.rpcClient()
-		if err != nil {
-			return err
-		}
-
-		a.Cli = &rpcCli{
-			App:    a,
-			Client: client,
-		}
-		return nil
+		return a.initRPCCli()
 	}
 
 	if a.Store == nil {
 		backend, err := boltdb.NewBackend(filepath.Join(a.DataDir, defaultDBName))
 		if err != nil {
-			return err
+			return fmt.Errorf("Can't open the data directory %s: %s", a.DataDir, err)
 		}
 
 		registry, err := boltdb.NewRegistry(filepath.Join(a.DataDir, registryDB), backend)
 		if err != nil {
-			return err
+			backend.Close()
+			return fmt.Errorf("Can't open the data directory %s: %s", a.DataDir, err)
 		}
 
 		a.Store = store.NewStore(registry)
@@ -96,14 +160,18 @@ func (a *app) PostRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if a.Store != nil {
-		err = a.Store.Close()
-		if err != nil {
-			return err
-		}
+	return a.closeStore()
+}
+
+// closeStore closes the local store, releasing the lock of the data directory.
+func (a *app) closeStore() error {
+	if a.Store == nil {
+		return nil
 	}
 
-	return nil
+	err := a.Store.Close()
+	a.Store = nil
+	return err
 }
 
 // manages brazier config
@@ -114,22 +182,114 @@ func (a *app) initConfig() error {
 	return nil
 }
 
-// manages brazier config
-func (a *app) initDataDir() error {
-	if a.DataDir == "" {
-		// check in the local directory
-		fi, err := os.Stat(defaultDataDir)
-		if err == nil && fi.Mode().IsDir() {
-			a.DataDir = defaultDataDir
+// profilesPath returns the path of the profiles file.
+func (a *app) profilesPath() string {
+	if a.ProfilesPath != "" {
+		return a.ProfilesPath
+	}
+
+	if p := os.Getenv(envProfiles); p != "" {
+		return p
+	}
+
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, defaultDataDir, profilesFile)
+	}
+
+	return ""
+}
+
+// loadProfiles reads the profiles file.
+func (a *app) loadProfiles() (*config.Profiles, error) {
+	path := a.profilesPath()
+	if path == "" {
+		return nil, errors.New("Can't find $HOME directory")
+	}
+
+	profiles, err := config.LoadProfiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read the profiles file %s: %s", path, err)
+	}
+
+	return profiles, nil
+}
+
+// applyProfile uses the selected profile, or the current one, to fill the connection settings.
+// A selected profile replaces the host set in the environment and can't be used with --host
+// or --data-dir. The current profile is ignored if a host or a data directory was set, or if
+// the profiles can't be read. Other settings set with flags or environment variables take precedence.
+func (a *app) applyProfile() error {
+	name := a.Profile
+	if name == "" {
+		name = os.Getenv(envProfile)
+	}
+
+	if name == "" {
+		if a.Client.Host != "" || a.DataDir != "" || a.profilesPath() == "" {
 			return nil
 		}
 
-		// check in the home directory
-		home := os.Getenv("HOME")
-		if home == "" {
-			return errors.New("Can't find $HOME directory")
+		// no profile was selected, the commands don't depend on the profiles file
+		profiles, err := a.loadProfiles()
+		if err != nil || profiles.Current == "" {
+			return nil
 		}
-		a.DataDir = filepath.Join(home, defaultDataDir)
+
+		return a.useProfile(profiles, profiles.Current)
+	}
+
+	if a.DataDir != "" || (a.Client.Host != "" && !a.Client.hostFromEnv) {
+		return fmt.Errorf("The profile \"%s\" can't be used with --host or --data-dir", name)
+	}
+
+	profiles, err := a.loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	if a.Client.hostFromEnv {
+		a.Client.Host = ""
+		a.Client.hostFromEnv = false
+	}
+
+	return a.useProfile(profiles, name)
+}
+
+// useProfile fills the connection settings with the given profile.
+func (a *app) useProfile(profiles *config.Profiles, name string) error {
+	p, ok := profiles.Profiles[name]
+	if !ok {
+		return fmt.Errorf("Unknown profile \"%s\"", name)
+	}
+
+	a.DataDir = p.DataDir
+	a.Client.Socket = p.Socket
+	a.Client.Host = p.Host
+	a.Client.TLS = a.Client.TLS || p.TLS
+	if a.Client.CACert == "" {
+		a.Client.CACert = p.CACert
+	}
+	if a.Client.ClientCert == "" {
+		a.Client.ClientCert = p.ClientCert
+	}
+	if a.Client.ClientKey == "" {
+		a.Client.ClientKey = p.ClientKey
+	}
+	if a.Client.Token == "" {
+		a.Client.Token = p.Token
+	}
+	if a.Client.Timeout == 0 {
+		a.Client.Timeout = time.Duration(p.Timeout)
+	}
+
+	return nil
+}
+
+// manages brazier config
+func (a *app) initDataDir() error {
+	err := a.findDataDir()
+	if err != nil {
+		return err
 	}
 
 	fi, err := os.Stat(a.DataDir)
@@ -144,23 +304,113 @@ func (a *app) initDataDir() error {
 	return nil
 }
 
+// findDataDir selects the data directory without creating it.
+func (a *app) findDataDir() error {
+	if a.DataDir != "" {
+		return nil
+	}
+
+	// check in the local directory
+	fi, err := os.Stat(defaultDataDir)
+	if err == nil && fi.Mode().IsDir() {
+		a.DataDir = defaultDataDir
+		return nil
+	}
+
+	// check in the home directory
+	home := os.Getenv("HOME")
+	if home == "" {
+		return errors.New("Can't find $HOME directory")
+	}
+	a.DataDir = filepath.Join(home, defaultDataDir)
+	return nil
+}
+
+// initServerCli connects to the selected server without ever opening the local store,
+// which is locked by a server running on the data directory even if it doesn't answer.
+// Without a remote host, the connection is only set if a local server answers on the socket.
+func (a *app) initServerCli(cmd *cobra.Command, args []string) error {
+	err := a.initConfig()
+	if err != nil {
+		return err
+	}
+
+	a.Client.loadEnv()
+	err = a.applyProfile()
+	if err != nil {
+		return err
+	}
+
+	if a.Client.Host != "" {
+		return a.initRPCCli()
+	}
+
+	if a.Client.Socket != "" {
+		a.SocketPath = a.Client.Socket
+		a.DataDir = filepath.Dir(a.Client.Socket)
+	} else {
+		err = a.findDataDir()
+		if err != nil {
+			return err
+		}
+		a.SocketPath = filepath.Join(a.DataDir, defaultSocketName)
+	}
+
+	if a.serverIsLaunched() {
+		return a.initRPCCli()
+	}
+
+	return nil
+}
+
+// serverIsLaunched returns true if a server answers on the socket. A socket left
+// by a server that didn't stop properly is ignored.
 func (a *app) serverIsLaunched() bool {
 	_, err := os.Stat(a.SocketPath)
-	return err == nil
+	if err != nil {
+		return false
+	}
+
+	return probeSocket(a.SocketPath, socketProbeTimeout) == nil
 }
 
-func (a *app) rpcClient() (proto.BucketClient, error) {
-	conn, err := grpc.Dial("",
-		grpc.WithInsecure(),
-		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
-			sock, err := net.DialTimeout("unix", a.SocketPath, timeout)
-			return sock, err
-		}),
-	)
+func (a *app) initRPCCli() error {
+	c, err := a.rpcClient()
 	if err != nil {
-		return nil, err
+		if a.Client.Host != "" {
+			return fmt.Errorf("Can't connect to %s: %s", a.Client.Host, err)
+		}
+		return fmt.Errorf("Can't connect to the server through %s: %s", a.SocketPath, err)
+	}
+
+	a.conn = c.Conn()
+	a.Cli = &rpcCli{
+		App:    a,
+		Client: c,
+	}
+	return nil
+}
+
+func (a *app) rpcClient() (*client.Client, error) {
+	opts := []client.Option{client.WithTimeout(a.Client.Timeout)}
+
+	if a.Client.Host == "" {
+		return client.DialSocket(a.SocketPath, opts...)
+	}
+
+	dialTimeout := a.Client.Timeout
+	if dialTimeout == 0 {
+		dialTimeout = defaultDialTimeout
+	}
+	opts = append(opts, client.WithDialTimeout(dialTimeout), client.WithToken(a.Client.Token))
+
+	if a.Client.tlsEnabled() {
+		cfg, err := tlsutil.ClientConfig(a.Client.CACert, a.Client.ClientCert, a.Client.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithTLS(cfg))
 	}
 
-	a.conn = conn
-	return proto.NewBucketClient(conn), nil
+	return client.Dial(a.Client.Host, opts...)
 }
@@ -3,15 +3,21 @@ package cli
 import (
 	"bytes"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/asdine/brazier/mock"
 	"github.com/asdine/brazier/rpc"
 	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/tlsutil"
+	"github.com/asdine/brazier/tlsutil/tlstest"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func testableApp(t *testing.T) (*app, func()) {
@@ -23,6 +29,8 @@ func testableApp(t *testing.T) (*app, func()) {
 		Store:      store.NewStore(mock.NewRegistry(mock.NewBackend())),
 		DataDir:    dir,
 		SocketPath: filepath.Join(dir, defaultSocketName),
+		// ignore the profiles of the user
+		ProfilesPath: filepath.Join(dir, profilesFile),
 	}
 
 	a.PreRun(nil, nil)
@@ -40,12 +48,12 @@ func testableAppRPC(t *testing.T) (*app, func()) {
 	app.Config.RPC.Address = ":"
 
 	s := serverCmd{
-		App:              app,
-		HTTPServerFunc:   mock.NewServer,
-		RPCServerFunc:    mock.NewServer,
-		SocketServerFunc: rpc.NewServer,
-		c:                make(chan os.Signal, 1),
+		App:            app,
+		HTTPServerFunc: mock.NewServer,
+		RPCServerFunc:  mock.NewServer,
+		c:              make(chan os.Signal, 1),
 	}
+	s.SocketServerFunc = s.newSocketServer
 
 	servers, err := s.createServers()
 	require.NoError(t, err)
@@ -66,6 +74,40 @@ func testableAppRPC(t *testing.T) (*app, func()) {
 	}
 }
 
+func testableAppTLS(t *testing.T) (*app, func()) {
+	app, cleanup := testableApp(t)
+
+	ca, err := tlstest.NewCA(app.DataDir)
+	require.NoError(t, err)
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+	clientCertFile, clientKeyFile, err := ca.Issue("client")
+	require.NoError(t, err)
+
+	kp, err := tlsutil.NewKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	cfg, err := tlsutil.ServerConfig(kp, ca.CertFile)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(app.Store, rpc.WithGRPCOptions(grpc.Creds(credentials.NewTLS(cfg))))
+	go srv.Serve(l)
+
+	app.Client.Host = l.Addr().String()
+	app.Client.CACert = ca.CertFile
+	app.Client.ClientCert = clientCertFile
+	app.Client.ClientKey = clientKeyFile
+	err = app.PreRun(nil, nil)
+	require.NoError(t, err)
+
+	return app, func() {
+		cleanup()
+		srv.Stop(time.Second)
+	}
+}
+
 func TestAppDataDir(t *testing.T) {
 	app, cleanup := testableApp(t)
 	defer cleanup()
@@ -104,3 +146,70 @@ func TestAppDataDir(t *testing.T) {
 	require.True(t, fi.Mode().IsDir())
 	require.Equal(t, os.FileMode(0755), fi.Mode().Perm())
 }
+
+func TestAppHost(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(app.Store, rpc.WithToken("secret"))
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+
+	os.Setenv(envHost, l.Addr().String())
+	defer os.Unsetenv(envHost)
+
+	t.Run("Token", func(t *testing.T) {
+		a := *app
+		a.Client = clientConfig{Timeout: time.Second}
+		os.Setenv(envToken, "secret")
+		defer os.Unsetenv(envToken)
+
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, l.Addr().String(), a.Client.Host)
+		require.IsType(t, new(rpcCli), a.Cli)
+		defer a.conn.Close()
+
+		err = a.Cli.Create("a/")
+		require.NoError(t, err)
+	})
+
+	t.Run("WrongToken", func(t *testing.T) {
+		a := *app
+		a.Client = clientConfig{Token: "wrong", Timeout: time.Second}
+
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.conn.Close()
+
+		err = a.Cli.Create("b/")
+		require.Error(t, err)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		a := *app
+		a.Client = clientConfig{Host: "127.0.0.1:1", Timeout: 50 * time.Millisecond}
+
+		err := a.PreRun(nil, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Can't connect to 127.0.0.1:1")
+	})
+}
+
+func TestAppNoDataDir(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	err = ioutil.WriteFile(path, nil, 0644)
+	require.NoError(t, err)
+
+	a := app{DataDir: path}
+	err = a.PreRun(nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "No server or data directory available")
+}
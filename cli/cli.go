@@ -1,18 +1,37 @@
 package cli
 
 import (
+	"io"
 	"strings"
+	"time"
 
 	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/json"
+	"github.com/asdine/brazier/store"
+	"golang.org/x/net/context"
 )
 
 // Cli handles command line requests
 type Cli interface {
 	Create(path string) error
 	Put(path string, data []byte) error
-	Get(path string, recursive bool) ([]byte, error)
+	PutIf(path string, data []byte, updatedAt time.Time) error
+	Get(w io.Writer, path string, recursive bool) error
+	Item(path string) (*brazier.Item, error)
 	Delete(path string) error
+	Bulk() (BulkWriter, error)
+	Walk(path string, recursive bool, fn store.WalkFunc) error
+	WalkDepth(path string, depth int, fn store.WalkFunc) error
+	Entries(path string) ([]brazier.Item, error)
+	Buckets(path string) ([]brazier.Item, error)
+}
+
+// A BulkWriter saves large amounts of items efficiently.
+// Items that can't be saved are reported by Close, Abort drops the buffered items.
+type BulkWriter interface {
+	Put(path string, data []byte) error
+	Close() (*store.BulkResult, error)
+	Abort()
 }
 
 type cli struct {
@@ -30,7 +49,14 @@ func (c *cli) Put(path string, data []byte) error {
 	return err
 }
 
-func (c *cli) Get(path string, recursive bool) ([]byte, error) {
+func (c *cli) PutIf(path string, data []byte, updatedAt time.Time) error {
+	data = json.ToValidJSON(data)
+
+	_, err := c.App.Store.PutIf(path, data, updatedAt)
+	return err
+}
+
+func (c *cli) Get(w io.Writer, path string, recursive bool) error {
 	var err error
 	var data []byte
 
@@ -44,26 +70,99 @@ func (c *cli) Get(path string, recursive bool) ([]byte, error) {
 		}
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		data, err = json.MarshalListPretty(items)
 	} else {
 		item, err := c.App.Store.Get(path)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		data, err = json.PrettyPrintRaw(item.Data)
 	}
 
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeTree writes the content of the bucket as an indented JSON list, one node at a time,
+// without loading the whole bucket in memory. The output is the same as MarshalListPretty.
+func writeTree(w io.Writer, c Cli, path string, recursive bool) error {
+	tw := json.NewTreeWriter(w)
+
+	err := c.Walk(path, recursive, func(p string, depth int, item *brazier.Item) error {
+		if strings.HasSuffix(item.Key, "/") {
+			return tw.WriteBucket(depth, item.Key)
+		}
+
+		return tw.WriteItem(depth, item.Key, item.Data)
+	})
+	if err != nil {
+		return err
 	}
 
-	return append(data, '\n'), nil
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (c *cli) Item(path string) (*brazier.Item, error) {
+	return c.App.Store.Get(path)
 }
 
 func (c *cli) Delete(path string) error {
 	return c.App.Store.Delete(path)
 }
+
+func (c *cli) Bulk() (BulkWriter, error) {
+	return &bulkWriter{c.App.Store.NewBulkWriter(context.Background(), store.DefaultBulkSize)}, nil
+}
+
+// bulkWriter converts the values to valid JSON before saving them, like the gRPC server does.
+type bulkWriter struct {
+	*store.BulkWriter
+}
+
+func (b *bulkWriter) Put(path string, data []byte) error {
+	return b.BulkWriter.Put(path, json.ToValidJSON(data))
+}
+
+func (c *cli) Walk(path string, recursive bool, fn store.WalkFunc) error {
+	return c.App.Store.Walk(path, recursive, fn)
+}
+
+func (c *cli) WalkDepth(path string, depth int, fn store.WalkFunc) error {
+	return c.App.Store.Walk(path, true, store.LimitDepth(depth, fn))
+}
+
+func (c *cli) Entries(path string) ([]brazier.Item, error) {
+	items, err := c.App.Store.List(path, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := c.App.Store.Children(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range buckets {
+		buckets[i].Children = nil
+	}
+
+	return append(items, buckets...), nil
+}
+
+func (c *cli) Buckets(path string) ([]brazier.Item, error) {
+	return c.App.Store.Children(path)
+}
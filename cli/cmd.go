@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/asdine/brazier/query"
 	"github.com/spf13/cobra"
 )
 
@@ -30,10 +32,33 @@ func New() *cobra.Command {
 	cmd.AddCommand(NewPutCmd(&a))
 	cmd.AddCommand(NewGetCmd(&a, false))
 	cmd.AddCommand(NewDeleteCmd(&a))
+	cmd.AddCommand(NewEditCmd(&a))
+	cmd.AddCommand(NewLsCmd(&a))
+	cmd.AddCommand(NewTreeCmd(&a))
+	cmd.AddCommand(NewImportCmd(&a))
+	cmd.AddCommand(NewExportCmd(&a))
+	cmd.AddCommand(NewDiffCmd(&a))
+	cmd.AddCommand(NewEnvCmd(&a))
+	cmd.AddCommand(NewExecCmd(&a))
+	cmd.AddCommand(NewTemplateCmd(&a))
+	cmd.AddCommand(NewShellCmd(&a))
+	cmd.AddCommand(NewCompactCmd(&a))
 	cmd.AddCommand(NewServerCmd(&a))
+	cmd.AddCommand(NewAdminCmd(&a))
+	cmd.AddCommand(NewProfileCmd(&a))
+	cmd.AddCommand(NewCompletionCmd(&a))
+	setPathCompletions(&cmd, &a)
 
 	cmd.PersistentFlags().StringVar(&a.ConfigPath, "config", "", "config file")
 	cmd.PersistentFlags().StringVar(&a.DataDir, "data-dir", "", "data directory (default $HOME/.brazier)")
+	cmd.PersistentFlags().StringVar(&a.Profile, "profile", "", "connection profile, the current one by default (default $BRAZIER_PROFILE)")
+	cmd.PersistentFlags().StringVar(&a.Client.Host, "host", "", "gRPC address of a remote server (default $BRAZIER_HOST)")
+	cmd.PersistentFlags().BoolVar(&a.Client.TLS, "tls", false, "use TLS to connect to the remote server")
+	cmd.PersistentFlags().StringVar(&a.Client.CACert, "ca-cert", "", "CA file used to verify the remote server certificate, implies --tls")
+	cmd.PersistentFlags().StringVar(&a.Client.ClientCert, "client-cert", "", "client certificate file used to authenticate to the remote server, implies --tls")
+	cmd.PersistentFlags().StringVar(&a.Client.ClientKey, "client-key", "", "client key file")
+	cmd.PersistentFlags().StringVar(&a.Client.Token, "token", "", "token used to authenticate to the remote server (default $BRAZIER_TOKEN)")
+	cmd.PersistentFlags().DurationVar(&a.Client.Timeout, "timeout", 0, "maximum duration of the connection to the server, of every request and of the wait for every streamed item, 0 for no limit on requests")
 	return &cmd
 }
 
@@ -65,57 +90,60 @@ brazier create food/drinks/sodas`,
 	return &cmd
 }
 
-// NewSaveCmd creates a "Save" cli command
-func NewPutCmd(a *app) *cobra.Command {
-	cmd := cobra.Command{
-		Use:   "put PATH",
-		Short: "Set or replace a value in a bucket",
-		Long: `Set or replace a value in a bucket. A value can be anything.
-JSON values are automatically detected.`,
-		Example: `brazier put friends/john/phone 555-666
-brazier put users/1 '{"username": "john"}'`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
-				return errors.New("Wrong number of arguments")
-			}
-
-			err := a.Cli.Put(args[0], []byte(args[1]))
-			if err != nil {
-				return err
-			}
-
-			fmt.Fprintf(a.Out, "Item \"%s\" successfully saved.\n", args[0])
-			return nil
-		},
-	}
-
-	return &cmd
-}
-
 // NewGetCmd creates a "Get" cli command
 func NewGetCmd(a *app, recByDefault bool) *cobra.Command {
 	var recursive bool
+	var format, expr string
 
 	cmd := cobra.Command{
 		Use:   "get PATH",
 		Short: "Get a value from a key or list bucket content",
-		Long:  `Get a value from a key or list bucket content.`,
+		Long: `Get a value from a key or list bucket content.
+
+The output format is selected with -o:
+  json      indented JSON, a bucket being a list of key and value objects
+  compact   same as json, without indentation
+  raw       strings without quotes and other values as compact JSON, one value per line for a bucket
+  yaml      same as json, converted to YAML
+  table     one row per item, the columns being the fields of the values
+  ndjson    one object per line, holding the path of the item and its value
+  keys      the fields of an object, or the paths of the items and buckets of a bucket
+
+The formats are supported by get, in the command line and in the shell. The ls and tree
+commands only write listings meant to be read, use get -o keys or -o ndjson in scripts.
+
+The value can be filtered with a jq-style query given with -q, a bucket being queried
+as the list of key and value objects of the json format. Queries support field access
+(.name), indexes and slices (.[0], .[1:3]), iteration (.[]), pipes, comparisons,
+object and array construction and the select, map, has, length, keys, type, not and
+empty functions. Each result is written in the json, compact, ndjson, raw or yaml format.`,
+		Example: `brazier get users/john
+brazier get config/name -o raw
+brazier get users/ -o table
+brazier get users/ -r -o keys
+brazier get config/app -q '.servers[0].host'
+brazier get users/ -q '.[] | select(.value.age > 30) | .key' -o raw`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New("Wrong number of arguments")
 			}
 
-			out, err := a.Cli.Get(args[0], recursive)
-			if err != nil {
-				return err
+			if expr != "" {
+				q, err := query.Parse(expr)
+				if err != nil {
+					return err
+				}
+
+				return printQuery(a.Out, a.Cli, args[0], recursive, format, q)
 			}
 
-			_, err = a.Out.Write(out)
-			return err
+			return printOutput(a.Out, a.Cli, args[0], recursive, format)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&recursive, "recursive", "r", recByDefault, "display all the items recursively from the given bucket path.")
+	cmd.Flags().StringVarP(&format, "output", "o", formatJSON, "output format: "+strings.Join(outputFormats, ", "))
+	cmd.Flags().StringVarP(&expr, "query", "q", "", "jq-style query applied to the value")
 
 	return &cmd
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/asdine/brazier/store"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,6 +45,13 @@ func TestCliDelete(t *testing.T) {
 	testDelete(t, app)
 }
 
+func TestCliPutBulk(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testPutBulk(t, app)
+}
+
 func TestCliRPCCreate(t *testing.T) {
 	app, cleanup := testableAppRPC(t)
 	defer cleanup()
@@ -79,6 +87,33 @@ func TestCliRPCDelete(t *testing.T) {
 	testDelete(t, app)
 }
 
+func TestCliRPCPutBulk(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testPutBulk(t, app)
+}
+
+func TestCliTLS(t *testing.T) {
+	tests := map[string]func(*testing.T, *app){
+		"Create":       testCreate,
+		"Save":         testSave,
+		"Get":          testGet,
+		"GetListItems": testGetListItems,
+		"Delete":       testDelete,
+		"PutBulk":      testPutBulk,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			app, cleanup := testableAppTLS(t)
+			defer cleanup()
+
+			test(t, app)
+		})
+	}
+}
+
 func testCreate(t *testing.T, app *app) {
 	out := app.Out.(*bytes.Buffer)
 
@@ -106,6 +141,35 @@ func testSave(t *testing.T, app *app) {
 	require.Equal(t, "Item \"my bucket/my key\" successfully saved.\n", out.String())
 }
 
+func testPutBulk(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	s := NewPutCmd(app)
+	g := NewGetCmd(app, false)
+
+	err := s.RunE(nil, []string{"a/b", "1", "a/c"})
+	require.EqualError(t, err, "Wrong number of arguments")
+
+	err = s.RunE(nil, []string{"a/b", "1", "a/c/d", "hello", "a/b", "2"})
+	require.NoError(t, err)
+	require.Equal(t, "3 items successfully saved.\n", out.String())
+
+	out.Reset()
+	err = g.RunE(nil, []string{"a/b"})
+	require.NoError(t, err)
+	require.Equal(t, "2\n", out.String())
+
+	out.Reset()
+	err = g.RunE(nil, []string{"a/c/d"})
+	require.NoError(t, err)
+	require.Equal(t, "\"hello\"\n", out.String())
+
+	out.Reset()
+	err = s.RunE(nil, []string{"a/e", "1", "a/", "2"})
+	require.EqualError(t, err, "1 items couldn't be saved")
+	require.Equal(t, "1 items successfully saved.\nItem \"a/\" failed: forbidden\n", out.String())
+}
+
 func testGet(t *testing.T, app *app) {
 	out := app.Out.(*bytes.Buffer)
 
@@ -169,7 +233,7 @@ func testGetListItems(t *testing.T, app *app) {
 
 	out.Reset()
 	err = g.RunE(nil, []string{"some bucket/"})
-	require.Error(t, err)
+	require.Equal(t, store.ErrNotFound, err)
 
 	out.Reset()
 	err = gr.RunE(nil, []string{"test/"})
@@ -199,5 +263,5 @@ func testDelete(t *testing.T, app *app) {
 	require.Equal(t, "Item \"a/b/c/d\" successfully deleted.\n", out.String())
 
 	err = d.RunE(nil, []string{"a/b/c/d"})
-	require.Error(t, err)
+	require.Equal(t, store.ErrNotFound, err)
 }
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/spf13/cobra"
+)
+
+// NewCompactCmd creates a "Compact" cli command
+func NewCompactCmd(a *app) *cobra.Command {
+	compactCmd := compactCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "compact",
+		Short: "Compact the databases to reclaim the unused space",
+		Long: `Compact the databases to reclaim the unused space.
+Each database of the data directory is copied to a new file which replaces the original
+one once its content is verified.
+If a server is running, the compaction is done by the server through the admin service.`,
+		// the databases are locked by the server, even if it doesn't answer
+		PersistentPreRunE: a.initServerCli,
+		RunE:              compactCmd.Compact,
+	}
+
+	return &cmd
+}
+
+type compactCmd struct {
+	App *app
+}
+
+func (c *compactCmd) Compact(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if c.App.conn != nil {
+		fmt.Fprintln(c.App.Out, "A server is running, compacting through the server.")
+		admin := adminCmd{App: c.App}
+		return admin.Compact(cmd, args)
+	}
+
+	pid, err := runningPID(filepath.Join(c.App.DataDir, defaultPIDName))
+	if err != nil {
+		return err
+	}
+	if pid != 0 {
+		return fmt.Errorf("A server is running with PID %d but doesn't answer on %s, stop it before compacting", pid, c.App.SocketPath)
+	}
+
+	// the databases must be closed to be replaced
+	err = c.App.closeStore()
+	if err != nil {
+		return err
+	}
+
+	databases := []struct{ name, file string }{
+		{"registry", registryDB},
+		{"backend", defaultDBName},
+	}
+
+	for _, db := range databases {
+		before, after, err := boltdb.CompactFile(filepath.Join(c.App.DataDir, db.file))
+		if err != nil {
+			return fmt.Errorf("Can't compact the %s database: %s", db.name, err)
+		}
+
+		fmt.Fprintf(c.App.Out, "Database %s compacted: %d -> %d bytes\n", db.name, before, after)
+	}
+
+	return nil
+}
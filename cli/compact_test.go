@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	t.Run("Local", func(t *testing.T) {
+		app, cleanup := testableApp(t)
+		defer cleanup()
+
+		for _, name := range []string{registryDB, defaultDBName} {
+			db, err := bolt.Open(filepath.Join(app.DataDir, name), 0644, nil)
+			require.NoError(t, err)
+			err = db.Update(func(tx *bolt.Tx) error {
+				b, err := tx.CreateBucket([]byte("a"))
+				if err != nil {
+					return err
+				}
+
+				for i := 0; i < 100; i++ {
+					err = b.Put([]byte(fmt.Sprintf("%d", i)), bytes.Repeat([]byte("a"), 1000))
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			err = db.Update(func(tx *bolt.Tx) error {
+				return tx.DeleteBucket([]byte("a"))
+			})
+			require.NoError(t, err)
+			require.NoError(t, db.Close())
+		}
+
+		c := compactCmd{App: app}
+		err := c.Compact(nil, nil)
+		require.NoError(t, err)
+		require.Nil(t, app.Store)
+		require.Regexp(t, regexp.MustCompile(`^Database registry compacted: \d+ -> \d+ bytes
+Database backend compacted: \d+ -> \d+ bytes
+$`), app.Out.(*bytes.Buffer).String())
+
+		err = c.Compact(nil, []string{"a"})
+		require.EqualError(t, err, "Wrong number of arguments")
+	})
+
+	t.Run("NotAnswering", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "brazier")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		defer hungServer(t, dir)()
+
+		a := app{Out: new(bytes.Buffer), DataDir: dir, ProfilesPath: filepath.Join(dir, profilesFile)}
+		cmd := NewCompactCmd(&a)
+		err = cmd.PersistentPreRunE(cmd, nil)
+		require.NoError(t, err)
+		err = cmd.RunE(cmd, nil)
+		require.EqualError(t, err, fmt.Sprintf("A server is running with PID %d but doesn't answer on %s, stop it before compacting", os.Getpid(), a.SocketPath))
+		require.Nil(t, a.Store)
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		app, cleanup := testableAppRPC(t)
+		defer cleanup()
+
+		out := app.Out.(*bytes.Buffer)
+		out.Reset()
+
+		c := compactCmd{App: app}
+		err := c.Compact(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "A server is running, compacting through the server.\n", out.String())
+	})
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCompletionCmd creates a "Completion" cli command
+func NewCompletionCmd(a *app) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "completion bash|zsh|fish",
+		Short: "Generate the shell completion script",
+		Long: `Generate the completion script of brazier for bash, zsh or fish.
+Commands and flags are completed, as well as the paths of the buckets and of the items,
+read through the server when one is running.`,
+		Example: `source <(brazier completion bash)
+brazier completion bash > /etc/bash_completion.d/brazier
+brazier completion zsh > "${fpath[1]}/_brazier"
+brazier completion fish > ~/.config/fish/completions/brazier.fish`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		// the script is generated without opening the store or connecting to a server
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("Wrong number of arguments")
+			}
+
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(a.Out)
+			case "zsh":
+				return root.GenZshCompletion(a.Out)
+			case "fish":
+				return root.GenFishCompletion(a.Out, true)
+			}
+
+			return fmt.Errorf("Unsupported shell %q, use one of bash, zsh, fish", args[0])
+		},
+	}
+
+	return &cmd
+}
+
+// argKind is the kind of value expected by a positional argument.
+type argKind int
+
+const (
+	argNone argKind = iota
+	argPath
+	argBucket
+	argFile
+)
+
+// firstArg returns the kinds of the arguments of a command taking a single path.
+func firstArg(kind argKind) func(n int) argKind {
+	return func(n int) argKind {
+		if n == 0 {
+			return kind
+		}
+		return argNone
+	}
+}
+
+// pathCompletions returns the kind of the nth argument of the commands whose paths are completed.
+var pathCompletions = map[string]func(n int) argKind{
+	"create": firstArg(argBucket),
+	"get":    firstArg(argPath),
+	"delete": firstArg(argPath),
+	"edit":   firstArg(argPath),
+	"ls":     firstArg(argBucket),
+	"tree":   firstArg(argBucket),
+	"export": firstArg(argBucket),
+	"env":    firstArg(argBucket),
+	"put": func(n int) argKind {
+		// values can be read from files
+		if n%2 == 0 {
+			return argPath
+		}
+		return argFile
+	},
+	"import": func(n int) argKind {
+		switch n {
+		case 0:
+			return argBucket
+		case 1:
+			return argFile
+		}
+		return argNone
+	},
+	"diff": func(n int) argKind {
+		if n < 2 {
+			return argBucket
+		}
+		return argNone
+	},
+	"exec": func(n int) argKind {
+		if n == 0 {
+			return argBucket
+		}
+		return argFile
+	},
+}
+
+// setPathCompletions completes the paths of the subcommands of cmd with the content of the store.
+func setPathCompletions(cmd *cobra.Command, a *app) {
+	for _, c := range cmd.Commands() {
+		if kinds, ok := pathCompletions[c.Name()]; ok {
+			c.ValidArgsFunction = a.completePath(kinds)
+		}
+	}
+}
+
+// completePath returns a cobra completion function listing the children of the bucket of the path being typed.
+func (a *app) completePath(kinds func(n int) argKind) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		kind := kinds(len(args))
+		switch kind {
+		case argNone:
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		case argFile:
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		// PreRun is skipped by completion requests, it is run once the flags of the command
+		// are parsed. It connects to the server if one is running, the database being locked
+		// by it otherwise. PostRun is run by the completion request.
+		if a.Cli == nil {
+			err := a.PreRun(cmd, args)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+		}
+
+		bucket := toComplete[:strings.LastIndex(toComplete, "/")+1]
+		if bucket == "" {
+			bucket = "/"
+		}
+
+		candidates := pathCandidates(a.Cli, bucket, toComplete, kind == argBucket)
+
+		directive := cobra.ShellCompDirectiveNoFileComp
+		for _, c := range candidates {
+			// buckets are completed without a trailing space so that their content can be completed next
+			if strings.HasSuffix(c, "/") {
+				directive |= cobra.ShellCompDirectiveNoSpace
+				break
+			}
+		}
+
+		return candidates, directive
+	}
+}
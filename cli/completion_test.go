@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliCompletion(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testCompletion(t, app)
+}
+
+func TestCliRPCCompletion(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testCompletion(t, app)
+}
+
+func testCompletion(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"users/john", `"John"`,
+		"users/jack", `"Jack"`,
+		"users/admins/root", `"Root"`,
+		"config/name", `"brazier"`,
+	})
+	require.NoError(t, err)
+
+	root := cobra.Command{Use: "brazier"}
+	root.AddCommand(NewGetCmd(app, false), NewCreateCmd(app), NewPutCmd(app), NewImportCmd(app), NewCompletionCmd(app))
+	setPathCompletions(&root, app)
+
+	// complete runs a completion request as the shell scripts do and returns the candidates and the directive.
+	complete := func(args ...string) ([]string, string) {
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetArgs(append([]string{cobra.ShellCompNoDescRequestCmd}, args...))
+		err := root.Execute()
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		return lines[:len(lines)-1], lines[len(lines)-1]
+	}
+
+	t.Run("Paths", func(t *testing.T) {
+		candidates, directive := complete("get", "")
+		require.Equal(t, []string{"config/", "users/"}, candidates)
+		require.Equal(t, ":6", directive)
+
+		candidates, _ = complete("get", "users/")
+		require.Equal(t, []string{"users/admins/", "users/jack", "users/john"}, candidates)
+
+		candidates, _ = complete("get", "users/j")
+		require.Equal(t, []string{"users/jack", "users/john"}, candidates)
+
+		candidates, directive = complete("get", "/config/n")
+		require.Equal(t, []string{"/config/name"}, candidates)
+		require.Equal(t, ":4", directive)
+
+		candidates, directive = complete("get", "users/john", "")
+		require.Empty(t, candidates)
+		require.Equal(t, ":4", directive)
+	})
+
+	t.Run("Buckets", func(t *testing.T) {
+		candidates, _ := complete("create", "users/")
+		require.Equal(t, []string{"users/admins/"}, candidates)
+
+		candidates, _ = complete("import", "u")
+		require.Equal(t, []string{"users/"}, candidates)
+
+		candidates, directive := complete("import", "users/", "")
+		require.Empty(t, candidates)
+		require.Equal(t, ":0", directive)
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		candidates, _ := complete("put", "c")
+		require.Equal(t, []string{"config/"}, candidates)
+
+		_, directive := complete("put", "config/name", "")
+		require.Equal(t, ":0", directive)
+
+		candidates, _ = complete("put", "config/name", "value", "users/ja")
+		require.Equal(t, []string{"users/jack"}, candidates)
+	})
+
+	t.Run("Scripts", func(t *testing.T) {
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			out.Reset()
+			root.SetArgs([]string{"completion", shell})
+			err := root.Execute()
+			require.NoError(t, err)
+			require.Contains(t, out.String(), "brazier", shell)
+		}
+
+		root.SetArgs([]string{"completion", "tcsh"})
+		err := root.Execute()
+		require.EqualError(t, err, "Unsupported shell \"tcsh\", use one of bash, zsh, fish")
+	})
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asdine/brazier/client"
+	"golang.org/x/net/context"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultPIDName = "brazier.pid"
+	defaultLogName = "brazier.log"
+	// envDaemon is set in the environment of a server started in the background.
+	envDaemon = "BRAZIER_DAEMON"
+	// socketProbeTimeout is the maximum duration of the health check used to detect a running server.
+	socketProbeTimeout = time.Second
+)
+
+// errLocked is returned by lockFile when another process holds a conflicting lock.
+var errLocked = errors.New("file locked by another process")
+
+// A pidFile holds the PID of the running server. It is locked as long as the server runs,
+// so a file left by a server that didn't stop properly is detected.
+type pidFile struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// lockPIDFile locks the file and writes the PID of the current process in it.
+// It fails if another server holds the lock.
+func lockPIDFile(path string) (*pidFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(f, true)
+	if err != nil {
+		f.Close()
+		if err == errLocked {
+			pid, _ := readPID(path)
+			return nil, fmt.Errorf("A server is already running with PID %d", pid)
+		}
+		return nil, err
+	}
+
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &pidFile{f: f}, nil
+}
+
+// Release removes the file and releases the lock. It can be called multiple times.
+func (p *pidFile) Release() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.f == nil {
+		return nil
+	}
+
+	err := os.Remove(p.f.Name())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = p.f.Close()
+	p.f = nil
+	return err
+}
+
+// readPID returns the PID written in the file.
+func readPID(path string) (int, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
+
+// runningPID returns the PID of the server holding the lock of the file, or 0 if no server runs.
+func runningPID(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	err = lockFile(f, false)
+	if err == nil {
+		// the server is gone, the file is stale
+		return 0, nil
+	}
+	if err != errLocked {
+		return 0, err
+	}
+
+	return readPID(path)
+}
+
+// probeSocket checks that a server answers on the socket.
+// Any health status is accepted, a server that is stopping is still running.
+func probeSocket(path string, timeout time.Duration) error {
+	c, err := client.DialSocket(path, client.WithDialTimeout(timeout))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(c.Conn()).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+// executable returns the path of the running binary.
+func executable() (string, error) {
+	exe, err := os.Readlink("/proc/self/exe")
+	if err == nil {
+		return exe, nil
+	}
+
+	// systems without procfs
+	return exec.LookPath(os.Args[0])
+}
+
+// startDaemon starts the current command again in the background, detached from the terminal,
+// its output being appended to the log file. It returns once the server answers on the socket.
+func startDaemon(socketPath, logPath string, timeout time.Duration) (int, error) {
+	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer log.Close()
+
+	exe, err := executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), envDaemon+"=1")
+	cmd.Stdout = log
+	cmd.Stderr = log
+	detach(cmd)
+
+	err = cmd.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-exited:
+			return 0, fmt.Errorf("The server exited, see %s", logPath)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if probeSocket(socketPath, socketProbeTimeout) == nil {
+			return cmd.Process.Pid, nil
+		}
+	}
+
+	return cmd.Process.Pid, fmt.Errorf("The server didn't start after %s, see %s", timeout, logPath)
+}
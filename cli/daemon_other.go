@@ -0,0 +1,23 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+)
+
+// lockFile can't lock files on this platform. The PID file is trusted as long as it exists:
+// exclusive locks always succeed and shared ones always fail, so a file left by a server
+// that didn't stop properly must be removed by hand.
+func lockFile(f *os.File, exclusive bool) error {
+	if exclusive {
+		return nil
+	}
+
+	return errLocked
+}
+
+// detach does nothing on this platform, the command is started as is.
+func detach(cmd *exec.Cmd) {}
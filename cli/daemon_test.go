@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleSocket(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	// a regular file doesn't answer the health checks
+	socketPath := filepath.Join(app.DataDir, defaultSocketName)
+	err := ioutil.WriteFile(socketPath, nil, 0644)
+	require.NoError(t, err)
+
+	a := *app
+	a.Store = store.NewStore(mock.NewRegistry(mock.NewBackend()))
+	err = a.PreRun(nil, nil)
+	require.NoError(t, err)
+	require.IsType(t, new(cli), a.Cli)
+
+	app.Config.HTTP.Address = "127.0.0.1:"
+	app.Config.RPC.Address = "127.0.0.1:"
+	app.Config.Admin.Address = ""
+	s := serverCmd{
+		App:            app,
+		HTTPServerFunc: mock.NewServer,
+		RPCServerFunc:  mock.NewServer,
+	}
+	s.SocketServerFunc = s.newSocketServer
+
+	servers, err := s.createServers()
+	require.NoError(t, err)
+	defer s.pidFile.Release()
+	for _, ls := range servers {
+		defer ls.l.Close()
+	}
+	require.Contains(t, app.Out.(*bytes.Buffer).String(), "Removed stale socket "+socketPath)
+
+	fi, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.ModeSocket, fi.Mode()&os.ModeSocket)
+
+	other := serverCmd{App: app}
+	_, err = other.createServers()
+	require.EqualError(t, err, "A server is already running with PID "+strconv.Itoa(os.Getpid()))
+}
@@ -0,0 +1,53 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIDFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, defaultPIDName)
+
+	pid, err := runningPID(path)
+	require.NoError(t, err)
+	require.Zero(t, pid)
+
+	// stale file
+	err = ioutil.WriteFile(path, []byte("123456\n"), 0644)
+	require.NoError(t, err)
+	pid, err = runningPID(path)
+	require.NoError(t, err)
+	require.Zero(t, pid)
+
+	p, err := lockPIDFile(path)
+	require.NoError(t, err)
+
+	pid, err = runningPID(path)
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), pid)
+
+	_, err = lockPIDFile(path)
+	require.EqualError(t, err, "A server is already running with PID "+strconv.Itoa(os.Getpid()))
+
+	require.NoError(t, p.Release())
+	require.NoError(t, p.Release())
+
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	pid, err = runningPID(path)
+	require.NoError(t, err)
+	require.Zero(t, pid)
+}
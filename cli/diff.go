@@ -0,0 +1,491 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/store"
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the diff command.
+const (
+	formatText      = "text"
+	formatJSONPatch = "json-patch"
+)
+
+// NewDiffCmd creates a "Diff" cli command
+func NewDiffCmd(a *app) *cobra.Command {
+	diffCmd := diffCmd{
+		App: a,
+		In:  os.Stdin,
+	}
+
+	cmd := cobra.Command{
+		Use:   "diff PATH_A PATH_B",
+		Short: "Compare the content of two buckets",
+		Long: `Compare the items of two buckets and of all their children, key by key,
+and the fields of the values that are JSON objects, field by field.
+The differences are the changes needed to make PATH_B match PATH_A:
+  +  the item or the field only exists in PATH_A
+  -  the item or the field only exists in PATH_B
+  ~  the value differs
+
+With --file, the bucket is compared with the content of a file, as produced
+by the export command in the json or ndjson format, use - to read from stdin.
+The file then takes the place of PATH_A.
+
+The json-patch output is a JSON Patch (RFC 6902) document, the items being
+the members of a root object whose names are their paths relative to the buckets.
+With --apply, the items of PATH_B are added, updated or deleted to match PATH_A.`,
+		Example: `brazier diff env/staging/ env/prod/
+brazier diff env/staging/ env/prod/ -o json-patch
+brazier diff env/staging/ env/prod/ --apply
+brazier diff env/prod/ --file prod.json`,
+		RunE: diffCmd.Diff,
+	}
+
+	cmd.Flags().StringVar(&diffCmd.File, "file", "", "compare the bucket with the content of a file")
+	cmd.Flags().StringVar(&diffCmd.Format, "format", "", "format of the file: json or ndjson (default detected from the file extension, json otherwise)")
+	cmd.Flags().StringVarP(&diffCmd.Output, "output", "o", formatText, "output format: text or json-patch")
+	cmd.Flags().BoolVar(&diffCmd.Apply, "apply", false, "update PATH_B to match PATH_A")
+	return &cmd
+}
+
+type diffCmd struct {
+	App    *app
+	In     io.Reader
+	File   string
+	Format string
+	Output string
+	Apply  bool
+}
+
+// itemChange is a difference between two items with the same relative key.
+// Old is nil for added items and New is nil for removed items.
+type itemChange struct {
+	Key      string
+	Old, New []byte
+}
+
+func (s *diffCmd) Diff(cmd *cobra.Command, args []string) error {
+	if (s.File == "" && len(args) != 2) || (s.File != "" && len(args) != 1) {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if s.Output != formatText && s.Output != formatJSONPatch {
+		return fmt.Errorf("Unsupported format %q, use one of %s, %s", s.Output, formatText, formatJSONPatch)
+	}
+
+	var a map[string][]byte
+	var err error
+	if s.File != "" {
+		a, err = s.readFile()
+	} else {
+		a, err = loadTree(s.App.Cli, args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	target := bucketPath(args[len(args)-1])
+	b, err := loadTree(s.App.Cli, target)
+	if err == store.ErrNotFound {
+		// the bucket is created when the changes are applied
+		b, err = make(map[string][]byte), nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changes, err := diffItems(a, b)
+	if err != nil {
+		return err
+	}
+
+	if s.Output == formatJSONPatch {
+		err = writeJSONPatch(s.App.Out, changes)
+	} else {
+		err = writeTextDiff(s.App.Out, changes)
+	}
+	if err != nil || !s.Apply || len(changes) == 0 {
+		return err
+	}
+
+	for _, c := range changes {
+		if c.New == nil {
+			err = s.App.Cli.Delete(target + c.Key)
+		} else {
+			err = s.App.Cli.Put(target+c.Key, compactJSON(c.New))
+		}
+		if err != nil {
+			return fmt.Errorf("Item \"%s\": %s", target+c.Key, err)
+		}
+	}
+
+	// the patch output must stay valid JSON
+	if s.Output == formatText {
+		fmt.Fprintf(s.App.Out, "%d changes applied to \"%s\".\n", len(changes), target)
+	}
+	return nil
+}
+
+// readFile reads the items of an exported file.
+func (s *diffCmd) readFile() (map[string][]byte, error) {
+	format := s.Format
+	if format == "" {
+		format = detectFormat(s.File)
+	}
+
+	var r io.Reader = s.In
+	if s.File != "-" {
+		f, err := os.Open(s.File)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	items := make(map[string][]byte)
+	put := func(key string, data []byte) error {
+		items[key] = data
+		return nil
+	}
+
+	var err error
+	switch format {
+	case formatJSON:
+		err = (&importCmd{}).importJSON(r, put)
+	case formatNDJSON:
+		// lines written by the export command
+		err = (&importCmd{Key: "key", Value: "value"}).importNDJSON(r, put)
+	default:
+		err = fmt.Errorf("Unsupported format %q", format)
+	}
+
+	return items, err
+}
+
+// loadTree returns the values of the items of a bucket and of its children,
+// indexed by their paths relative to the bucket.
+func loadTree(c Cli, path string) (map[string][]byte, error) {
+	path = bucketPath(path)
+	prefix := strings.TrimPrefix(path, "/")
+
+	items := make(map[string][]byte)
+	err := c.Walk(path, true, func(p string, depth int, item *brazier.Item) error {
+		if !strings.HasSuffix(p, "/") {
+			items[strings.TrimPrefix(p, prefix)] = item.Data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// diffItems returns the changes needed to make b match a, sorted by key.
+func diffItems(a, b map[string][]byte) ([]itemChange, error) {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []itemChange
+	for _, k := range keys {
+		newData, inA := a[k]
+		oldData, inB := b[k]
+
+		if inA && inB {
+			equal, err := jsonEqual(oldData, newData)
+			if err != nil {
+				return nil, fmt.Errorf("Item \"%s\": %s", k, err)
+			}
+			if equal {
+				continue
+			}
+		}
+
+		c := itemChange{Key: k}
+		if inA {
+			c.New = nonNilJSON(newData)
+		}
+		if inB {
+			c.Old = nonNilJSON(oldData)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// nonNilJSON returns null for empty values, so that they aren't taken for missing ones.
+func nonNilJSON(data []byte) []byte {
+	if len(data) == 0 {
+		return []byte("null")
+	}
+
+	return data
+}
+
+func jsonEqual(a, b []byte) (bool, error) {
+	if bytes.Equal(a, b) {
+		return true, nil
+	}
+
+	va, err := decodeJSON(a)
+	if err != nil {
+		return false, err
+	}
+
+	vb, err := decodeJSON(b)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(va, vb), nil
+}
+
+// decodeJSON decodes a value, numbers being kept as written.
+func decodeJSON(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// fieldChange is a difference between the fields of two objects.
+// Path holds the names of the fields from the root object.
+type fieldChange struct {
+	Path     []string
+	Old, New interface{}
+	// Kind is one of '+', '-' or '~'
+	Kind byte
+}
+
+// diffFields returns the changes needed to make the object before match the object after.
+// Nested objects are compared field by field, other values are compared as a whole.
+func diffFields(path []string, before, after map[string]interface{}) []fieldChange {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range after {
+		keys = append(keys, k)
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []fieldChange
+	for _, k := range keys {
+		p := append(append([]string(nil), path...), k)
+		vo, inOld := before[k]
+		vn, inNew := after[k]
+
+		switch {
+		case !inOld:
+			changes = append(changes, fieldChange{Path: p, New: vn, Kind: '+'})
+		case !inNew:
+			changes = append(changes, fieldChange{Path: p, Old: vo, Kind: '-'})
+		case reflect.DeepEqual(vo, vn):
+		default:
+			oo, ok1 := vo.(map[string]interface{})
+			on, ok2 := vn.(map[string]interface{})
+			if ok1 && ok2 {
+				changes = append(changes, diffFields(p, oo, on)...)
+				continue
+			}
+			changes = append(changes, fieldChange{Path: p, Old: vo, New: vn, Kind: '~'})
+		}
+	}
+
+	return changes
+}
+
+// objectChanges returns the field changes of an item whose old and new values are both objects.
+func objectChanges(c *itemChange) ([]fieldChange, bool) {
+	if c.Old == nil || c.New == nil || !isObject(c.Old) || !isObject(c.New) {
+		return nil, false
+	}
+
+	before, err := decodeJSON(c.Old)
+	if err != nil {
+		return nil, false
+	}
+
+	after, err := decodeJSON(c.New)
+	if err != nil {
+		return nil, false
+	}
+
+	return diffFields(nil, before.(map[string]interface{}), after.(map[string]interface{})), true
+}
+
+// writeTextDiff writes the changes in a readable format, followed by a summary.
+func writeTextDiff(w io.Writer, changes []itemChange) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No differences.")
+		return err
+	}
+
+	var added, removed, changed int
+	for i := range changes {
+		c := &changes[i]
+
+		switch {
+		case c.Old == nil:
+			added++
+			fmt.Fprintf(w, "+ %s: %s\n", c.Key, compactJSON(c.New))
+		case c.New == nil:
+			removed++
+			fmt.Fprintf(w, "- %s: %s\n", c.Key, compactJSON(c.Old))
+		default:
+			changed++
+			fields, ok := objectChanges(c)
+			if !ok {
+				fmt.Fprintf(w, "~ %s: %s -> %s\n", c.Key, compactJSON(c.Old), compactJSON(c.New))
+				continue
+			}
+
+			fmt.Fprintf(w, "~ %s\n", c.Key)
+			for _, f := range fields {
+				name := fieldPath(f.Path)
+				switch f.Kind {
+				case '+':
+					fmt.Fprintf(w, "    + %s: %s\n", name, marshalCompact(f.New))
+				case '-':
+					fmt.Fprintf(w, "    - %s: %s\n", name, marshalCompact(f.Old))
+				default:
+					fmt.Fprintf(w, "    ~ %s: %s -> %s\n", name, marshalCompact(f.Old), marshalCompact(f.New))
+				}
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d added, %d changed, %d removed.\n", added, changed, removed)
+	return err
+}
+
+// fieldPath returns the path of a field as written in a query, e.g. .servers.main or .["a b"].
+func fieldPath(path []string) string {
+	var buf bytes.Buffer
+	for _, name := range path {
+		if isIdentifier(name) {
+			buf.WriteString("." + name)
+			continue
+		}
+
+		buf.WriteString(".[")
+		buf.Write(marshalCompact(name))
+		buf.WriteString("]")
+	}
+
+	return buf.String()
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && (i == 0 || !(c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func marshalCompact(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return []byte(fmt.Sprint(v))
+	}
+
+	return data
+}
+
+// patchOperation is an operation of a JSON Patch document.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// writeJSONPatch writes the changes as a JSON Patch document.
+func writeJSONPatch(w io.Writer, changes []itemChange) error {
+	ops := []patchOperation{}
+
+	for i := range changes {
+		c := &changes[i]
+		ptr := "/" + escapePointer(c.Key)
+
+		switch {
+		case c.Old == nil:
+			ops = append(ops, patchOperation{Op: "add", Path: ptr, Value: compactJSON(c.New)})
+		case c.New == nil:
+			ops = append(ops, patchOperation{Op: "remove", Path: ptr})
+		default:
+			fields, ok := objectChanges(c)
+			if !ok {
+				ops = append(ops, patchOperation{Op: "replace", Path: ptr, Value: compactJSON(c.New)})
+				continue
+			}
+
+			for _, f := range fields {
+				op := patchOperation{Path: ptr}
+				for _, name := range f.Path {
+					op.Path += "/" + escapePointer(name)
+				}
+
+				switch f.Kind {
+				case '+':
+					op.Op, op.Value = "add", marshalCompact(f.New)
+				case '-':
+					op.Op = "remove"
+				default:
+					op.Op, op.Value = "replace", marshalCompact(f.New)
+				}
+				ops = append(ops, op)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// escapePointer escapes a name to be used as a JSON Pointer reference token.
+func escapePointer(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
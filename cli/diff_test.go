@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliDiff(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testDiff(t, app)
+}
+
+func TestCliRPCDiff(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testDiff(t, app)
+}
+
+func testDiff(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"staging/app", `{"name": "app", "port": 8080, "db": {"host": "db-staging", "pool": 10}, "debug": true}`,
+		"staging/version", `"1.2"`,
+		"staging/workers/mail", `{"count": 2}`,
+		"staging/new", `1`,
+		"prod/app", `{"name": "app", "port": 80, "db": {"host": "db-prod", "pool": 10}, "legacy": "x"}`,
+		"prod/version", `"1.1"`,
+		"prod/workers/mail", `{ "count" : 2 }`,
+		"prod/old", `true`,
+	})
+	require.NoError(t, err)
+
+	diff := func(s *diffCmd, args ...string) string {
+		s.App = app
+		if s.Output == "" {
+			s.Output = formatText
+		}
+		out.Reset()
+		err := s.Diff(nil, args)
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		output := diff(&diffCmd{}, "staging/", "prod")
+		require.Equal(t, `~ app
+    ~ .db.host: "db-prod" -> "db-staging"
+    + .debug: true
+    - .legacy: "x"
+    ~ .port: 80 -> 8080
++ new: 1
+- old: true
+~ version: "1.1" -> "1.2"
+1 added, 2 changed, 1 removed.
+`, output)
+
+		output = diff(&diffCmd{}, "staging/workers", "prod/workers/")
+		require.Equal(t, "No differences.\n", output)
+	})
+
+	t.Run("JSONPatch", func(t *testing.T) {
+		output := diff(&diffCmd{Output: formatJSONPatch}, "staging/", "prod/")
+		require.JSONEq(t, `[
+			{"op": "replace", "path": "/app/db/host", "value": "db-staging"},
+			{"op": "add", "path": "/app/debug", "value": true},
+			{"op": "remove", "path": "/app/legacy"},
+			{"op": "replace", "path": "/app/port", "value": 8080},
+			{"op": "add", "path": "/new", "value": 1},
+			{"op": "remove", "path": "/old"},
+			{"op": "replace", "path": "/version", "value": "1.2"}
+		]`, output)
+
+		output = diff(&diffCmd{Output: formatJSONPatch}, "staging/", "unknown/")
+		require.Contains(t, output, `"path": "/workers~1mail"`)
+
+		output = diff(&diffCmd{Output: formatJSONPatch}, "staging/workers/", "prod/workers/")
+		require.Equal(t, "[]\n", output)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		s := diffCmd{
+			File: "-",
+			In:   strings.NewReader(`{"version": "1.1", "old": true, "workers/": {"mail": {"count": 3}}}`),
+		}
+		output := diff(&s, "prod/")
+		require.Equal(t, `- app: {"name":"app","port":80,"db":{"host":"db-prod","pool":10},"legacy":"x"}
+~ workers/mail
+    ~ .count: 2 -> 3
+0 added, 1 changed, 1 removed.
+`, output)
+
+		s = diffCmd{
+			File:   "-",
+			Format: formatNDJSON,
+			In:     strings.NewReader("{\"key\": \"new\", \"value\": 1}\n{\"key\": \"workers/mail\", \"value\": {\"count\": 2}}\n"),
+		}
+		output = diff(&s, "staging/")
+		require.Equal(t, `- app: {"name":"app","port":8080,"db":{"host":"db-staging","pool":10},"debug":true}
+- version: "1.2"
+0 added, 0 changed, 2 removed.
+`, output)
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		output := diff(&diffCmd{Apply: true}, "staging/", "prod/")
+		require.Contains(t, output, "4 changes applied to \"prod/\".\n")
+
+		output = diff(&diffCmd{}, "staging/", "prod/")
+		require.Equal(t, "No differences.\n", output)
+
+		output = diff(&diffCmd{Apply: true}, "staging/", "copy/")
+		require.Contains(t, output, "4 changes applied to \"copy/\".\n")
+
+		item, err := app.Cli.Item("copy/workers/mail")
+		require.NoError(t, err)
+		require.Equal(t, `{"count":2}`, string(item.Data))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		s := diffCmd{App: app, Output: formatText}
+		err := s.Diff(nil, []string{"staging/"})
+		require.EqualError(t, err, "Wrong number of arguments")
+
+		s.File = "-"
+		err = s.Diff(nil, []string{"staging/", "prod/"})
+		require.EqualError(t, err, "Wrong number of arguments")
+
+		s = diffCmd{App: app, Output: "xml"}
+		err = s.Diff(nil, []string{"staging/", "prod/"})
+		require.EqualError(t, err, "Unsupported format \"xml\", use one of text, json-patch")
+
+		s = diffCmd{App: app, Output: formatText}
+		err = s.Diff(nil, []string{"unknown/", "prod/"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/asdine/brazier/store"
+	"github.com/spf13/cobra"
+)
+
+// defaultEditor is used when neither $VISUAL nor $EDITOR are set.
+const defaultEditor = "vi"
+
+// NewEditCmd creates an "Edit" cli command
+func NewEditCmd(a *app) *cobra.Command {
+	editCmd := editCmd{
+		App: a,
+		In:  os.Stdin,
+	}
+	editCmd.RunEditor = editCmd.runEditor
+
+	cmd := cobra.Command{
+		Use:   "edit PATH",
+		Short: "Edit a value in an editor",
+		Long: `Edit a value in an editor, $VISUAL or $EDITOR by default.
+The value is opened as indented JSON and saved once the editor is closed.
+If the result isn't valid JSON, the editor can be opened again to fix it.
+The value isn't saved if the item was modified while it was edited, the edited
+value is then kept in a temporary file.
+The item is created if it doesn't exist. Saving an empty file cancels the edition.`,
+		Example: `brazier edit config/app
+EDITOR=nano brazier edit users/john`,
+		RunE: editCmd.Edit,
+	}
+
+	cmd.Flags().StringVar(&editCmd.Editor, "editor", "", "editor command (default $VISUAL or $EDITOR)")
+	return &cmd
+}
+
+type editCmd struct {
+	App    *app
+	In     io.Reader
+	Editor string
+	// RunEditor opens the file in the editor and returns once it is closed.
+	RunEditor func(editor, path string) error
+}
+
+func (e *editCmd) Edit(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	path := args[0]
+	if strings.HasSuffix(path, "/") {
+		return errors.New("Only items can be edited")
+	}
+
+	var original []byte
+	var updatedAt time.Time
+	item, err := e.App.Cli.Item(path)
+	switch err {
+	case nil:
+		original = item.Data
+		updatedAt = item.UpdatedAt
+	case store.ErrNotFound:
+	default:
+		return err
+	}
+
+	content := []byte("\n")
+	if original != nil {
+		var buf bytes.Buffer
+		err = json.Indent(&buf, original, "", "  ")
+		if err != nil {
+			return err
+		}
+		content = append(buf.Bytes(), '\n')
+	}
+
+	f, err := ioutil.TempFile("", "brazier-edit-")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	data, err := e.editLoop(tmp)
+	if err != nil || data == nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if original != nil && bytes.Equal(compactJSON(data), compactJSON(original)) {
+		os.Remove(tmp)
+		fmt.Fprintln(e.App.Out, "No changes.")
+		return nil
+	}
+
+	// the item is only saved if it wasn't modified since it was read
+	err = e.App.Cli.PutIf(path, compactJSON(data), updatedAt)
+	if err == store.ErrConflict {
+		return fmt.Errorf("Item \"%s\" was modified while it was edited, the edited value was kept in %s", path, tmp)
+	}
+	if err != nil {
+		return fmt.Errorf("%s, the edited value was kept in %s", err, tmp)
+	}
+
+	os.Remove(tmp)
+	fmt.Fprintf(e.App.Out, "Item \"%s\" successfully saved.\n", path)
+	return nil
+}
+
+// editLoop opens the editor until the file contains valid JSON.
+// It returns nil if the file is empty or if the user gives up.
+func (e *editCmd) editLoop(path string) ([]byte, error) {
+	editor := e.editor()
+	in := bufio.NewReader(e.In)
+
+	for {
+		err := e.RunEditor(editor, path)
+		if err != nil {
+			return nil, fmt.Errorf("Editor failed: %s", err)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			fmt.Fprintln(e.App.Out, "Empty value, edition cancelled.")
+			return nil, nil
+		}
+
+		var v interface{}
+		err = json.Unmarshal(data, &v)
+		if err == nil {
+			return data, nil
+		}
+
+		fmt.Fprintf(e.App.Out, "Invalid JSON: %s\nEdit again? [Y/n] ", err)
+		answer, rerr := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if (rerr != nil && answer == "") || (answer != "" && answer != "y" && answer != "yes") {
+			fmt.Fprintln(e.App.Out)
+			return nil, errors.New("Edition cancelled, the value isn't valid JSON")
+		}
+	}
+}
+
+// editor returns the editor command.
+func (e *editCmd) editor() string {
+	for _, editor := range []string{e.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if editor != "" {
+			return editor
+		}
+	}
+
+	return defaultEditor
+}
+
+// runEditor runs the editor through the shell, the editor command can contain arguments.
+func (e *editCmd) runEditor(editor, path string) error {
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "--", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// compactJSON removes the insignificant spaces of a valid JSON value.
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) != nil {
+		return data
+	}
+
+	return buf.Bytes()
+}
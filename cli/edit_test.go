@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliEdit(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testEdit(t, app)
+}
+
+func TestCliRPCEdit(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testEdit(t, app)
+}
+
+func testEdit(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{"config/app", `{"name": "brazier", "port": 5656}`})
+	require.NoError(t, err)
+
+	// edit runs the command, the editor writing the given contents in turn.
+	edit := func(path, in string, contents ...string) (string, error) {
+		var opened []string
+		e := editCmd{
+			App:    app,
+			In:     strings.NewReader(in),
+			Editor: "fake",
+			RunEditor: func(editor, file string) error {
+				require.Equal(t, "fake", editor)
+				data, err := ioutil.ReadFile(file)
+				require.NoError(t, err)
+				opened = append(opened, string(data))
+
+				if len(contents) == 0 {
+					return errors.New("no more content")
+				}
+				err = ioutil.WriteFile(file, []byte(contents[0]), 0600)
+				contents = contents[1:]
+				return err
+			},
+		}
+
+		out.Reset()
+		err := e.Edit(nil, []string{path})
+		require.Empty(t, contents)
+		return strings.Join(opened, "---\n") + "===\n" + out.String(), err
+	}
+
+	get := func(path string) string {
+		item, err := app.Cli.Item(path)
+		require.NoError(t, err)
+		return string(item.Data)
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		s, err := edit("config/app", "", `{"name": "brazier", "port": 8080}`)
+		require.NoError(t, err)
+		require.Equal(t, "{\n  \"name\": \"brazier\",\n  \"port\": 5656\n}\n===\nItem \"config/app\" successfully saved.\n", s)
+		require.Equal(t, `{"name":"brazier","port":8080}`, get("config/app"))
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		s, err := edit("config/new", "", `"value"`)
+		require.NoError(t, err)
+		require.Equal(t, "\n===\nItem \"config/new\" successfully saved.\n", s)
+		require.Equal(t, `"value"`, get("config/new"))
+	})
+
+	t.Run("NoChanges", func(t *testing.T) {
+		s, err := edit("config/new", "", "\"value\"\n")
+		require.NoError(t, err)
+		require.Equal(t, "\"value\"\n===\nNo changes.\n", s)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		s, err := edit("config/app", "", "  \n")
+		require.NoError(t, err)
+		require.Contains(t, s, "Empty value, edition cancelled.\n")
+		require.Equal(t, `{"name":"brazier","port":8080}`, get("config/app"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		s, err := edit("config/app", "\n", `{"name": `, `{"name": "fixed"}`)
+		require.NoError(t, err)
+		require.Contains(t, s, "---\n{\"name\": ===\nInvalid JSON: unexpected end of JSON input\nEdit again? [Y/n] Item \"config/app\" successfully saved.\n")
+		require.Equal(t, `{"name":"fixed"}`, get("config/app"))
+
+		_, err = edit("config/app", "n\n", `nope`)
+		require.EqualError(t, err, "Edition cancelled, the value isn't valid JSON")
+
+		_, err = edit("config/app", "", `nope`)
+		require.EqualError(t, err, "Edition cancelled, the value isn't valid JSON")
+		require.Equal(t, `{"name":"fixed"}`, get("config/app"))
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		e := editCmd{
+			App: app,
+			In:  strings.NewReader(""),
+			RunEditor: func(editor, file string) error {
+				err := app.Cli.Put("config/app", []byte(`{"name": "other"}`))
+				require.NoError(t, err)
+				return ioutil.WriteFile(file, []byte(`{"name": "mine"}`), 0600)
+			},
+		}
+
+		err := e.Edit(nil, []string{"config/app"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Item \"config/app\" was modified while it was edited, the edited value was kept in ")
+
+		tmp := err.Error()[strings.LastIndex(err.Error(), " ")+1:]
+		defer os.Remove(tmp)
+		data, err := ioutil.ReadFile(tmp)
+		require.NoError(t, err)
+		require.Equal(t, `{"name": "mine"}`, string(data))
+		require.Equal(t, `{"name":"other"}`, get("config/app"))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		e := editCmd{App: app}
+		err := e.Edit(nil, []string{"config/"})
+		require.EqualError(t, err, "Only items can be edited")
+
+		err = e.Edit(nil, nil)
+		require.EqualError(t, err, "Wrong number of arguments")
+
+		_, err = edit("config/app", "")
+		require.EqualError(t, err, "Editor failed: no more content")
+	})
+}
+
+func TestRunEditor(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	err = ioutil.WriteFile(src, []byte(`"edited"`), 0600)
+	require.NoError(t, err)
+
+	dst := filepath.Join(dir, "file with spaces")
+	var e editCmd
+	err = e.runEditor("cp "+src, dst)
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, `"edited"`, string(data))
+}
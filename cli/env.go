@@ -0,0 +1,457 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the env command.
+const (
+	formatDotenv = "dotenv"
+	formatExport = "export"
+)
+
+const (
+	// defaultPollInterval is the delay between two reads of the bucket when the command is restarted on change.
+	defaultPollInterval = 5 * time.Second
+	// killTimeout is the delay given to the command to stop before it is killed.
+	killTimeout = 10 * time.Second
+)
+
+// envOptions controls how items are converted to environment variables.
+type envOptions struct {
+	Prefix    string
+	Upper     bool
+	Recursive bool
+}
+
+func (o *envOptions) flags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Prefix, "prefix", "", "prefix added to the name of the variables")
+	cmd.Flags().BoolVar(&o.Upper, "upper", true, "upper-case the name of the variables")
+	cmd.Flags().BoolVarP(&o.Recursive, "recursive", "r", false, "include the items of the sub-buckets")
+}
+
+const envHelp = `Every item of the bucket becomes a variable named after its key, the fields of
+the values that are JSON objects becoming variables named after the key and the
+path of the field, joined with '_'. Characters other than letters, digits and '_'
+are replaced by '_' and names starting with a digit are prefixed with '_'. Strings are used as is, null as an empty string and the other
+values as compact JSON. With -r, the items of the sub-buckets are included, their
+variables starting with the path of the sub-bucket.`
+
+// NewEnvCmd creates an "Env" cli command
+func NewEnvCmd(a *app) *cobra.Command {
+	envCmd := envCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "env PATH",
+		Short: "Print the items of a bucket as environment variables",
+		Long: `Print the items of a bucket as environment variables, in the dotenv or the shell export format.
+
+` + envHelp,
+		Example: `brazier env config/app > .env
+brazier env config/app --prefix APP_ --format export
+eval "$(brazier env config/app --format export)"`,
+		RunE: envCmd.Env,
+	}
+
+	envCmd.Options.flags(&cmd)
+	cmd.Flags().StringVar(&envCmd.Format, "format", formatDotenv, "output format: dotenv or export")
+	return &cmd
+}
+
+type envCmd struct {
+	App     *app
+	Options envOptions
+	Format  string
+}
+
+func (s *envCmd) Env(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if s.Format != formatDotenv && s.Format != formatExport {
+		return fmt.Errorf("Unsupported format %q, use one of %s, %s", s.Format, formatDotenv, formatExport)
+	}
+
+	vars, err := envVars(s.App.Cli, args[0], &s.Options)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range vars {
+		if s.Format == formatExport {
+			_, err = fmt.Fprintf(s.App.Out, "export %s=%s\n", v.Name, shellQuote(v.Value))
+		} else {
+			_, err = fmt.Fprintf(s.App.Out, "%s=%s\n", v.Name, dotenvQuote(v.Value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewExecCmd creates an "Exec" cli command
+func NewExecCmd(a *app) *cobra.Command {
+	execCmd := execCmd{
+		App: a,
+		In:  os.Stdin,
+		Err: os.Stderr,
+	}
+
+	cmd := cobra.Command{
+		Use:   "exec PATH -- COMMAND [ARGS...]",
+		Short: "Run a command with the items of a bucket as environment variables",
+		Long: `Run a command with the items of a bucket added to its environment.
+
+` + envHelp + `
+
+brazier exits with the exit status of the command. Without a running server, the
+data directory is released once the bucket is read.
+
+With --restart, the bucket is read again periodically and the command is stopped
+with SIGTERM and started again when the variables change. It is killed if it
+doesn't stop within 10 seconds. --restart requires a running server. SIGINT and
+SIGTERM are forwarded to the command.`,
+		Example: `brazier exec config/app -- ./server --port 8080
+brazier exec config/app --prefix APP_ --restart --interval 10s -- ./worker`,
+		RunE: execCmd.Exec,
+	}
+
+	execCmd.Options.flags(&cmd)
+	cmd.Flags().BoolVar(&execCmd.Restart, "restart", false, "restart the command when the variables change")
+	cmd.Flags().DurationVar(&execCmd.Interval, "interval", defaultPollInterval, "delay between two reads of the bucket, with --restart")
+	return &cmd
+}
+
+type execCmd struct {
+	App      *app
+	In       io.Reader
+	Err      io.Writer
+	Options  envOptions
+	Restart  bool
+	Interval time.Duration
+
+	// signals forwarded to the command, listened to by Exec if nil
+	signals chan os.Signal
+	// ticks triggers the reads of the bucket with --restart, a ticker is used if nil
+	ticks <-chan time.Time
+}
+
+func (s *execCmd) Exec(cmd *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if s.Restart && s.Interval <= 0 {
+		return errors.New("The interval must be positive")
+	}
+
+	// the local store is locked as long as it is open, it can't be read again while the command runs
+	_, local := s.App.Cli.(*cli)
+	if s.Restart && local {
+		return errors.New("--restart requires a running server, start one with \"brazier server\"")
+	}
+
+	path, command := args[0], args[1:]
+
+	vars, err := envVars(s.App.Cli, path, &s.Options)
+	if err != nil {
+		return err
+	}
+
+	if local {
+		err = s.App.closeStore()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.signals == nil {
+		s.signals = make(chan os.Signal, 1)
+		signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(s.signals)
+	}
+
+	var poll <-chan time.Time
+	if s.Restart {
+		poll = s.ticks
+		if poll == nil {
+			ticker := time.NewTicker(s.Interval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+	}
+
+	for {
+		proc, done, err := s.start(command, vars)
+		if err != nil {
+			return err
+		}
+
+		restart := false
+		for !restart {
+			select {
+			case err = <-done:
+				return commandError(err)
+			case sig := <-s.signals:
+				proc.Process.Signal(sig)
+				return commandError(<-done)
+			case <-poll:
+				latest, err := envVars(s.App.Cli, path, &s.Options)
+				if err != nil {
+					// the command keeps running with the previous variables
+					fmt.Fprintf(s.Err, "Can't read the variables: %s\n", err)
+					continue
+				}
+
+				if !equalVars(vars, latest) {
+					fmt.Fprintln(s.Err, "The variables changed, restarting the command.")
+					vars, restart = latest, true
+					stopProcess(proc, done)
+				}
+			}
+		}
+	}
+}
+
+// start runs the command and returns a channel receiving its result once it exits.
+func (s *execCmd) start(command []string, vars []envVar) (*exec.Cmd, <-chan error, error) {
+	proc := exec.Command(command[0], command[1:]...)
+	proc.Stdin = s.In
+	proc.Stdout = s.App.Out
+	proc.Stderr = s.Err
+	proc.Env = os.Environ()
+	for _, v := range vars {
+		proc.Env = append(proc.Env, v.Name+"="+v.Value)
+	}
+
+	err := proc.Start()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- proc.Wait()
+	}()
+
+	return proc, done, nil
+}
+
+// ExitError is returned when a command run by brazier fails.
+// Code is the exit status brazier must exit with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("Command failed: %s", e.Err)
+}
+
+// commandError converts the result of a command to an ExitError carrying its exit status,
+// the status being 128 plus the number of the signal if the command was killed, like in shells.
+func commandError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	code := 1
+	if ee, ok := err.(*exec.ExitError); ok {
+		if status, ok := ee.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				code = 128 + int(status.Signal())
+			} else {
+				code = status.ExitStatus()
+			}
+		}
+	}
+
+	return &ExitError{Code: code, Err: err}
+}
+
+// stopProcess sends SIGTERM to the process and kills it if it doesn't exit in time.
+func stopProcess(proc *exec.Cmd, done <-chan error) {
+	proc.Process.Signal(syscall.SIGTERM)
+
+	select {
+	case <-done:
+	case <-time.After(killTimeout):
+		proc.Process.Kill()
+		<-done
+	}
+}
+
+// envVar is an environment variable.
+type envVar struct {
+	Name, Value string
+}
+
+type envVarsByName []envVar
+
+func (v envVarsByName) Len() int           { return len(v) }
+func (v envVarsByName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v envVarsByName) Less(i, j int) bool { return v[i].Name < v[j].Name }
+
+// envVars converts the items of the bucket to environment variables, sorted by name.
+func envVars(c Cli, path string, opts *envOptions) ([]envVar, error) {
+	path = bucketPath(path)
+	prefix := strings.TrimPrefix(path, "/")
+
+	values := make(map[string]string)
+	origins := make(map[string]string)
+
+	err := c.Walk(path, opts.Recursive, func(p string, depth int, item *brazier.Item) error {
+		if strings.HasSuffix(p, "/") {
+			return nil
+		}
+
+		key := strings.TrimPrefix(p, prefix)
+		v, err := decodeJSON(item.Data)
+		if err != nil {
+			return fmt.Errorf("Item \"%s\": %s", key, err)
+		}
+
+		return flattenEnv(strings.Split(key, "/"), v, func(parts []string, value string) error {
+			name := envName(opts, parts)
+			if origin, ok := origins[name]; ok {
+				return fmt.Errorf("Items \"%s\" and \"%s\" both define the variable %s", origin, key, name)
+			}
+
+			origins[name] = key
+			values[name] = value
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make([]envVar, 0, len(values))
+	for name, value := range values {
+		vars = append(vars, envVar{Name: name, Value: value})
+	}
+	sort.Sort(envVarsByName(vars))
+
+	return vars, nil
+}
+
+// flattenEnv calls fn for every value that isn't an object, with the path leading to it.
+func flattenEnv(parts []string, v interface{}, fn func(parts []string, value string) error) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			err := flattenEnv(append(parts[:len(parts):len(parts)], k), field, fn)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		return fn(parts, "")
+	case string:
+		return fn(parts, v)
+	}
+
+	return fn(parts, string(marshalCompact(v)))
+}
+
+// envName builds the name of a variable from the path of the value.
+func envName(opts *envOptions, parts []string) string {
+	name := []byte(opts.Prefix + strings.Join(parts, "_"))
+	for i, c := range name {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = append([]byte{'_'}, name...)
+	}
+
+	if opts.Upper {
+		return strings.ToUpper(string(name))
+	}
+
+	return string(name)
+}
+
+func equalVars(a, b []envVar) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isShellSafe returns true if the value doesn't need to be quoted.
+func isShellSafe(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, c := range value {
+		if !strings.ContainsRune("_-./:@%+,=", c) && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// shellQuote quotes the value with single quotes if needed.
+func shellQuote(value string) string {
+	if isShellSafe(value) {
+		return value
+	}
+
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// dotenvQuote quotes the value with double quotes if needed, escaping
+// the characters interpreted by dotenv parsers.
+func dotenvQuote(value string) string {
+	if value == "" || isShellSafe(value) {
+		return value
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, c := range value {
+		switch c {
+		case '\\', '"', '$', '`':
+			buf.WriteByte('\\')
+			buf.WriteRune(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	buf.WriteByte('"')
+
+	return buf.String()
+}
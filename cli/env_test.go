@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliEnv(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testEnv(t, app)
+}
+
+func TestCliRPCEnv(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testEnv(t, app)
+}
+
+func testEnv(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"config/name", `"my app"`,
+		"config/port", `8080`,
+		"config/db", `{"host": "localhost", "options": {"ssl": true}, "password": null}`,
+		"config/tags", `["a", "b"]`,
+		"config/log-level", `debug`,
+		"config/workers/mail", `{"count": 2}`,
+	})
+	require.NoError(t, err)
+
+	env := func(s *envCmd, path string) string {
+		s.App = app
+		if s.Format == "" {
+			s.Format = formatDotenv
+		}
+		out.Reset()
+		err := s.Env(nil, []string{path})
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	t.Run("Dotenv", func(t *testing.T) {
+		output := env(&envCmd{Options: envOptions{Upper: true}}, "config")
+		require.Equal(t, `DB_HOST=localhost
+DB_OPTIONS_SSL=true
+DB_PASSWORD=
+LOG_LEVEL=debug
+NAME="my app"
+PORT=8080
+TAGS="[\"a\",\"b\"]"
+`, output)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		output := env(&envCmd{Format: formatExport, Options: envOptions{Prefix: "app_", Recursive: true}}, "config/")
+		require.Equal(t, `export app_db_host=localhost
+export app_db_options_ssl=true
+export app_db_password=''
+export app_log_level=debug
+export app_name='my app'
+export app_port=8080
+export app_tags='["a","b"]'
+export app_workers_mail_count=2
+`, output)
+	})
+
+	t.Run("Digit", func(t *testing.T) {
+		err := p.RunE(nil, []string{"digits/2fa", `true`, "digits/key", `{"3d": 1}`})
+		require.NoError(t, err)
+
+		output := env(&envCmd{Options: envOptions{Upper: true}}, "digits")
+		require.Equal(t, "KEY_3D=1\n_2FA=true\n", output)
+
+		output = env(&envCmd{Options: envOptions{Prefix: "1-"}}, "digits")
+		require.Equal(t, "_1_2fa=true\n_1_key_3d=1\n", output)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		s := envCmd{App: app, Format: "xml"}
+		err := s.Env(nil, []string{"config"})
+		require.EqualError(t, err, "Unsupported format \"xml\", use one of dotenv, export")
+
+		s.Format = formatDotenv
+		err = s.Env(nil, nil)
+		require.EqualError(t, err, "Wrong number of arguments")
+
+		err = s.Env(nil, []string{"unknown/"})
+		require.Error(t, err)
+
+		err = p.RunE(nil, []string{"conflict/a-b", `1`, "conflict/a_b", `2`})
+		require.NoError(t, err)
+		err = s.Env(nil, []string{"conflict/"})
+		require.EqualError(t, err, "Items \"a-b\" and \"a_b\" both define the variable a_b")
+	})
+}
+
+// syncBuffer is a buffer that can be written by a command while being read by the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCliExec(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{"config/name", `"first"`})
+	require.NoError(t, err)
+
+	var out, errOut syncBuffer
+	app.Out = &out
+
+	s := execCmd{App: app, In: strings.NewReader(""), Err: &errOut, Options: envOptions{Upper: true}, Restart: true, Interval: time.Second}
+	err = s.Exec(nil, []string{"config", "true"})
+	require.EqualError(t, err, "--restart requires a running server, start one with \"brazier server\"")
+	require.NotNil(t, app.Store)
+
+	// the store is closed before the command is started
+	s.Restart = false
+	err = s.Exec(nil, []string{"config", "sh", "-c", `echo "$NAME"`})
+	require.NoError(t, err)
+	require.Equal(t, "first\n", out.String())
+	require.Nil(t, app.Store)
+}
+
+func TestCliRPCExec(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{"config/name", `"first"`, "config/port", `8080`})
+	require.NoError(t, err)
+
+	var out, errOut syncBuffer
+	app.Out = &out
+
+	t.Run("OK", func(t *testing.T) {
+		s := execCmd{App: app, In: strings.NewReader(""), Err: &errOut, Options: envOptions{Prefix: "APP_", Upper: true}}
+		err := s.Exec(nil, []string{"config", "sh", "-c", `echo "$APP_NAME:$APP_PORT"`})
+		require.NoError(t, err)
+		require.Equal(t, "first:8080\n", out.String())
+
+		err = s.Exec(nil, []string{"config", "sh", "-c", "exit 3"})
+		require.EqualError(t, err, "Command failed: exit status 3")
+		require.Equal(t, 3, err.(*ExitError).Code)
+
+		err = s.Exec(nil, []string{"config", "sh", "-c", "kill -KILL $$"})
+		require.Equal(t, 128+int(syscall.SIGKILL), err.(*ExitError).Code)
+
+		err = s.Exec(nil, []string{"config"})
+		require.EqualError(t, err, "Wrong number of arguments")
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		ticks := make(chan time.Time)
+		s := execCmd{
+			App:      app,
+			In:       strings.NewReader(""),
+			Err:      &errOut,
+			Options:  envOptions{Upper: true},
+			Restart:  true,
+			Interval: time.Second,
+			signals:  make(chan os.Signal, 1),
+			ticks:    ticks,
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.Exec(nil, []string{"config/", "sh", "-c", `echo "started $NAME"; exec sleep 10`})
+		}()
+
+		waitOutput := func(expected string) {
+			for i := 0; i < 100 && !strings.Contains(out.String(), expected); i++ {
+				time.Sleep(20 * time.Millisecond)
+			}
+			require.Contains(t, out.String(), expected)
+		}
+
+		waitOutput("started first\n")
+
+		// the store isn't read between two ticks
+		err := p.RunE(nil, []string{"config/name", `"second"`})
+		require.NoError(t, err)
+		ticks <- time.Now()
+
+		waitOutput("started second\n")
+		require.Contains(t, errOut.String(), "The variables changed, restarting the command.\n")
+
+		// the variables didn't change
+		ticks <- time.Now()
+
+		// the status of the command stopped by the forwarded signal is returned
+		s.signals <- syscall.SIGTERM
+		select {
+		case err = <-done:
+			require.Equal(t, 128+int(syscall.SIGTERM), err.(*ExitError).Code)
+		case <-time.After(5 * time.Second):
+			t.Fatal("exec didn't stop")
+		}
+
+		require.Equal(t, 1, strings.Count(out.String(), "started first\n"))
+		require.Equal(t, 1, strings.Count(out.String(), "started second\n"))
+	})
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/asdine/brazier"
+	"github.com/spf13/cobra"
+)
+
+// NewExportCmd creates an "Export" cli command
+func NewExportCmd(a *app) *cobra.Command {
+	exportCmd := exportCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "export PATH",
+		Short: "Export the content of a bucket",
+		Long: `Export the content of a bucket as JSON, NDJSON or CSV.
+
+JSON exports the same list of nodes as the get command, the content of the sub-buckets
+being the value of their node. The output can be imported back with the import command.
+NDJSON exports one object per line, with the path of the item relative to the bucket
+and its value.
+CSV exports one row per item, the columns being the key and the fields of the values.
+Values that aren't objects are exported in the "value" column.`,
+		Example: `brazier export users/ > users.json
+brazier export users/ -r --format ndjson
+brazier export users/ --format csv --key id`,
+		RunE: exportCmd.Export,
+	}
+
+	cmd.Flags().StringVar(&exportCmd.Format, "format", formatJSON, "output format: json, ndjson or csv")
+	cmd.Flags().BoolVarP(&exportCmd.Recursive, "recursive", "r", false, "export the content of the sub-buckets")
+	cmd.Flags().StringVarP(&exportCmd.Key, "key", "k", "key", "name of the key field or column, for ndjson and csv")
+	return &cmd
+}
+
+type exportCmd struct {
+	App       *app
+	Format    string
+	Recursive bool
+	Key       string
+}
+
+func (s *exportCmd) Export(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	path := strings.TrimSuffix(args[0], "/") + "/"
+	prefix := strings.TrimPrefix(path, "/")
+
+	w := bufio.NewWriter(s.App.Out)
+
+	var err error
+	switch s.Format {
+	case formatJSON:
+		err = writeTree(w, s.App.Cli, path, s.Recursive)
+	case formatNDJSON:
+		err = s.exportNDJSON(w, path, prefix)
+	case formatCSV:
+		err = s.exportCSV(w, path, prefix)
+	default:
+		return fmt.Errorf("Unsupported format %q", s.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// exportNDJSON writes one object per item.
+func (s *exportCmd) exportNDJSON(w io.Writer, path, prefix string) error {
+	key, err := json.Marshal(s.Key)
+	if err != nil {
+		return err
+	}
+
+	return s.App.Cli.Walk(path, s.Recursive, func(p string, depth int, item *brazier.Item) error {
+		if strings.HasSuffix(item.Key, "/") {
+			return nil
+		}
+
+		return writeNDJSONLine(w, key, strings.TrimPrefix(p, prefix), item.Data)
+	})
+}
+
+// writeNDJSONLine writes an item as an object holding its path in the key field and its value.
+func writeNDJSONLine(w io.Writer, key []byte, path string, data []byte) error {
+	k, err := json.Marshal(path)
+	if err != nil {
+		return err
+	}
+
+	var value bytes.Buffer
+	err = json.Compact(&value, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "{%s:%s,\"value\":%s}\n", key, k, value.Bytes())
+	return err
+}
+
+// exportCSV writes one row per item. The items are loaded in memory to compute the columns.
+func (s *exportCmd) exportCSV(w io.Writer, path, prefix string) error {
+	rows := newRowSet(s.Key)
+
+	err := s.App.Cli.Walk(path, s.Recursive, func(p string, depth int, item *brazier.Item) error {
+		if !strings.HasSuffix(item.Key, "/") {
+			rows.add(strings.TrimPrefix(p, prefix), item.Data)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+
+	err = cw.Write(rows.header())
+	if err != nil {
+		return err
+	}
+
+	for i := range rows.rows {
+		err = cw.Write(rows.record(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// rowSet holds items as rows whose columns are the fields of their values, in the order
+// they were found. Values that aren't objects are stored in the "value" column.
+type rowSet struct {
+	key     string
+	columns []string
+	rows    []row
+	seen    map[string]bool
+}
+
+type row struct {
+	key    string
+	fields map[string]json.RawMessage
+}
+
+// newRowSet returns a rowSet whose first column, holding the keys, is named key.
+func newRowSet(key string) *rowSet {
+	return &rowSet{
+		key:  key,
+		seen: map[string]bool{key: true},
+	}
+}
+
+func (s *rowSet) add(key string, data []byte) {
+	r := row{key: key}
+	if json.Unmarshal(data, &r.fields) != nil || r.fields == nil {
+		r.fields = map[string]json.RawMessage{"value": data}
+	}
+
+	for _, name := range objectKeys(data) {
+		if !s.seen[name] {
+			s.seen[name] = true
+			s.columns = append(s.columns, name)
+		}
+	}
+
+	s.rows = append(s.rows, r)
+}
+
+// header returns the names of the columns.
+func (s *rowSet) header() []string {
+	return append([]string{s.key}, s.columns...)
+}
+
+// record returns the cells of the i-th row.
+func (s *rowSet) record(i int) []string {
+	r := s.rows[i]
+
+	record := []string{r.key}
+	for _, name := range s.columns {
+		record = append(record, csvCell(r.fields[name]))
+	}
+
+	return record
+}
+
+// objectKeys returns the keys of the fields in the order they appear in the object.
+func objectKeys(data []byte) []string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('{') {
+		return []string{"value"}
+	}
+
+	var keys []string
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			break
+		}
+		keys = append(keys, tok.(string))
+
+		var skip json.RawMessage
+		if dec.Decode(&skip) != nil {
+			break
+		}
+	}
+
+	return keys
+}
+
+// csvCell converts a JSON value to a cell. Strings are written as is, null as an empty cell
+// and other values as JSON.
+func csvCell(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliExport(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testExport(t, app)
+}
+
+func TestCliRPCExport(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testExport(t, app)
+}
+
+func testExport(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"users/john", `{"name": "John", "age": 10}`,
+		"users/jack", `{"name": "Jack", "tags": ["a"]}`,
+		"users/admins/root", `"Root"`,
+	})
+	require.NoError(t, err)
+
+	export := func(s *exportCmd, path string) string {
+		s.App = app
+		out.Reset()
+		err := s.Export(nil, []string{path})
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		output := export(&exportCmd{Format: formatJSON}, "users")
+		require.JSONEq(t, `[
+  {"key": "john", "value": {"name": "John", "age": 10}},
+  {"key": "jack", "value": {"name": "Jack", "tags": ["a"]}}
+]`, output)
+
+		output = export(&exportCmd{Format: formatJSON, Recursive: true}, "users/")
+		require.Equal(t, `[
+  {
+    "key": "john",
+    "value": {
+      "name": "John",
+      "age": 10
+    }
+  },
+  {
+    "key": "jack",
+    "value": {
+      "name": "Jack",
+      "tags": [
+        "a"
+      ]
+    }
+  },
+  {
+    "key": "admins/",
+    "value": [
+      {
+        "key": "root",
+        "value": "Root"
+      }
+    ]
+  }
+]
+`, output)
+
+		// the output is the same as get
+		out.Reset()
+		err := app.Cli.Get(out, "users/", true)
+		require.NoError(t, err)
+		require.Equal(t, output, out.String())
+
+		// the output can be imported back
+		s := importCmd{App: app, In: strings.NewReader(output)}
+		err = s.Import(nil, []string{"copy", "-"})
+		require.NoError(t, err)
+		require.Equal(t, output, export(&exportCmd{Format: formatJSON, Recursive: true}, "copy/"))
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		output := export(&exportCmd{Format: formatNDJSON, Recursive: true, Key: "key"}, "users/")
+		require.Equal(t, `{"key":"john","value":{"name":"John","age":10}}
+{"key":"jack","value":{"name":"Jack","tags":["a"]}}
+{"key":"admins/root","value":"Root"}
+`, output)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		output := export(&exportCmd{Format: formatCSV, Recursive: true, Key: "id"}, "users/")
+		require.Equal(t, `id,name,age,tags,value
+john,John,10,,
+jack,Jack,,"[""a""]",
+admins/root,,,,Root
+`, output)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		s := exportCmd{App: app, Format: "xml"}
+		err := s.Export(nil, []string{"users/"})
+		require.EqualError(t, err, `Unsupported format "xml"`)
+
+		s.Format = formatJSON
+		err = s.Export(nil, []string{"unknown/"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,491 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Supported import and export formats.
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// maxLineSize is the maximum size of a NDJSON line.
+const maxLineSize = 16 * 1024 * 1024
+
+// NewImportCmd creates an "Import" cli command
+func NewImportCmd(a *app) *cobra.Command {
+	importCmd := importCmd{
+		App: a,
+		In:  os.Stdin,
+	}
+
+	cmd := cobra.Command{
+		Use:   "import PATH FILE",
+		Short: "Import items from a file into a bucket",
+		Long: `Import items from a JSON, NDJSON or CSV file into a bucket.
+The format is detected from the file extension unless --format is set, use - to read from stdin.
+
+A JSON object is imported as is, every field becoming an item. Fields whose name ends with a '/'
+and whose value is an object are imported in a sub-bucket.
+A JSON array of nodes, as written by the get and export commands, is imported as is
+unless --key or --auto-key is set. Otherwise every element of a JSON array, every line
+of a NDJSON file and every row of a CSV file becomes an item, its key being read from
+the field selected with --key or generated with --auto-key.
+CSV rows are converted to JSON objects using the header row. Numbers, booleans and empty cells
+are converted to their JSON equivalent unless --strings is set.`,
+		Example: `brazier import users/ users.json
+brazier import users/ users.csv --key id
+brazier import logs/ logs.ndjson --auto-key
+cat export.ndjson | brazier import users/ - --format ndjson --key key --value value`,
+		RunE: importCmd.Import,
+	}
+
+	cmd.Flags().StringVar(&importCmd.Format, "format", "", "format of the file: json, ndjson or csv (default detected from the file extension, json otherwise)")
+	cmd.Flags().StringVarP(&importCmd.Key, "key", "k", "", "field or column used as key")
+	cmd.Flags().BoolVar(&importCmd.AutoKey, "auto-key", false, "generate the keys using the position of the records, starting at 1")
+	cmd.Flags().StringVar(&importCmd.Value, "value", "", "field saved as value instead of the whole record")
+	cmd.Flags().BoolVar(&importCmd.Strings, "strings", false, "keep CSV values as strings")
+	return &cmd
+}
+
+type importCmd struct {
+	App     *app
+	In      io.Reader
+	Format  string
+	Key     string
+	AutoKey bool
+	Value   string
+	Strings bool
+
+	// number of records read, used to generate keys
+	count int
+}
+
+func (s *importCmd) Import(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if s.Key != "" && s.AutoKey {
+		return errors.New("--key and --auto-key can't be used together")
+	}
+
+	format := s.Format
+	if format == "" {
+		format = detectFormat(args[1])
+	}
+
+	var r io.Reader = s.In
+	if args[1] != "-" {
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	prefix := strings.TrimSuffix(args[0], "/") + "/"
+
+	return writeBulk(s.App, func(w BulkWriter) error {
+		put := func(key string, data []byte) error {
+			return w.Put(prefix+key, data)
+		}
+
+		switch format {
+		case formatJSON:
+			return s.importJSON(r, put)
+		case formatNDJSON:
+			return s.importNDJSON(r, put)
+		case formatCSV:
+			return s.importCSV(r, put)
+		}
+
+		return fmt.Errorf("Unsupported format %q", format)
+	})
+}
+
+func detectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".csv":
+		return formatCSV
+	}
+
+	return formatJSON
+}
+
+type putFunc func(key string, data []byte) error
+
+// importJSON imports a JSON object or the elements of a JSON array.
+func (s *importCmd) importJSON(r io.Reader, put putFunc) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("Invalid JSON: %s", err)
+	}
+
+	switch tok {
+	case json.Delim('['):
+		for dec.More() {
+			var raw json.RawMessage
+			err = dec.Decode(&raw)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %s", err)
+			}
+
+			if s.Key == "" && !s.AutoKey {
+				err = s.importNode(raw, "", put)
+			} else {
+				err = s.putRecord(raw, put)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case json.Delim('{'):
+		err = s.importObject(dec, "", put)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("JSON content must be an array or an object")
+	}
+
+	_, err = dec.Token()
+	if err != nil {
+		return fmt.Errorf("Invalid JSON: %s", err)
+	}
+
+	return nil
+}
+
+// treeNode is a node of the list written by the get and export commands.
+type treeNode struct {
+	Key   *string         `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// importNode imports a node of a list written by the get and export commands.
+// The nodes of a bucket, whose key ends with a '/', are imported in a sub-bucket.
+func (s *importCmd) importNode(raw json.RawMessage, prefix string, put putFunc) error {
+	var n treeNode
+	if json.Unmarshal(raw, &n) != nil || n.Key == nil || n.Value == nil {
+		return errors.New("A key is required, use --key or --auto-key")
+	}
+	key := *n.Key
+
+	if strings.HasSuffix(key, "/") {
+		var children []json.RawMessage
+		err := json.Unmarshal(n.Value, &children)
+		if err != nil {
+			return fmt.Errorf("Sub-bucket %q must be a list of nodes", prefix+key)
+		}
+
+		for _, child := range children {
+			err = s.importNode(child, prefix+key, put)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	data := []byte(n.Value)
+	if s.Value != "" {
+		var err error
+		data, err = field(n.Value, s.Value)
+		if err != nil {
+			return fmt.Errorf("Item %q: %s", prefix+key, err)
+		}
+	}
+
+	return put(prefix+key, data)
+}
+
+// importObject imports every field of the object being decoded. The opening brace must
+// have been read already.
+func (s *importCmd) importObject(dec *json.Decoder, prefix string, put putFunc) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("Invalid JSON: %s", err)
+		}
+		key := tok.(string)
+
+		if strings.HasSuffix(key, "/") {
+			tok, err = dec.Token()
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %s", err)
+			}
+			if tok != json.Delim('{') {
+				return fmt.Errorf("Sub-bucket %q must be an object", prefix+key)
+			}
+
+			err = s.importObject(dec, prefix+key, put)
+			if err != nil {
+				return err
+			}
+
+			_, err = dec.Token()
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %s", err)
+			}
+			continue
+		}
+
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return fmt.Errorf("Invalid JSON: %s", err)
+		}
+
+		data := []byte(raw)
+		if s.Value != "" {
+			data, err = field(raw, s.Value)
+			if err != nil {
+				return fmt.Errorf("Item %q: %s", prefix+key, err)
+			}
+		}
+
+		err = put(prefix+key, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importNDJSON imports every line as a record.
+func (s *importCmd) importNDJSON(r io.Reader, put putFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var line int
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var raw json.RawMessage
+		if json.Unmarshal(data, &raw) != nil {
+			return fmt.Errorf("Invalid JSON at line %d", line)
+		}
+
+		err := s.putRecord(append([]byte(nil), data...), put)
+		if err != nil {
+			return fmt.Errorf("Line %d: %s", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// importCSV converts every row to a JSON object using the header row.
+func (s *importCmd) importCSV(r io.Reader, put putFunc) error {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for n := 1; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := s.csvObject(header, row)
+		if err != nil {
+			return err
+		}
+
+		err = s.putRecord(data, put)
+		if err != nil {
+			return fmt.Errorf("Row %d: %s", n, err)
+		}
+	}
+}
+
+// csvObject converts a row to a JSON object, keeping the order of the columns.
+func (s *importCmd) csvObject(header, row []string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, name := range header {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		v, err := s.csvValue(row[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// csvValue converts a cell to JSON, inferring its type unless --strings is set.
+func (s *importCmd) csvValue(cell string) ([]byte, error) {
+	if !s.Strings {
+		switch cell {
+		case "":
+			return []byte("null"), nil
+		case "true", "false":
+			return []byte(cell), nil
+		}
+
+		if _, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return []byte(cell), nil
+		}
+
+		if f, err := strconv.ParseFloat(cell, 64); err == nil {
+			return json.Marshal(f)
+		}
+	}
+
+	return json.Marshal(cell)
+}
+
+// putRecord saves a record using the selected key field or a generated key.
+func (s *importCmd) putRecord(data []byte, put putFunc) error {
+	s.count++
+
+	var key string
+	switch {
+	case s.AutoKey:
+		key = strconv.Itoa(s.count)
+	case s.Key != "":
+		raw, err := field(data, s.Key)
+		if err != nil {
+			return err
+		}
+
+		key, err = keyString(raw)
+		if err != nil {
+			return fmt.Errorf("Field %q: %s", s.Key, err)
+		}
+	default:
+		return errors.New("A key is required, use --key or --auto-key")
+	}
+
+	if s.Value != "" {
+		var err error
+		data, err = field(data, s.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return put(key, data)
+}
+
+// decodeDocuments reads a list of JSON documents, separated by white spaces or new lines.
+// If the input is a single array, its elements are returned instead.
+func decodeDocuments(r io.Reader) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var docs []json.RawMessage
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid JSON at document %d: %s", len(docs)+1, err)
+		}
+
+		docs = append(docs, raw)
+	}
+
+	if len(docs) == 1 && bytes.HasPrefix(bytes.TrimSpace(docs[0]), []byte("[")) {
+		var elems []json.RawMessage
+		err := json.Unmarshal(docs[0], &elems)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid JSON: %s", err)
+		}
+		docs = elems
+	}
+
+	return docs, nil
+}
+
+// field returns the raw value of a field of a JSON object.
+func field(data []byte, name string) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+
+	err := json.Unmarshal(data, &obj)
+	if err != nil || obj == nil {
+		return nil, errors.New("Record is not an object")
+	}
+
+	v, ok := obj[name]
+	if !ok {
+		return nil, fmt.Errorf("Field %q is missing", name)
+	}
+
+	return v, nil
+}
+
+// keyString converts a JSON string or number to a key.
+func keyString(raw json.RawMessage) (string, error) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	if err != nil {
+		return "", err
+	}
+
+	switch k := v.(type) {
+	case string:
+		if k == "" {
+			return "", errors.New("Key is empty")
+		}
+		return k, nil
+	case json.Number:
+		return k.String(), nil
+	}
+
+	return "", errors.New("Key must be a string or a number")
+}
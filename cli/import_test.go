@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliImport(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testImport(t, app)
+}
+
+func TestCliRPCImport(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testImport(t, app)
+}
+
+func testImport(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+	g := NewGetCmd(app, false)
+
+	get := func(path string) string {
+		out.Reset()
+		err := g.RunE(nil, []string{path})
+		require.NoError(t, err)
+		return strings.TrimSpace(out.String())
+	}
+
+	run := func(s *importCmd, content string, args ...string) error {
+		s.App = app
+		s.In = strings.NewReader(content)
+		out.Reset()
+		return s.Import(nil, args)
+	}
+
+	t.Run("Object", func(t *testing.T) {
+		err := run(&importCmd{}, `{"a": 1, "b": {"c": "d"}, "sub/": {"e": true}}`, "obj", "-")
+		require.NoError(t, err)
+		require.Equal(t, "3 items successfully saved.\n", out.String())
+		require.Equal(t, "1", get("obj/a"))
+		require.JSONEq(t, `{"c": "d"}`, get("obj/b"))
+		require.Equal(t, "true", get("obj/sub/e"))
+	})
+
+	t.Run("Nodes", func(t *testing.T) {
+		content := `[{"key": "a", "value": 1}, {"key": "sub/", "value": [{"key": "b", "value": {"c": "d"}}]}]`
+		err := run(&importCmd{}, content, "nodes", "-")
+		require.NoError(t, err)
+		require.Equal(t, "2 items successfully saved.\n", out.String())
+		require.Equal(t, "1", get("nodes/a"))
+		require.JSONEq(t, `{"c": "d"}`, get("nodes/sub/b"))
+
+		err = run(&importCmd{}, `[{"key": "sub/", "value": 1}]`, "nodes", "-")
+		require.EqualError(t, err, `Sub-bucket "sub/" must be a list of nodes`)
+
+		// with a key, the nodes are imported as records
+		err = run(&importCmd{Key: "key"}, `[{"key": "a", "value": 1}]`, "records", "-")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"key": "a", "value": 1}`, get("records/a"))
+	})
+
+	t.Run("Array", func(t *testing.T) {
+		content := `[{"id": "john", "age": 10}, {"id": 2, "age": 20}]`
+		err := run(&importCmd{}, content, "arr/", "-")
+		require.EqualError(t, err, "A key is required, use --key or --auto-key")
+
+		err = run(&importCmd{Key: "id"}, content, "arr/", "-")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": "john", "age": 10}`, get("arr/john"))
+		require.JSONEq(t, `{"id": 2, "age": 20}`, get("arr/2"))
+
+		err = run(&importCmd{Key: "id", Value: "age"}, content, "ages/", "-")
+		require.NoError(t, err)
+		require.Equal(t, "10", get("ages/john"))
+
+		err = run(&importCmd{Key: "name"}, content, "arr/", "-")
+		require.EqualError(t, err, `Field "name" is missing`)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		content := "{\"a\": 1}\n\n\"b\"\n"
+		err := run(&importCmd{Format: formatNDJSON, AutoKey: true}, content, "lines", "-")
+		require.NoError(t, err)
+		require.Equal(t, "2 items successfully saved.\n", out.String())
+		require.JSONEq(t, `{"a": 1}`, get("lines/1"))
+		require.Equal(t, `"b"`, get("lines/2"))
+
+		err = run(&importCmd{Format: formatNDJSON, AutoKey: true}, "{\"a\": 1}\n{a}\n", "broken", "-")
+		require.EqualError(t, err, "Invalid JSON at line 2")
+
+		// nothing is saved when the import fails
+		_, err = app.Cli.Item("broken/1")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		content := "id,name,age,score,admin,email\n1,john,10,1.5,true,\n2,jack,20,3,false,jack@example.com\n"
+		err := run(&importCmd{Format: formatCSV, Key: "id"}, content, "csv", "-")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": 1, "name": "john", "age": 10, "score": 1.5, "admin": true, "email": null}`, get("csv/1"))
+
+		err = run(&importCmd{Format: formatCSV, Key: "name", Strings: true}, content, "csv-strings", "-")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": "2", "name": "jack", "age": "20", "score": "3", "admin": "false", "email": "jack@example.com"}`, get("csv-strings/jack"))
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(app.DataDir, "items.csv")
+		err := ioutil.WriteFile(path, []byte("k,v\na,1\n"), 0644)
+		require.NoError(t, err)
+
+		err = run(&importCmd{Key: "k"}, "", "file", path)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"k": "a", "v": 1}`, get("file/a"))
+	})
+}
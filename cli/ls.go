@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/spf13/cobra"
+)
+
+// timeFormat is the format used to display modification times.
+const timeFormat = "2006-01-02 15:04:05"
+
+// NewLsCmd creates a "Ls" cli command
+func NewLsCmd(a *app) *cobra.Command {
+	lsCmd := lsCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "ls [PATH]",
+		Short: "List the content of a bucket",
+		Long: `List the items and the buckets of a bucket, one per line. Buckets end with a '/'.
+The root bucket is listed if no path is given.
+
+With --long, the size and the modification time of every item are displayed.
+For buckets, the size, the number of items and the most recent modification time
+of their whole content are displayed.
+
+The listing is meant to be read, use "brazier get PATH/ -o keys" in scripts.`,
+		Example: `brazier ls
+brazier ls users/
+brazier ls users/ -l`,
+		RunE: lsCmd.Ls,
+	}
+
+	cmd.Flags().BoolVarP(&lsCmd.Long, "long", "l", false, "use a long listing format")
+	return &cmd
+}
+
+type lsCmd struct {
+	App  *app
+	Long bool
+}
+
+func (s *lsCmd) Ls(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	return listBucket(s.App.Out, s.App.Cli, bucketPath(path), s.Long)
+}
+
+// listBucket writes the content of a bucket, one entry per line, as it is walked.
+func listBucket(w io.Writer, c Cli, path string, long bool) error {
+	if !long {
+		return c.WalkDepth(path, 1, func(p string, depth int, item *brazier.Item) error {
+			_, err := fmt.Fprintln(w, item.Key)
+			return err
+		})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tITEMS\tUPDATED\tNAME")
+
+	// the content of a bucket is walked right after it, its line is written
+	// once the next entry of the listed bucket is reached.
+	var bucket string
+	var st bucketStats
+	flush := func() {
+		if bucket != "" {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", humanSize(st.size), st.items, formatTime(st.updatedAt), bucket)
+		}
+		bucket, st = "", bucketStats{}
+	}
+
+	err := c.WalkDepth(path, 0, func(p string, depth int, item *brazier.Item) error {
+		if depth > 0 {
+			st.add(item)
+			return nil
+		}
+
+		flush()
+		if strings.HasSuffix(item.Key, "/") {
+			bucket = item.Key
+			return nil
+		}
+
+		fmt.Fprintf(tw, "%s\t-\t%s\t%s\n", humanSize(len(item.Data)), formatTime(item.UpdatedAt), item.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	flush()
+
+	return tw.Flush()
+}
+
+// bucketStats sums up the content of a bucket and of its children.
+type bucketStats struct {
+	items     int
+	size      int
+	updatedAt time.Time
+}
+
+// add counts the item, buckets being ignored.
+func (b *bucketStats) add(item *brazier.Item) {
+	if strings.HasSuffix(item.Key, "/") {
+		return
+	}
+
+	b.items++
+	b.size += len(item.Data)
+	if item.UpdatedAt.After(b.updatedAt) {
+		b.updatedAt = item.UpdatedAt
+	}
+}
+
+// bucketPath returns the path of the bucket, ending with a '/'.
+// An empty path is the root bucket.
+func bucketPath(path string) string {
+	return strings.TrimSuffix(path, "/") + "/"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(timeFormat)
+}
+
+// humanSize formats a size in bytes using binary prefixes.
+func humanSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
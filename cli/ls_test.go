@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliLs(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testLs(t, app)
+}
+
+func TestCliRPCLs(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testLs(t, app)
+}
+
+func testLs(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"users/john", `{"name": "John"}`,
+		"users/jack", `"Jack"`,
+		"users/admins/root", `"Root"`,
+		"users/admins/guests/bob", `"Bob"`,
+	})
+	require.NoError(t, err)
+
+	ls := func(s *lsCmd, args ...string) string {
+		s.App = app
+		out.Reset()
+		err := s.Ls(nil, args)
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	require.Equal(t, "users/\n", ls(&lsCmd{}))
+	require.Equal(t, "john\njack\nadmins/\n", ls(&lsCmd{}, "users"))
+	require.Equal(t, "root\nguests/\n", ls(&lsCmd{}, "/users/admins/"))
+
+	date := `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`
+	require.Regexp(t, regexp.MustCompile(`^SIZE +ITEMS +UPDATED +NAME
+15 B +- +`+date+` +john
+6 B +- +`+date+` +jack
+11 B +2 +`+date+` +admins/
+$`), ls(&lsCmd{Long: true}, "users/"))
+
+	s := lsCmd{App: app}
+	err = s.Ls(nil, []string{"unknown/"})
+	require.Error(t, err)
+
+	err = s.Ls(nil, []string{"a/", "b/"})
+	require.EqualError(t, err, "Wrong number of arguments")
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := map[int]string{
+		0:                  "0 B",
+		1023:               "1023 B",
+		1024:               "1.0 KiB",
+		1536:               "1.5 KiB",
+		5 * 1024 * 1024:    "5.0 MiB",
+		3 << 30:            "3.0 GiB",
+		1024*1024*1024 - 1: "1024.0 MiB",
+	}
+
+	for n, s := range tests {
+		require.Equal(t, s, humanSize(n))
+	}
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/query"
+	"github.com/ghodss/yaml"
+)
+
+// Output formats of the values, in addition to json and ndjson.
+const (
+	formatCompact = "compact"
+	formatRaw     = "raw"
+	formatYAML    = "yaml"
+	formatTable   = "table"
+	formatKeys    = "keys"
+)
+
+// outputFormats lists the formats supported by printOutput.
+var outputFormats = []string{formatJSON, formatCompact, formatRaw, formatYAML, formatTable, formatNDJSON, formatKeys}
+
+// printOutput writes the value of an item or the content of a bucket in the given format.
+// The formats are implemented on top of the Cli interface so that every implementation
+// produces the same output.
+func printOutput(w io.Writer, c Cli, path string, recursive bool, format string) error {
+	switch format {
+	case "", formatJSON:
+		return c.Get(w, path, recursive)
+	case formatCompact, formatYAML:
+		return convertOutput(w, c, path, recursive, format)
+	case formatRaw, formatTable, formatNDJSON, formatKeys:
+	default:
+		return fmt.Errorf("Unsupported format %q, use one of %s", format, strings.Join(outputFormats, ", "))
+	}
+
+	if !strings.HasSuffix(path, "/") {
+		item, err := c.Item(path)
+		if err != nil {
+			return err
+		}
+
+		return printValue(w, item, format)
+	}
+
+	prefix := strings.TrimPrefix(path, "/")
+
+	// the columns of the table depend on every item, the other formats are written while walking
+	if format == formatTable {
+		rows := newRowSet("key")
+		err := c.Walk(path, recursive, func(p string, depth int, item *brazier.Item) error {
+			if !strings.HasSuffix(p, "/") {
+				rows.add(strings.TrimPrefix(p, prefix), item.Data)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return printTable(w, rows)
+	}
+
+	return c.Walk(path, recursive, func(p string, depth int, item *brazier.Item) error {
+		return printListItem(w, strings.TrimPrefix(p, prefix), item.Data, format)
+	})
+}
+
+// queryFormats lists the formats supported by printQuery.
+var queryFormats = []string{formatJSON, formatCompact, formatRaw, formatYAML, formatNDJSON}
+
+// printQuery applies the query to the json output of the path and writes each result in the given format.
+// Buckets are queried as lists of key and value objects.
+func printQuery(w io.Writer, c Cli, path string, recursive bool, format string, q *query.Query) error {
+	switch format {
+	case "", formatJSON, formatCompact, formatRaw, formatYAML, formatNDJSON:
+	default:
+		return fmt.Errorf("Unsupported format %q with a query, use one of %s", format, strings.Join(queryFormats, ", "))
+	}
+
+	var buf bytes.Buffer
+	err := c.Get(&buf, path, recursive)
+	if err != nil {
+		return err
+	}
+
+	results, err := q.Run(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("Query failed: %s", err)
+	}
+
+	for i, r := range results {
+		var data []byte
+
+		switch format {
+		case "", formatJSON:
+			var out bytes.Buffer
+			err = json.Indent(&out, r, "", "  ")
+			data = append(out.Bytes(), '\n')
+		case formatRaw:
+			data = []byte(rawValue(r) + "\n")
+		case formatYAML:
+			data, err = yaml.JSONToYAML(r)
+			if i > 0 {
+				data = append([]byte("---\n"), data...)
+			}
+		default:
+			data = append(r, '\n')
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// convertOutput converts the json output to the compact or yaml format.
+func convertOutput(w io.Writer, c Cli, path string, recursive bool, format string) error {
+	var buf bytes.Buffer
+
+	err := c.Get(&buf, path, recursive)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if format == formatYAML {
+		data, err = yaml.JSONToYAML(buf.Bytes())
+	} else {
+		var out bytes.Buffer
+		err = json.Compact(&out, buf.Bytes())
+		data = append(out.Bytes(), '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// printValue writes the value of a single item.
+func printValue(w io.Writer, item *brazier.Item, format string) error {
+	switch format {
+	case formatRaw:
+		_, err := fmt.Fprintln(w, rawValue(item.Data))
+		return err
+	case formatNDJSON:
+		var buf bytes.Buffer
+		err := json.Compact(&buf, item.Data)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", buf.Bytes())
+		return err
+	case formatKeys:
+		if !isObject(item.Data) {
+			return fmt.Errorf("The value of \"%s\" is not an object", item.Key)
+		}
+
+		for _, k := range objectKeys(item.Data) {
+			_, err := fmt.Fprintln(w, k)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows := newRowSet("key")
+	rows.add(item.Key, item.Data)
+	return printTable(w, rows)
+}
+
+// printListItem writes an item whose key is its path relative to the listed bucket.
+// Buckets are only written by the keys format.
+func printListItem(w io.Writer, key string, data []byte, format string) error {
+	var err error
+
+	switch {
+	case format == formatKeys:
+		_, err = fmt.Fprintln(w, key)
+	case strings.HasSuffix(key, "/"):
+	case format == formatRaw:
+		_, err = fmt.Fprintln(w, rawValue(data))
+	case format == formatNDJSON:
+		err = writeNDJSONLine(w, []byte(`"key"`), key, data)
+	}
+
+	return err
+}
+
+// printTable writes the rows of a table.
+func printTable(w io.Writer, rows *rowSet) error {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(rows.header(), "\t")))
+	for i := range rows.rows {
+		fmt.Fprintln(tw, strings.Join(rows.record(i), "\t"))
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	// empty cells at the end of the rows are padded by the tabwriter
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		_, err = fmt.Fprintln(w, strings.TrimRight(line, " "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rawValue returns strings without quotes and other values as compact JSON.
+func rawValue(data []byte) string {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) != nil {
+		return string(data)
+	}
+
+	return buf.String()
+}
+
+// isObject returns true if data is a JSON object.
+func isObject(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/query"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliOutput(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testOutput(t, app)
+}
+
+func TestCliRPCOutput(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testOutput(t, app)
+}
+
+func testOutput(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"users/john", `{"name": "John", "age": 30}`,
+		"users/jack", `{"name": "Jack", "admin": true}`,
+		"users/admins/root", `"Root"`,
+		"config/name", `brazier`,
+	})
+	require.NoError(t, err)
+
+	get := func(path, format string, recursive bool) string {
+		out.Reset()
+		err := printOutput(out, app.Cli, path, recursive, format)
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	t.Run("Compact", func(t *testing.T) {
+		require.Equal(t, "{\"name\":\"John\",\"age\":30}\n", get("users/john", formatCompact, false))
+		require.Equal(t, `[{"key":"john","value":{"name":"John","age":30}},{"key":"jack","value":{"name":"Jack","admin":true}},{"key":"admins/","value":[{"key":"root","value":"Root"}]}]`+"\n", get("users/", formatCompact, true))
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		require.Equal(t, "brazier\n", get("config/name", formatRaw, false))
+		require.Equal(t, "{\"name\":\"John\",\"age\":30}\n", get("users/john", formatRaw, false))
+		require.Equal(t, "{\"name\":\"John\",\"age\":30}\n{\"name\":\"Jack\",\"admin\":true}\nRoot\n", get("users/", formatRaw, true))
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		require.Equal(t, "age: 30\nname: John\n", get("users/john", formatYAML, false))
+		require.Equal(t, `- key: john
+  value:
+    age: 30
+    name: John
+- key: jack
+  value:
+    admin: true
+    name: Jack
+`, get("users/", formatYAML, false))
+	})
+
+	t.Run("Table", func(t *testing.T) {
+		require.Equal(t, `KEY   NAME  AGE  ADMIN
+john  John  30
+jack  Jack       true
+`, get("users/", formatTable, false))
+		require.Equal(t, `KEY          NAME  AGE  ADMIN  VALUE
+john         John  30
+jack         Jack       true
+admins/root                    Root
+`, get("users/", formatTable, true))
+		require.Equal(t, "KEY   VALUE\nname  brazier\n", get("config/name", formatTable, false))
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		require.Equal(t, `{"key":"john","value":{"name":"John","age":30}}
+{"key":"jack","value":{"name":"Jack","admin":true}}
+{"key":"admins/root","value":"Root"}
+`, get("users/", formatNDJSON, true))
+		require.Equal(t, "\"brazier\"\n", get("config/name", formatNDJSON, false))
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		require.Equal(t, "john\njack\n", get("users/", formatKeys, false))
+		require.Equal(t, "john\njack\nadmins/\nadmins/root\n", get("/users/", formatKeys, true))
+		require.Equal(t, "name\nage\n", get("users/john", formatKeys, false))
+
+		err := printOutput(out, app.Cli, "config/name", false, formatKeys)
+		require.EqualError(t, err, "The value of \"name\" is not an object")
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		// length of the output once the first item is handled
+		var first int
+		c := walkObserver{Cli: app.Cli, after: func() {
+			if first < 0 {
+				first = out.Len()
+			}
+		}}
+
+		for _, format := range []string{formatRaw, formatNDJSON, formatKeys} {
+			out.Reset()
+			first = -1
+			err := printOutput(out, &c, "users/", true, format)
+			require.NoError(t, err)
+			require.True(t, first > 0, format)
+		}
+
+		// the table is written once every item is read
+		out.Reset()
+		first = -1
+		err := printOutput(out, &c, "users/", true, formatTable)
+		require.NoError(t, err)
+		require.Equal(t, 0, first)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		err := printOutput(out, app.Cli, "users/john", false, "xml")
+		require.EqualError(t, err, "Unsupported format \"xml\", use one of json, compact, raw, yaml, table, ndjson, keys")
+
+		err = printOutput(out, app.Cli, "users/unknown", false, formatRaw)
+		require.Error(t, err)
+
+		err = printOutput(out, app.Cli, "unknown/", false, formatKeys)
+		require.Error(t, err)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		run := func(path, expr, format string, recursive bool) string {
+			q, err := query.Parse(expr)
+			require.NoError(t, err)
+
+			out.Reset()
+			err = printQuery(out, app.Cli, path, recursive, format, q)
+			require.NoError(t, err)
+			return out.String()
+		}
+
+		require.Equal(t, "\"John\"\n", run("users/john", ".name", formatJSON, false))
+		require.Equal(t, "John\n", run("users/john", ".name", formatRaw, false))
+		require.Equal(t, "{\n  \"n\": \"John\",\n  \"a\": 30\n}\n", run("users/john", "{n: .name, a: .age}", formatJSON, false))
+		require.Equal(t, "{\"n\":\"John\"}\n", run("users/john", "{n: .name}", formatCompact, false))
+		require.Equal(t, "john\njack\n", run("users/", ".[] | select(.value.name?) | .key", formatRaw, true))
+		require.Equal(t, "\"jack\"\n", run("users/", ".[] | select(.value.admin) | .key", formatNDJSON, false))
+		require.Equal(t, "name: John\n---\nname: Jack\n", run("users/", ".[] | select(.value.name?) | {name: .value.name}", formatYAML, false))
+		require.Equal(t, "", run("users/", ".[] | select(.key == \"nobody\")", formatJSON, false))
+
+		q, err := query.Parse(".name")
+		require.NoError(t, err)
+		err = printQuery(out, app.Cli, "users/john", false, formatTable, q)
+		require.EqualError(t, err, "Unsupported format \"table\" with a query, use one of json, compact, raw, yaml, ndjson")
+
+		err = printQuery(out, app.Cli, "config/name", false, formatJSON, q)
+		require.EqualError(t, err, "Query failed: cannot index string with \"name\"")
+
+		err = printQuery(out, app.Cli, "users/unknown", false, formatJSON, q)
+		require.Error(t, err)
+	})
+
+	t.Run("Cmd", func(t *testing.T) {
+		g := NewGetCmd(app, false)
+		g.Flags().Set("output", "raw")
+
+		out.Reset()
+		err := g.RunE(g, []string{"config/name"})
+		require.NoError(t, err)
+		require.Equal(t, "brazier\n", out.String())
+
+		g = NewGetCmd(app, false)
+		g.Flags().Set("query", ".age")
+
+		out.Reset()
+		err = g.RunE(g, []string{"users/john"})
+		require.NoError(t, err)
+		require.Equal(t, "30\n", out.String())
+
+		g = NewGetCmd(app, false)
+		g.Flags().Set("query", ".age |")
+		err = g.RunE(g, []string{"users/john"})
+		require.EqualError(t, err, "invalid query: unexpected end of expression")
+	})
+}
+
+// walkObserver calls after every time an item is handled by the walk function.
+type walkObserver struct {
+	Cli
+	after func()
+}
+
+func (c *walkObserver) Walk(path string, recursive bool, fn store.WalkFunc) error {
+	return c.Cli.Walk(path, recursive, func(p string, depth int, item *brazier.Item) error {
+		err := fn(p, depth, item)
+		c.after()
+		return err
+	})
+}
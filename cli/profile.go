@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asdine/brazier/config"
+	"github.com/spf13/cobra"
+)
+
+// NewProfileCmd creates a "Profile" cli command
+func NewProfileCmd(a *app) *cobra.Command {
+	profileCmd := profileCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "profile",
+		Short: "Manage the connection profiles",
+		Long: `Manage the connection profiles.
+A profile names a data directory, the socket of a local server or a remote host
+with its TLS and token settings. The current profile is used by every command,
+unless another one is selected with --profile or $BRAZIER_PROFILE, or a host or
+a data directory is set. A selected profile replaces $BRAZIER_HOST and can't be
+used with --host or --data-dir.
+Profiles are stored in $HOME/.brazier/profiles.yml, or in $BRAZIER_PROFILES.`,
+		// profiles are managed without opening the store or connecting to a server
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	add := cobra.Command{
+		Use:   "add NAME",
+		Short: "Add or replace a profile",
+		Long: `Add or replace a profile using the --data-dir, --socket or --host flags.
+The TLS, token and timeout flags are saved with the host.`,
+		Example: `brazier profile add local --data-dir /var/lib/brazier
+brazier profile add dev --socket /tmp/brazier/brazier.sock
+brazier profile add prod --host brazier.example.com:5657 --ca-cert ca.pem --token secret --use`,
+		RunE: profileCmd.Add,
+	}
+	add.Flags().StringVar(&profileCmd.Socket, "socket", "", "socket of a local server")
+	add.Flags().BoolVar(&profileCmd.Use, "use", false, "make it the current profile")
+	cmd.AddCommand(&add)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the current profile",
+		RunE:  profileCmd.UseProfile,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the profiles",
+		RunE:  profileCmd.List,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove NAME",
+		Short: "Remove a profile",
+		RunE:  profileCmd.Remove,
+	})
+
+	return &cmd
+}
+
+type profileCmd struct {
+	App    *app
+	Socket string
+	Use    bool
+}
+
+func (p *profileCmd) Add(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	c := p.App.Client
+	profile := config.Profile{
+		DataDir:    p.App.DataDir,
+		Socket:     p.Socket,
+		Host:       c.Host,
+		TLS:        c.TLS,
+		CACert:     c.CACert,
+		ClientCert: c.ClientCert,
+		ClientKey:  c.ClientKey,
+		Token:      c.Token,
+		Timeout:    config.Duration(c.Timeout),
+	}
+
+	err := profile.Validate()
+	if err != nil {
+		return err
+	}
+
+	return p.update(func(profiles *config.Profiles) error {
+		profiles.Profiles[args[0]] = profile
+		if p.Use {
+			profiles.Current = args[0]
+		}
+
+		fmt.Fprintf(p.App.Out, "Profile \"%s\" successfully saved.\n", args[0])
+		return nil
+	})
+}
+
+func (p *profileCmd) UseProfile(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	return p.update(func(profiles *config.Profiles) error {
+		if _, ok := profiles.Profiles[args[0]]; !ok {
+			return fmt.Errorf("Unknown profile \"%s\"", args[0])
+		}
+
+		profiles.Current = args[0]
+		fmt.Fprintf(p.App.Out, "Now using profile \"%s\".\n", args[0])
+		return nil
+	})
+}
+
+func (p *profileCmd) List(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	profiles, err := p.App.loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(profiles.Profiles))
+	for name := range profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(p.App.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tTYPE\tTARGET")
+	for _, name := range names {
+		var current string
+		if name == profiles.Current {
+			current = "*"
+		}
+
+		kind, target := profileTarget(profiles.Profiles[name])
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", current, name, kind, target)
+	}
+
+	return tw.Flush()
+}
+
+func (p *profileCmd) Remove(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	return p.update(func(profiles *config.Profiles) error {
+		if _, ok := profiles.Profiles[args[0]]; !ok {
+			return fmt.Errorf("Unknown profile \"%s\"", args[0])
+		}
+
+		delete(profiles.Profiles, args[0])
+		if profiles.Current == args[0] {
+			profiles.Current = ""
+		}
+
+		fmt.Fprintf(p.App.Out, "Profile \"%s\" successfully removed.\n", args[0])
+		return nil
+	})
+}
+
+// update loads the profiles, calls fn and saves the profiles if fn succeeds.
+func (p *profileCmd) update(fn func(*config.Profiles) error) error {
+	profiles, err := p.App.loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	err = fn(profiles)
+	if err != nil {
+		return err
+	}
+
+	return profiles.Save(p.App.profilesPath())
+}
+
+// profileTarget describes how the profile reaches the store.
+func profileTarget(p config.Profile) (string, string) {
+	switch {
+	case p.Socket != "":
+		return "socket", p.Socket
+	case p.Host == "":
+		return "data-dir", p.DataDir
+	}
+
+	kind := "host"
+	if p.TLS || p.CACert != "" || p.ClientCert != "" {
+		kind += "+tls"
+	}
+	if p.Token != "" {
+		kind += "+token"
+	}
+	if p.Timeout != 0 {
+		return kind, fmt.Sprintf("%s (timeout %s)", p.Host, time.Duration(p.Timeout))
+	}
+
+	return kind, p.Host
+}
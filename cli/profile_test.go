@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier/config"
+	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileCmd(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := bytes.NewBuffer(nil)
+	path := filepath.Join(dir, profilesFile)
+	a := app{Out: out, ProfilesPath: path}
+
+	run := func(fn func(*profileCmd) error) (string, error) {
+		out.Reset()
+		err := fn(&profileCmd{App: &a})
+		return out.String(), err
+	}
+
+	a.DataDir = "/var/lib/brazier"
+	s, err := run(func(p *profileCmd) error { return p.Add(nil, []string{"local"}) })
+	require.NoError(t, err)
+	require.Equal(t, "Profile \"local\" successfully saved.\n", s)
+
+	a.DataDir = ""
+	a.Client = clientConfig{Host: "brazier.example.com:5657", CACert: "ca.pem", Token: "secret", Timeout: 5 * time.Second}
+	_, err = run(func(p *profileCmd) error {
+		p.Use = true
+		return p.Add(nil, []string{"prod"})
+	})
+	require.NoError(t, err)
+
+	a.Client = clientConfig{}
+	_, err = run(func(p *profileCmd) error {
+		p.Socket = "/tmp/brazier.sock"
+		return p.Add(nil, []string{"dev"})
+	})
+	require.NoError(t, err)
+
+	_, err = run(func(p *profileCmd) error { return p.Add(nil, []string{"empty"}) })
+	require.EqualError(t, err, "A profile needs a data directory, a socket or a host")
+
+	s, err = run(func(p *profileCmd) error { return p.List(nil, nil) })
+	require.NoError(t, err)
+	require.Equal(t, `CURRENT  NAME   TYPE            TARGET
+         dev    socket          /tmp/brazier.sock
+         local  data-dir        /var/lib/brazier
+*        prod   host+tls+token  brazier.example.com:5657 (timeout 5s)
+`, s)
+
+	s, err = run(func(p *profileCmd) error { return p.UseProfile(nil, []string{"local"}) })
+	require.NoError(t, err)
+	require.Equal(t, "Now using profile \"local\".\n", s)
+
+	_, err = run(func(p *profileCmd) error { return p.UseProfile(nil, []string{"unknown"}) })
+	require.EqualError(t, err, "Unknown profile \"unknown\"")
+
+	s, err = run(func(p *profileCmd) error { return p.Remove(nil, []string{"local"}) })
+	require.NoError(t, err)
+	require.Equal(t, "Profile \"local\" successfully removed.\n", s)
+
+	_, err = run(func(p *profileCmd) error { return p.Remove(nil, []string{"local"}) })
+	require.EqualError(t, err, "Unknown profile \"local\"")
+
+	profiles, err := config.LoadProfiles(path)
+	require.NoError(t, err)
+	require.Empty(t, profiles.Current)
+	require.Len(t, profiles.Profiles, 2)
+	require.Equal(t, config.Profile{
+		Host:    "brazier.example.com:5657",
+		CACert:  "ca.pem",
+		Token:   "secret",
+		Timeout: config.Duration(5 * time.Second),
+	}, profiles.Profiles["prod"])
+}
+
+func TestAppProfile(t *testing.T) {
+	newStore := func() *store.Store {
+		return store.NewStore(mock.NewRegistry(mock.NewBackend()))
+	}
+
+	srv, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	path := filepath.Join(srv.DataDir, "client", profilesFile)
+	profiles, err := config.LoadProfiles(path)
+	require.NoError(t, err)
+	profiles.Current = "dev"
+	profiles.Profiles["dev"] = config.Profile{Socket: srv.SocketPath}
+	profiles.Profiles["local"] = config.Profile{DataDir: filepath.Join(srv.DataDir, "local")}
+	err = profiles.Save(path)
+	require.NoError(t, err)
+
+	t.Run("Current", func(t *testing.T) {
+		a := app{ProfilesPath: path}
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.PostRun(nil, nil)
+		require.IsType(t, new(rpcCli), a.Cli)
+
+		err = a.Cli.Create("a/")
+		require.NoError(t, err)
+	})
+
+	t.Run("Selected", func(t *testing.T) {
+		a := app{ProfilesPath: path, Profile: "local", Store: newStore()}
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.PostRun(nil, nil)
+		require.IsType(t, new(cli), a.Cli)
+		require.Equal(t, filepath.Join(srv.DataDir, "local"), a.DataDir)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		os.Setenv(envProfile, "local")
+		defer os.Unsetenv(envProfile)
+
+		a := app{ProfilesPath: path, Store: newStore()}
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.PostRun(nil, nil)
+		require.IsType(t, new(cli), a.Cli)
+	})
+
+	t.Run("Flags", func(t *testing.T) {
+		dataDir := filepath.Join(srv.DataDir, "other")
+		a := app{ProfilesPath: path, DataDir: dataDir, Store: newStore()}
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.PostRun(nil, nil)
+		require.IsType(t, new(cli), a.Cli)
+		require.Equal(t, dataDir, a.DataDir)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		a := app{ProfilesPath: path, Profile: "unknown"}
+		err := a.PreRun(nil, nil)
+		require.EqualError(t, err, "Unknown profile \"unknown\"")
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		a := app{ProfilesPath: path, Profile: "local", DataDir: filepath.Join(srv.DataDir, "other")}
+		err := a.PreRun(nil, nil)
+		require.EqualError(t, err, "The profile \"local\" can't be used with --host or --data-dir")
+
+		a = app{ProfilesPath: path, Profile: "local", Client: clientConfig{Host: "127.0.0.1:1"}}
+		err = a.PreRun(nil, nil)
+		require.EqualError(t, err, "The profile \"local\" can't be used with --host or --data-dir")
+	})
+
+	t.Run("HostEnv", func(t *testing.T) {
+		os.Setenv(envHost, "127.0.0.1:1")
+		defer os.Unsetenv(envHost)
+
+		// the selected profile replaces the host of the environment
+		a := app{ProfilesPath: path, Profile: "local", Store: newStore()}
+		err := a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.PostRun(nil, nil)
+		require.IsType(t, new(cli), a.Cli)
+		require.Empty(t, a.Client.Host)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		malformed := filepath.Join(srv.DataDir, "malformed.yml")
+		err := ioutil.WriteFile(malformed, []byte("profiles: ["), 0600)
+		require.NoError(t, err)
+
+		// the profiles file isn't needed if no profile is selected
+		a := app{ProfilesPath: malformed, Client: clientConfig{Socket: srv.SocketPath}}
+		err = a.PreRun(nil, nil)
+		require.NoError(t, err)
+		defer a.PostRun(nil, nil)
+		require.IsType(t, new(rpcCli), a.Cli)
+
+		a = app{ProfilesPath: malformed, Profile: "local"}
+		err = a.PreRun(nil, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Can't read the profiles file")
+	})
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/asdine/brazier/json"
+	"github.com/asdine/brazier/store"
+	"github.com/spf13/cobra"
+)
+
+// NewPutCmd creates a "Put" cli command
+func NewPutCmd(a *app) *cobra.Command {
+	putCmd := putCmd{
+		App: a,
+		In:  os.Stdin,
+	}
+
+	cmd := cobra.Command{
+		Use:   "put PATH VALUE [PATH VALUE]...",
+		Short: "Set or replace a value in a bucket",
+		Long: `Set or replace a value in a bucket. A value can be anything.
+JSON values are automatically detected, use --json to require a valid JSON value
+or --raw to save the value as a string.
+The value can be read from a file with -f, or from stdin by using - as value.
+Several values can be saved at once, they are then written in large batches.
+
+With --multi, the input is a list of JSON documents, separated by new lines or in a JSON array,
+saved in the bucket at PATH using the given field as key.
+All the values are validated before anything is written.`,
+		Example: `brazier put friends/john/phone 555-666
+brazier put users/1 '{"username": "john"}'
+brazier put users/1 '{"username": "john"}' users/2 '{"username": "jack"}'
+brazier put users/1 -f john.json
+curl https://example.com/john.json | brazier put users/1 -
+brazier put notes/todo --raw '{"not": "json"}'
+brazier put users/ -f users.ndjson --multi id`,
+		RunE: putCmd.Put,
+	}
+
+	cmd.Flags().StringVarP(&putCmd.File, "file", "f", "", "read the value from a file")
+	cmd.Flags().BoolVar(&putCmd.Raw, "raw", false, "save the value as a string, even if it is valid JSON")
+	cmd.Flags().BoolVar(&putCmd.JSON, "json", false, "require the value to be valid JSON")
+	cmd.Flags().StringVar(&putCmd.Multi, "multi", "", "save a list of JSON documents, using the given field as key")
+	return &cmd
+}
+
+type putCmd struct {
+	App   *app
+	In    io.Reader
+	File  string
+	Raw   bool
+	JSON  bool
+	Multi string
+}
+
+func (s *putCmd) Put(cmd *cobra.Command, args []string) error {
+	if s.Raw && (s.JSON || s.Multi != "") {
+		return errors.New("--raw can't be used with --json or --multi")
+	}
+
+	if s.Multi != "" {
+		return s.putMulti(args)
+	}
+
+	if s.File != "" || (len(args) == 2 && args[1] == "-") {
+		if len(args) != 1 && !(s.File == "" && len(args) == 2) {
+			return errors.New("Wrong number of arguments")
+		}
+
+		data, err := s.read()
+		if err != nil {
+			return err
+		}
+
+		return s.putOne(args[0], data)
+	}
+
+	if len(args) < 2 || len(args)%2 != 0 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if len(args) > 2 {
+		return s.putBulk(args)
+	}
+
+	return s.putOne(args[0], []byte(args[1]))
+}
+
+// read the input from the selected file or from stdin.
+// The trailing new line is removed.
+func (s *putCmd) read() ([]byte, error) {
+	var data []byte
+	var err error
+
+	if s.File != "" {
+		data, err = ioutil.ReadFile(s.File)
+	} else {
+		data, err = ioutil.ReadAll(s.In)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	return bytes.TrimSuffix(data, []byte("\r")), nil
+}
+
+// value converts the input according to --raw and --json.
+func (s *putCmd) value(data []byte) ([]byte, error) {
+	switch {
+	case s.Raw:
+		return json.ToJSONString(data), nil
+	case s.JSON && !json.IsValid(data):
+		return nil, errors.New("Invalid JSON value")
+	}
+
+	return data, nil
+}
+
+func (s *putCmd) putOne(path string, data []byte) error {
+	data, err := s.value(data)
+	if err != nil {
+		return err
+	}
+
+	err = s.App.Cli.Put(path, data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.App.Out, "Item \"%s\" successfully saved.\n", path)
+	return nil
+}
+
+// putBulk saves the given list of paths and values using a BulkWriter.
+func (s *putCmd) putBulk(args []string) error {
+	values := make([][]byte, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		data, err := s.value([]byte(args[i+1]))
+		if err != nil {
+			return fmt.Errorf("Item \"%s\": %s", args[i], err)
+		}
+		values = append(values, data)
+	}
+
+	return writeBulk(s.App, func(w BulkWriter) error {
+		for i, data := range values {
+			err := w.Put(args[i*2], data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// putMulti saves every document of the input in the bucket, using the selected field as key.
+func (s *putCmd) putMulti(args []string) error {
+	if len(args) != 1 && !(s.File == "" && len(args) == 2 && args[1] == "-") {
+		return errors.New("Wrong number of arguments")
+	}
+
+	var r io.Reader = s.In
+	if s.File != "" {
+		f, err := os.Open(s.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	docs, err := decodeDocuments(r)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, len(docs))
+	for i, doc := range docs {
+		raw, err := field(doc, s.Multi)
+		if err != nil {
+			return fmt.Errorf("Document %d: %s", i+1, err)
+		}
+
+		keys[i], err = keyString(raw)
+		if err != nil {
+			return fmt.Errorf("Document %d: field %q: %s", i+1, s.Multi, err)
+		}
+	}
+
+	prefix := strings.TrimSuffix(args[0], "/") + "/"
+	return writeBulk(s.App, func(w BulkWriter) error {
+		for i, doc := range docs {
+			err := w.Put(prefix+keys[i], doc)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// writeBulk calls fn with a BulkWriter and prints the result once it's done.
+// If fn fails, the items that weren't saved yet are dropped.
+func writeBulk(a *app, fn func(BulkWriter) error) error {
+	w, err := a.Cli.Bulk()
+	if err != nil {
+		return err
+	}
+
+	err = fn(w)
+	if err != nil {
+		w.Abort()
+		return err
+	}
+
+	res, err := w.Close()
+	if err != nil {
+		return err
+	}
+
+	return printBulkResult(a, res)
+}
+
+// printBulkResult prints the summary of a bulk write and returns an error if some items failed.
+func printBulkResult(a *app, res *store.BulkResult) error {
+	fmt.Fprintf(a.Out, "%d items successfully saved.\n", res.Saved)
+	for _, f := range res.Failures {
+		fmt.Fprintf(a.Out, "Item \"%s\" failed: %s\n", f.Path, f.Err)
+	}
+
+	if len(res.Failures) > 0 {
+		return fmt.Errorf("%d items couldn't be saved", len(res.Failures))
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliPutInput(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testPutInput(t, app)
+}
+
+func TestCliRPCPutInput(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testPutInput(t, app)
+}
+
+func testPutInput(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+	g := NewGetCmd(app, false)
+
+	get := func(path string) string {
+		out.Reset()
+		err := g.RunE(nil, []string{path})
+		require.NoError(t, err)
+		return strings.TrimSpace(out.String())
+	}
+
+	run := func(s *putCmd, content string, args ...string) error {
+		s.App = app
+		s.In = strings.NewReader(content)
+		out.Reset()
+		return s.Put(nil, args)
+	}
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(app.DataDir, "value.json")
+		err := ioutil.WriteFile(path, []byte("{\"a\": 1}\n"), 0644)
+		require.NoError(t, err)
+
+		err = run(&putCmd{File: path}, "", "file/a")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"a": 1}`, get("file/a"))
+
+		err = run(&putCmd{File: path}, "", "file/a", "value")
+		require.EqualError(t, err, "Wrong number of arguments")
+
+		err = run(&putCmd{File: filepath.Join(app.DataDir, "unknown")}, "", "file/a")
+		require.Error(t, err)
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		err := run(&putCmd{}, "hello\n", "stdin/a", "-")
+		require.NoError(t, err)
+		require.Equal(t, "Item \"stdin/a\" successfully saved.\n", out.String())
+		require.Equal(t, `"hello"`, get("stdin/a"))
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		err := run(&putCmd{Raw: true}, "", "raw/a", `{"a": "<b>"}`)
+		require.NoError(t, err)
+		require.JSONEq(t, `"{\"a\": \"<b>\"}"`, get("raw/a"))
+
+		err = run(&putCmd{Raw: true}, "10\n", "raw/b", "-")
+		require.NoError(t, err)
+		require.Equal(t, `"10"`, get("raw/b"))
+
+		err = run(&putCmd{Raw: true, JSON: true}, "", "raw/c", "10")
+		require.EqualError(t, err, "--raw can't be used with --json or --multi")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		err := run(&putCmd{JSON: true}, "", "json/a", "hello")
+		require.EqualError(t, err, "Invalid JSON value")
+
+		err = run(&putCmd{JSON: true}, "", "json/b", "1", "json/c", "{")
+		require.EqualError(t, err, "Item \"json/c\": Invalid JSON value")
+
+		// nothing is written if one of the values is invalid
+		out.Reset()
+		err = g.RunE(nil, []string{"json/b"})
+		require.Error(t, err)
+
+		err = run(&putCmd{JSON: true}, "", "json/b", "[1, 2]")
+		require.NoError(t, err)
+		require.JSONEq(t, "[1, 2]", get("json/b"))
+	})
+
+	t.Run("Multi", func(t *testing.T) {
+		content := "{\"id\": \"john\", \"age\": 10}\n{\"id\": 2, \"age\": 20}\n"
+		err := run(&putCmd{Multi: "id"}, content, "multi", "-")
+		require.NoError(t, err)
+		require.Equal(t, "2 items successfully saved.\n", out.String())
+		require.JSONEq(t, `{"id": "john", "age": 10}`, get("multi/john"))
+		require.JSONEq(t, `{"id": 2, "age": 20}`, get("multi/2"))
+
+		err = run(&putCmd{Multi: "id"}, `[{"id": "jack"}, {"id": "jane"}]`, "array/", "-")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": "jane"}`, get("array/jane"))
+
+		err = run(&putCmd{Multi: "id"}, `{"id": "a"} {"name": "b"}`, "missing/", "-")
+		require.EqualError(t, err, `Document 2: Field "id" is missing`)
+
+		out.Reset()
+		err = g.RunE(nil, []string{"missing/a"})
+		require.Error(t, err)
+
+		err = run(&putCmd{Multi: "id"}, `{"id": "a"} {`, "invalid/", "-")
+		require.Error(t, err)
+
+		err = run(&putCmd{Multi: "id", Raw: true}, "", "multi", "-")
+		require.EqualError(t, err, "--raw can't be used with --json or --multi")
+	})
+}
@@ -1,72 +1,83 @@
 package cli
 
 import (
+	"io"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/client"
 	"github.com/asdine/brazier/json"
-	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
 )
 
 type rpcCli struct {
 	App    *app
-	Client proto.BucketClient
+	Client *client.Client
 }
 
 func (r *rpcCli) Create(path string) error {
-	_, err := r.Client.Create(context.Background(), &proto.Selector{Path: path})
-	return err
+	return r.Client.CreateBucket(context.Background(), path)
 }
 
 func (r *rpcCli) Put(path string, data []byte) error {
-	_, err := r.Client.Put(context.Background(), &proto.NewItem{Path: path, Value: data})
-	return err
+	return r.Client.Put(context.Background(), path, data)
 }
 
-func (r *rpcCli) Get(path string, recursive bool) ([]byte, error) {
-	var err error
-	var data []byte
+func (r *rpcCli) PutIf(path string, data []byte, updatedAt time.Time) error {
+	return r.Client.PutIf(context.Background(), path, data, updatedAt)
+}
 
+func (r *rpcCli) Get(w io.Writer, path string, recursive bool) error {
 	if strings.HasSuffix(path, "/") {
-		resp, err := r.Client.List(context.Background(), &proto.Selector{Path: path, Recursive: recursive})
-		if err != nil {
-			return nil, err
-		}
-
-		data, err = json.MarshalListPretty(r.tree(resp.Children))
-	} else {
-		item, err := r.Client.Get(context.Background(), &proto.Selector{Path: path})
-		if err != nil {
-			return nil, err
-		}
-
-		data, err = json.PrettyPrintRaw(item.Value)
+		return writeTree(w, r, path, recursive)
 	}
 
+	item, err := r.Client.Get(context.Background(), path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return append(data, '\n'), nil
+	data, err := json.PrettyPrintRaw(item.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
 }
 
-func (r *rpcCli) tree(items []*proto.Node) []brazier.Item {
-	list := make([]brazier.Item, len(items))
-	for i, item := range items {
-		list[i].Key = item.Key
-		list[i].Data = item.Value
+func (r *rpcCli) Item(path string) (*brazier.Item, error) {
+	return r.Client.Get(context.Background(), path)
+}
+
+func (r *rpcCli) Delete(path string) error {
+	return r.Client.Delete(context.Background(), path)
+}
 
-		if item.Children != nil {
-			list[i].Children = r.tree(item.Children)
-		}
+func (r *rpcCli) Bulk() (BulkWriter, error) {
+	w, err := r.Client.BulkPut(context.Background())
+	if err != nil {
+		return nil, err
 	}
 
-	return list
+	return w, nil
 }
 
-func (r *rpcCli) Delete(path string) error {
-	_, err := r.Client.Delete(context.Background(), &proto.Selector{Path: path})
-	return err
+func (r *rpcCli) Walk(path string, recursive bool, fn store.WalkFunc) error {
+	return r.Client.Walk(context.Background(), path, recursive, fn)
+}
+
+func (r *rpcCli) WalkDepth(path string, depth int, fn store.WalkFunc) error {
+	return r.Client.WalkDepth(context.Background(), path, depth, fn)
+}
+
+func (r *rpcCli) Entries(path string) ([]brazier.Item, error) {
+	return r.Client.Entries(context.Background(), path)
+}
+
+func (r *rpcCli) Buckets(path string) ([]brazier.Item, error) {
+	return r.Client.Buckets(context.Background(), path)
 }
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -11,32 +13,66 @@ import (
 	"time"
 
 	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/config"
 	"github.com/asdine/brazier/http"
+	"github.com/asdine/brazier/metrics"
 	"github.com/asdine/brazier/rpc"
 	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/asdine/brazier/tlsutil"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// envRPCToken is the environment variable used when no token is set with --rpc-token.
+const envRPCToken = "BRAZIER_RPC_TOKEN"
+
 // NewServerCmd creates a "Server" cli command
 func NewServerCmd(a *app) *cobra.Command {
 	serverCmd := serverCmd{
-		App:              a,
-		HTTPServerFunc:   http.NewServer,
-		RPCServerFunc:    rpc.NewServer,
-		SocketServerFunc: rpc.NewServer,
-		useExit:          true,
-		c:                make(chan os.Signal, 1),
+		App:     a,
+		useExit: true,
+		c:       make(chan os.Signal, 1),
 	}
+	serverCmd.HTTPServerFunc = serverCmd.newHTTPServer
+	serverCmd.RPCServerFunc = serverCmd.newRPCServer
+	serverCmd.SocketServerFunc = serverCmd.newSocketServer
+	serverCmd.AdminServerFunc = serverCmd.newAdminServer
 
 	cmd := cobra.Command{
 		Use:   "server",
 		Short: "Run Brazier as an HTTP and gRPC server",
-		Long:  "Run Brazier as an HTTP and gRPC server",
-		RunE:  serverCmd.Serve,
+		Long: `Run Brazier as an HTTP and gRPC server.
+TLS is enabled on a listener when a certificate and a key are provided,
+client certificates are verified when a client CA is provided.
+Certificates are reloaded from disk when the server receives SIGHUP.
+Health checks are served on the admin address under /healthz and /readyz
+and Prometheus metrics under /metrics, an empty admin address disables them.
+The standard gRPC health service is registered on the gRPC listeners.
+When a token is set, gRPC clients connecting over TCP must send it, clients using the local socket don't.
+The PID of the server is written in brazier.pid in the data directory, which is locked
+while the server runs. A socket left by a server that didn't stop properly is removed.
+With -d, the server runs in the background and logs to brazier.log in the data directory
+or to --log-file.`,
+		RunE: serverCmd.Serve,
 	}
 
 	cmd.Flags().StringVar(&serverCmd.App.Config.HTTP.Address, "http-addr", ":5656", "HTTP address")
+	cmd.Flags().StringVar(&serverCmd.App.Config.HTTP.TLS.CertFile, "http-cert", "", "HTTP TLS certificate file")
+	cmd.Flags().StringVar(&serverCmd.App.Config.HTTP.TLS.KeyFile, "http-key", "", "HTTP TLS key file")
+	cmd.Flags().StringVar(&serverCmd.App.Config.HTTP.TLS.ClientCAFile, "http-client-ca", "", "HTTP client CA file, used to verify client certificates")
 	cmd.Flags().StringVar(&serverCmd.App.Config.RPC.Address, "rpc-addr", "127.0.0.1:5657", "gRPC address")
+	cmd.Flags().StringVar(&serverCmd.App.Config.RPC.TLS.CertFile, "rpc-cert", "", "gRPC TLS certificate file")
+	cmd.Flags().StringVar(&serverCmd.App.Config.RPC.TLS.KeyFile, "rpc-key", "", "gRPC TLS key file")
+	cmd.Flags().StringVar(&serverCmd.App.Config.RPC.TLS.ClientCAFile, "rpc-client-ca", "", "gRPC client CA file, used to verify client certificates")
+	cmd.Flags().StringVar(&serverCmd.App.Config.RPC.Token, "rpc-token", "", "token required from the gRPC clients, empty to disable the authentication (default $BRAZIER_RPC_TOKEN)")
+	cmd.Flags().StringVar(&serverCmd.App.Config.Admin.Address, "admin-addr", defaultAdminAddr, "Admin address, serving health checks and metrics")
+	cmd.Flags().BoolVarP(&serverCmd.Daemon, "daemon", "d", false, "run the server in the background")
+	cmd.Flags().StringVar(&serverCmd.LogFile, "log-file", "", "file the server logs to, appended if it exists (default brazier.log in the data directory with -d)")
+
+	cmd.AddCommand(NewServerStatusCmd(a))
+	cmd.AddCommand(NewServerStopCmd(a))
 	return &cmd
 }
 
@@ -47,68 +83,374 @@ type serverCmd struct {
 	HTTPServerFunc   func(*store.Store) brazier.Server
 	RPCServerFunc    func(*store.Store) brazier.Server
 	SocketServerFunc func(*store.Store) brazier.Server
+	AdminServerFunc  func(*store.Store) brazier.Server
+	httpTLS          *tls.Config
+	rpcTLS           *tls.Config
+	serverTLS        []*tlsutil.ServerTLS
+	metrics          *metrics.Metrics
+	admin            *rpc.Admin
+	pidFile          *pidFile
+	Daemon           bool
+	LogFile          string
 }
 
+// daemonStartTimeout is the maximum duration of the start of a server in the background.
+const daemonStartTimeout = 10 * time.Second
+
 func (s *serverCmd) Serve(cmd *cobra.Command, args []string) error {
+	if s.App.Config.RPC.Token == "" {
+		s.App.Config.RPC.Token = os.Getenv(envRPCToken)
+	}
+
+	if s.Daemon && os.Getenv(envDaemon) == "" {
+		return s.daemonize()
+	}
+
+	if s.LogFile != "" {
+		f, err := os.OpenFile(s.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s.App.Out = f
+	}
+
 	servers, err := s.createServers()
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(s.App.Out, "Serving HTTP on address %s\n", s.App.Config.HTTP.Address)
-	fmt.Fprintf(s.App.Out, "Serving RPC on address %s\n", s.App.Config.RPC.Address)
+	fmt.Fprintf(s.App.Out, "Serving HTTP on address %s%s\n", s.App.Config.HTTP.Address, tlsInfo(s.httpTLS))
+	fmt.Fprintf(s.App.Out, "Serving RPC on address %s%s\n", s.App.Config.RPC.Address, tlsInfo(s.rpcTLS))
+	if s.App.Config.Admin.Address != "" {
+		fmt.Fprintf(s.App.Out, "Serving admin on address %s\n", s.App.Config.Admin.Address)
+	}
 
 	s.runServers(servers)
 	return nil
 }
 
-func (s *serverCmd) createServers() (map[net.Listener]brazier.Server, error) {
-	servers := make(map[net.Listener]brazier.Server)
+// daemonize runs the server in a background process.
+func (s *serverCmd) daemonize() error {
+	pid, err := runningPID(filepath.Join(s.App.DataDir, defaultPIDName))
+	if err != nil {
+		return err
+	}
+	if pid != 0 {
+		return fmt.Errorf("A server is already running with PID %d", pid)
+	}
+
+	// the databases are opened by the background process
+	if s.App.Store != nil {
+		err = s.App.Store.Close()
+		if err != nil {
+			return err
+		}
+		s.App.Store = nil
+	}
+
+	logPath := s.LogFile
+	if logPath == "" {
+		logPath = filepath.Join(s.App.DataDir, defaultLogName)
+	}
+
+	pid, err = startDaemon(filepath.Join(s.App.DataDir, defaultSocketName), logPath, daemonStartTimeout)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.App.Out, "Server started in the background with PID %d, logging to %s\n", pid, logPath)
+	return nil
+}
+
+// listener is a bound listener and the server accepting its connections.
+type listener struct {
+	l   net.Listener
+	srv brazier.Server
+}
+
+// createServers locks the PID file and creates the listeners and their servers.
+// The listeners are returned in the order their servers must be stopped,
+// the admin listener last so that the server can be monitored while it shuts down.
+func (s *serverCmd) createServers() ([]listener, error) {
+	var err error
+
+	s.pidFile, err = lockPIDFile(filepath.Join(s.App.DataDir, defaultPIDName))
+	if err != nil {
+		return nil, err
+	}
+
+	// the lock guarantees that the socket isn't used by another server
+	socketPath := filepath.Join(s.App.DataDir, defaultSocketName)
+	err = os.Remove(socketPath)
+	if err == nil {
+		fmt.Fprintf(s.App.Out, "Removed stale socket %s\n", socketPath)
+	} else if !os.IsNotExist(err) {
+		s.pidFile.Release()
+		return nil, err
+	}
+
+	servers, err := s.listen()
+	if err != nil {
+		s.pidFile.Release()
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+// listen binds the listeners. The listeners already bound are closed if an error occurs.
+func (s *serverCmd) listen() ([]listener, error) {
+	var err error
+
+	s.httpTLS, err = s.tlsConfig(&s.App.Config.HTTP.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	// gRPC requires HTTP/2 to be negotiated with ALPN
+	s.rpcTLS, err = s.tlsConfig(&s.App.Config.RPC.TLS, "h2")
+	if err != nil {
+		return nil, err
+	}
+
+	if s.App.Config.Admin.Address != "" {
+		err = s.initMetrics()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.initAdmin()
+
+	var servers []listener
+	closeAll := func() {
+		for _, ls := range servers {
+			ls.l.Close()
+		}
+	}
 
 	httpListener, err := net.Listen("tcp", s.App.Config.HTTP.Address)
 	if err != nil {
 		return nil, err
 	}
-	servers[httpListener] = s.HTTPServerFunc(s.App.Store)
+	servers = append(servers, listener{httpListener, s.HTTPServerFunc(s.App.Store)})
+	s.admin.Listeners["http"] = httpListener.Addr().String()
 
 	rpcListener, err := net.Listen("tcp", s.App.Config.RPC.Address)
 	if err != nil {
+		closeAll()
 		return nil, err
 	}
-	servers[rpcListener] = s.RPCServerFunc(s.App.Store)
+	servers = append(servers, listener{rpcListener, s.RPCServerFunc(s.App.Store)})
+	s.admin.Listeners["rpc"] = rpcListener.Addr().String()
 
-	socketListener, err := net.Listen("unix", filepath.Join(s.App.DataDir, "brazier.sock"))
+	socketListener, err := net.Listen("unix", filepath.Join(s.App.DataDir, defaultSocketName))
 	if err != nil {
+		closeAll()
 		return nil, err
 	}
-	servers[socketListener] = s.SocketServerFunc(s.App.Store)
+	servers = append(servers, listener{socketListener, s.SocketServerFunc(s.App.Store)})
+	s.admin.Listeners["socket"] = socketListener.Addr().String()
+
+	if s.App.Config.Admin.Address != "" {
+		adminListener, err := net.Listen("tcp", s.App.Config.Admin.Address)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		servers = append(servers, listener{adminListener, s.AdminServerFunc(s.App.Store)})
+		s.admin.Listeners["admin"] = adminListener.Addr().String()
+	}
 
 	return servers, nil
 }
 
-func (s *serverCmd) runServers(servers map[net.Listener]brazier.Server) {
+// initAdmin prepares the Admin gRPC service, registered on the gRPC listeners.
+func (s *serverCmd) initAdmin() {
+	s.admin = &rpc.Admin{
+		Store:     s.App.Store,
+		Databases: make(map[string]rpc.Database),
+		Listeners: make(map[string]string),
+		StartTime: time.Now(),
+		TempDir:   s.App.DataDir,
+	}
+
+	r, ok := s.App.Store.Registry.(*boltdb.Registry)
+	if !ok {
+		return
+	}
+	s.admin.Databases["registry"] = r
+
+	if b, ok := r.Backend.(*boltdb.Backend); ok {
+		s.admin.Databases["backend"] = b
+	}
+}
+
+// initMetrics instruments the store and collects the statistics of the BoltDB databases.
+func (s *serverCmd) initMetrics() error {
+	s.metrics = metrics.New()
+	s.App.Store.Observer = s.metrics
+
+	r, ok := s.App.Store.Registry.(*boltdb.Registry)
+	if !ok {
+		return nil
+	}
+
+	err := s.metrics.RegisterBoltDB("registry", r)
+	if err != nil {
+		return err
+	}
+
+	if b, ok := r.Backend.(*boltdb.Backend); ok {
+		return s.metrics.RegisterBoltDB("backend", b)
+	}
+
+	return nil
+}
+
+func (s *serverCmd) tlsConfig(cfg *config.TLS, nextProtos ...string) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("A certificate and a key are required to verify client certificates")
+		}
+		return nil, nil
+	}
+
+	st, err := tlsutil.NewServerTLS(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	s.serverTLS = append(s.serverTLS, st)
+
+	return st.Config(nextProtos...), nil
+}
+
+func (s *serverCmd) newHTTPServer(st *store.Store) brazier.Server {
+	cfg := s.App.Config.HTTP
+	opts := []http.ServerOption{
+		http.WithReadTimeout(time.Duration(cfg.ReadTimeout)),
+		http.WithWriteTimeout(time.Duration(cfg.WriteTimeout)),
+		http.WithIdleTimeout(time.Duration(cfg.IdleTimeout)),
+		http.WithShutdownTimeout(time.Duration(cfg.ShutdownTimeout)),
+	}
+
+	if s.httpTLS != nil {
+		opts = append(opts, http.WithTLS(s.httpTLS))
+	}
+
+	if s.metrics != nil {
+		opts = append(opts, http.WithHandlerOptions(http.WithMiddleware(s.metrics.HTTPMiddleware)))
+	}
+
+	return http.NewServer(st, opts...)
+}
+
+func (s *serverCmd) newRPCServer(st *store.Store) brazier.Server {
+	opts := append(s.rpcOptions(), rpc.WithToken(s.App.Config.RPC.Token))
+
+	if s.rpcTLS != nil {
+		opts = append(opts, rpc.WithGRPCOptions(grpc.Creds(credentials.NewTLS(s.rpcTLS))))
+	}
+
+	return rpc.NewServer(st, opts...)
+}
+
+func (s *serverCmd) newSocketServer(st *store.Store) brazier.Server {
+	return rpc.NewServer(st, s.rpcOptions()...)
+}
+
+func (s *serverCmd) newAdminServer(st *store.Store) brazier.Server {
+	h := http.AdminHandler{
+		Store: st,
+	}
+
+	if s.metrics != nil {
+		h.Metrics = s.metrics.Handler()
+	}
+
+	return http.NewAdminServer(&h)
+}
+
+// rpcOptions returns the options shared by the gRPC servers.
+func (s *serverCmd) rpcOptions() []rpc.ServerOption {
+	opts := []rpc.ServerOption{rpc.WithAdmin(s.admin)}
+
+	if s.metrics != nil {
+		opts = append(opts, rpc.WithInterceptors(s.metrics.UnaryServerInterceptor, s.metrics.StreamServerInterceptor))
+	}
+
+	return opts
+}
+
+// reloadCertificates reloads all the certificates and client authorities from disk.
+func (s *serverCmd) reloadCertificates() {
+	for _, st := range s.serverTLS {
+		err := st.Reload()
+		if err != nil {
+			fmt.Fprintf(s.App.Out, "Failed to reload certificate: %s\n", err)
+			continue
+		}
+		fmt.Fprintf(s.App.Out, "Certificate %s reloaded\n", st.CertFile)
+	}
+}
+
+func (s *serverCmd) runServers(servers []listener) {
 	var wg sync.WaitGroup
 
-	for l, srv := range servers {
+	for _, ls := range servers {
 		wg.Add(1)
-		go func(l net.Listener, srv brazier.Server) {
+		go func(ls listener) {
 			defer wg.Done()
-			srv.Serve(l)
-		}(l, srv)
+			ls.srv.Serve(ls.l)
+		}(ls)
 	}
 
-	signal.Notify(s.c, os.Interrupt, syscall.SIGTERM)
+	// listeners are already bound, connections are queued until they are accepted.
+	setReady(servers, true)
+
+	signal.Notify(s.c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
-		for _ = range s.c {
+		for sig := range s.c {
+			if sig == syscall.SIGHUP {
+				s.reloadCertificates()
+				continue
+			}
+
 			fmt.Fprintf(s.App.Out, "\nStopping servers...")
-			for _, srv := range servers {
-				srv.Stop(time.Second)
+			setReady(servers, false)
+			for _, ls := range servers {
+				ls.srv.Stop(time.Second)
 			}
 			fmt.Fprintf(s.App.Out, " OK\n")
 			if s.useExit {
+				s.pidFile.Release()
 				os.Exit(1)
 			}
 		}
 	}()
 
 	wg.Wait()
+	signal.Stop(s.c)
+	s.pidFile.Release()
+}
+
+// setReady marks all the servers implementing brazier.Readiness as ready or not ready.
+func setReady(servers []listener, ready bool) {
+	for _, ls := range servers {
+		if r, ok := ls.srv.(brazier.Readiness); ok {
+			r.SetReady(ready)
+		}
+	}
+}
+
+func tlsInfo(cfg *tls.Config) string {
+	if cfg == nil {
+		return ""
+	}
+
+	if cfg.ClientAuth == tls.RequireAndVerifyClientCert {
+		return " with mutual TLS"
+	}
+
+	return " with TLS"
 }
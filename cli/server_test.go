@@ -1,12 +1,18 @@
 package cli
 
 import (
+	"crypto/tls"
+	"net"
 	"os"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/tlsutil/tlstest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,8 +34,36 @@ func TestCreateServers(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, servers, 3)
 
-	for l := range servers {
-		err = l.Close()
+	for _, ls := range servers {
+		err = ls.l.Close()
+		require.NoError(t, err)
+	}
+}
+
+func TestCreateServersWithAdmin(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	app.Config.HTTP.Address = ":"
+	app.Config.RPC.Address = ":"
+	app.Config.Admin.Address = "127.0.0.1:"
+
+	s := serverCmd{
+		App:              app,
+		HTTPServerFunc:   mock.NewServer,
+		RPCServerFunc:    mock.NewServer,
+		SocketServerFunc: mock.NewServer,
+		AdminServerFunc:  mock.NewServer,
+	}
+
+	servers, err := s.createServers()
+	require.NoError(t, err)
+	require.Len(t, servers, 4)
+	require.NotNil(t, s.metrics)
+	require.Equal(t, s.metrics, app.Store.Observer)
+
+	for _, ls := range servers {
+		err = ls.l.Close()
 		require.NoError(t, err)
 	}
 }
@@ -58,14 +92,205 @@ func TestRunServers(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		s.runServers(servers)
-		for _, srv := range servers {
-			m := srv.(*mock.Server)
+		for _, ls := range servers {
+			m := ls.srv.(*mock.Server)
 			require.True(t, m.ServeInvoked)
 			require.True(t, m.StopInvoked)
+			require.False(t, m.Ready)
 		}
 	}()
 
 	time.Sleep(100 * time.Millisecond)
+	for _, ls := range servers {
+		require.True(t, ls.srv.(*mock.Server).Ready)
+	}
 	s.c <- os.Interrupt
 	wg.Wait()
 }
+
+// orderedServer records the order in which the servers are stopped.
+type orderedServer struct {
+	*mock.Server
+	name    string
+	stopped *[]string
+}
+
+func (s *orderedServer) Stop(timeout time.Duration) {
+	*s.stopped = append(*s.stopped, s.name)
+	s.Server.Stop(timeout)
+}
+
+func TestRunServersStopOrder(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	app.Config.HTTP.Address = ":"
+	app.Config.RPC.Address = ":"
+	app.Config.Admin.Address = "127.0.0.1:"
+
+	var stopped []string
+	serverFunc := func(name string) func(*store.Store) brazier.Server {
+		return func(st *store.Store) brazier.Server {
+			return &orderedServer{Server: mock.NewServer(st).(*mock.Server), name: name, stopped: &stopped}
+		}
+	}
+
+	s := serverCmd{
+		App:              app,
+		HTTPServerFunc:   serverFunc("http"),
+		RPCServerFunc:    serverFunc("rpc"),
+		SocketServerFunc: serverFunc("socket"),
+		AdminServerFunc:  serverFunc("admin"),
+		c:                make(chan os.Signal, 1),
+	}
+
+	servers, err := s.createServers()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.runServers(servers)
+	}()
+
+	s.c <- os.Interrupt
+	wg.Wait()
+	require.Equal(t, []string{"http", "rpc", "socket", "admin"}, stopped)
+}
+
+func TestCreateServersCloseListeners(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	// the admin address is already in use
+	busy, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	app.Config.HTTP.Address = "127.0.0.1:"
+	app.Config.RPC.Address = "127.0.0.1:"
+	app.Config.Admin.Address = busy.Addr().String()
+
+	s := serverCmd{
+		App:              app,
+		HTTPServerFunc:   mock.NewServer,
+		RPCServerFunc:    mock.NewServer,
+		SocketServerFunc: mock.NewServer,
+		AdminServerFunc:  mock.NewServer,
+	}
+
+	_, err = s.createServers()
+	require.Error(t, err)
+
+	// the listeners bound before the failure are closed
+	for _, name := range []string{"http", "rpc"} {
+		l, err := net.Listen("tcp", s.admin.Listeners[name])
+		require.NoError(t, err)
+		l.Close()
+	}
+	_, err = os.Stat(s.admin.Listeners["socket"])
+	require.True(t, os.IsNotExist(err))
+
+	// the PID file is released
+	_, err = s.createServers()
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "already running")
+}
+
+func TestServersTLS(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	ca, err := tlstest.NewCA(app.DataDir)
+	require.NoError(t, err)
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+
+	app.Config.HTTP.Address = ":"
+	app.Config.RPC.Address = ":"
+	app.Config.HTTP.TLS.CertFile = certFile
+	app.Config.HTTP.TLS.KeyFile = keyFile
+	app.Config.RPC.TLS.CertFile = certFile
+	app.Config.RPC.TLS.KeyFile = keyFile
+	app.Config.RPC.TLS.ClientCAFile = ca.CertFile
+
+	s := serverCmd{
+		App:              app,
+		HTTPServerFunc:   mock.NewServer,
+		RPCServerFunc:    mock.NewServer,
+		SocketServerFunc: mock.NewServer,
+		c:                make(chan os.Signal, 1),
+	}
+
+	servers, err := s.createServers()
+	require.NoError(t, err)
+	require.NotNil(t, s.httpTLS)
+	require.Equal(t, tls.NoClientCert, s.httpTLS.ClientAuth)
+	require.NotNil(t, s.rpcTLS)
+	require.Equal(t, tls.RequireAndVerifyClientCert, s.rpcTLS.ClientAuth)
+	require.Equal(t, []string{"h2"}, s.rpcTLS.NextProtos)
+	require.Len(t, s.serverTLS, 2)
+
+	// current configuration of the gRPC server
+	current := func() *tls.Config {
+		cfg, err := s.rpcTLS.GetConfigForClient(nil)
+		require.NoError(t, err)
+		return cfg
+	}
+	before := current()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.runServers(servers)
+	}()
+
+	// the authority and the certificates are replaced
+	ca, err = tlstest.NewCA(app.DataDir)
+	require.NoError(t, err)
+	_, _, err = ca.Issue("server")
+	require.NoError(t, err)
+	s.c <- syscall.SIGHUP
+
+	for i := 0; i < 50 && current().ClientCAs == before.ClientCAs; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	after := current()
+	certBefore, err := before.GetCertificate(nil)
+	require.NoError(t, err)
+	certAfter, err := after.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotEqual(t, certBefore.Certificate[0], certAfter.Certificate[0])
+	require.False(t, before.ClientCAs.Equal(after.ClientCAs))
+	require.Equal(t, []string{"h2"}, after.NextProtos)
+
+	s.c <- os.Interrupt
+	wg.Wait()
+}
+
+func TestServersTLSInvalid(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	app.Config.HTTP.Address = ":"
+	app.Config.RPC.Address = ":"
+
+	s := serverCmd{
+		App:              app,
+		HTTPServerFunc:   mock.NewServer,
+		RPCServerFunc:    mock.NewServer,
+		SocketServerFunc: mock.NewServer,
+	}
+
+	app.Config.HTTP.TLS.ClientCAFile = "ca.pem"
+	_, err := s.createServers()
+	require.Error(t, err)
+
+	app.Config.HTTP.TLS.ClientCAFile = ""
+	app.Config.HTTP.TLS.CertFile = "cert.pem"
+	app.Config.HTTP.TLS.KeyFile = "key.pem"
+	_, err = s.createServers()
+	require.Error(t, err)
+}
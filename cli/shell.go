@@ -0,0 +1,397 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/asdine/brazier"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// errStopWalk is used to stop a walk once the first node was visited.
+var errStopWalk = errors.New("stop walk")
+
+// shellCommands lists the commands available in the shell, with their usage.
+var shellCommands = [][2]string{
+	{"cd", "cd [PATH]             change the current bucket, the root bucket by default"},
+	{"ls", "ls [-l] [PATH]        list the items and buckets of a bucket"},
+	{"get", "get [-o FORMAT] PATH  display a value or the content of a bucket"},
+	{"put", "put PATH VALUE        set or replace a value"},
+	{"rm", "rm PATH               delete an item"},
+	{"tree", "tree [PATH]           display the tree of a bucket"},
+	{"pwd", "pwd                   display the current bucket"},
+	{"history", "history               display the command history"},
+	{"help", "help                  display this help"},
+	{"exit", "exit                  leave the shell"},
+}
+
+// NewShellCmd creates a "Shell" cli command
+func NewShellCmd(a *app) *cobra.Command {
+	shellCmd := shellCmd{
+		App: a,
+		In:  os.Stdin,
+	}
+
+	cmd := cobra.Command{
+		Use:   "shell",
+		Short: "Start an interactive shell",
+		Long: `Start an interactive shell to explore and modify the store.
+The store or the connection to the server stays open until the shell is closed.
+Paths are relative to the current bucket, which can be changed with cd.
+Bucket and key names are completed with the Tab key and the previous commands
+are available with the Up and Down keys.
+Type help to list the available commands.`,
+		RunE: shellCmd.Shell,
+	}
+
+	return &cmd
+}
+
+type shellCmd struct {
+	App *app
+	In  io.Reader
+	// Dir is the path of the current bucket, empty for the root bucket.
+	Dir     string
+	history []string
+}
+
+func (s *shellCmd) Shell(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if f, ok := s.In.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		return s.interactive(f)
+	}
+
+	scanner := bufio.NewScanner(s.In)
+	for scanner.Scan() {
+		if !s.exec(scanner.Text()) {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+// interactive runs the shell in a terminal, with completion and history.
+func (s *shellCmd) interactive(f *os.File) error {
+	fd := int(f.Fd())
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+
+	t := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{f, s.App.Out}, s.prompt())
+	t.AutoCompleteCallback = s.complete
+
+	if width, height, err := terminal.GetSize(fd); err == nil {
+		t.SetSize(width, height)
+	}
+
+	// the terminal translates new lines while in raw mode
+	out := s.App.Out
+	s.App.Out = t
+	defer func() {
+		s.App.Out = out
+	}()
+
+	for {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !s.exec(line) {
+			return nil
+		}
+		t.SetPrompt(s.prompt())
+	}
+}
+
+func (s *shellCmd) prompt() string {
+	return fmt.Sprintf("brazier:/%s> ", s.Dir)
+}
+
+// exec runs a line and returns false if the shell must be closed.
+func (s *shellCmd) exec(line string) bool {
+	name, rest := nextArg(line)
+	if name == "" {
+		return true
+	}
+	s.history = append(s.history, strings.TrimSpace(line))
+
+	var err error
+	switch name {
+	case "exit", "quit":
+		return false
+	case "cd":
+		err = s.cd(rest)
+	case "ls":
+		err = s.ls(rest)
+	case "get":
+		err = s.get(rest)
+	case "put":
+		err = s.put(rest)
+	case "rm":
+		err = s.rm(rest)
+	case "tree":
+		err = s.tree(rest)
+	case "pwd":
+		fmt.Fprintf(s.App.Out, "/%s\n", s.Dir)
+	case "history":
+		for i, l := range s.history {
+			fmt.Fprintf(s.App.Out, "%4d  %s\n", i+1, l)
+		}
+	case "help":
+		for _, c := range shellCommands {
+			fmt.Fprintln(s.App.Out, c[1])
+		}
+	default:
+		err = fmt.Errorf("Unknown command %q, type help to list the available commands", name)
+	}
+
+	if err != nil {
+		fmt.Fprintf(s.App.Out, "Error: %s\n", err)
+	}
+
+	return true
+}
+
+func (s *shellCmd) cd(args string) error {
+	path, _ := nextArg(args)
+	dir := s.resolve(path, true)
+
+	if dir != "/" {
+		err := s.App.Cli.Walk(dir, false, func(string, int, *brazier.Item) error {
+			return errStopWalk
+		})
+		if err != nil && err != errStopWalk {
+			return err
+		}
+	}
+
+	s.Dir = strings.TrimPrefix(dir, "/")
+	return nil
+}
+
+func (s *shellCmd) ls(args string) error {
+	path, rest := nextArg(args)
+
+	var long bool
+	if path == "-l" {
+		long = true
+		path, _ = nextArg(rest)
+	}
+
+	return listBucket(s.App.Out, s.App.Cli, s.resolve(path, true), long)
+}
+
+func (s *shellCmd) get(args string) error {
+	path, rest := nextArg(args)
+
+	var format string
+	if path == "-o" {
+		format, rest = nextArg(rest)
+		path, _ = nextArg(rest)
+	}
+
+	if path == "" {
+		return errors.New("Path is missing")
+	}
+
+	return printOutput(s.App.Out, s.App.Cli, s.resolve(path, false), false, format)
+}
+
+func (s *shellCmd) put(args string) error {
+	path, value := nextArg(args)
+	value = strings.TrimSpace(value)
+	if path == "" || value == "" {
+		return errors.New("Usage: put PATH VALUE")
+	}
+
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = value[1 : len(value)-1]
+	}
+
+	path = s.resolve(path, false)
+	err := s.App.Cli.Put(path, []byte(value))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.App.Out, "Item \"%s\" successfully saved.\n", path)
+	return nil
+}
+
+func (s *shellCmd) rm(args string) error {
+	path, _ := nextArg(args)
+	if path == "" {
+		return errors.New("Path is missing")
+	}
+
+	path = s.resolve(path, false)
+	err := s.App.Cli.Delete(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.App.Out, "Item \"%s\" successfully deleted.\n", path)
+	return nil
+}
+
+func (s *shellCmd) tree(args string) error {
+	path, _ := nextArg(args)
+
+	return printTree(s.App.Out, s.App.Cli, s.resolve(path, true), 0, false)
+}
+
+// resolve converts a path relative to the current bucket to a full path.
+// The path of a bucket always ends with a '/'.
+func (s *shellCmd) resolve(p string, bucket bool) string {
+	base := p
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		base = p[i+1:]
+	}
+	bucket = bucket || base == "" || base == "." || base == ".."
+
+	if !strings.HasPrefix(p, "/") {
+		p = s.Dir + p
+	}
+
+	var nodes []string
+	for _, n := range strings.Split(p, "/") {
+		switch n {
+		case "", ".":
+		case "..":
+			if len(nodes) > 0 {
+				nodes = nodes[:len(nodes)-1]
+			}
+		default:
+			nodes = append(nodes, n)
+		}
+	}
+
+	path := strings.Join(nodes, "/")
+	if bucket {
+		path += "/"
+	}
+
+	return path
+}
+
+// complete is called by the terminal when a key is pressed, it completes
+// the command name or the path under the cursor when Tab is pressed.
+func (s *shellCmd) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head := line[:pos]
+	start := strings.LastIndexAny(head, " \t") + 1
+	word := head[start:]
+
+	var candidates []string
+	if strings.TrimSpace(head[:start]) == "" {
+		for _, c := range shellCommands {
+			if strings.HasPrefix(c[0], word) {
+				candidates = append(candidates, c[0])
+			}
+		}
+	} else {
+		name, _ := nextArg(head)
+		candidates = s.completePath(word, name == "cd")
+	}
+
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	completion := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(completion, "/") {
+		completion += " "
+	}
+
+	return head[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// completePath returns the children of the bucket of the given partial path
+// whose name starts with the last element of the path.
+func (s *shellCmd) completePath(word string, bucketsOnly bool) []string {
+	dir := word[:strings.LastIndex(word, "/")+1]
+	return pathCandidates(s.App.Cli, s.resolve(dir, true), word, bucketsOnly)
+}
+
+// pathCandidates returns the items and buckets of the bucket whose path, as written in word,
+// starts with word. The bucket is the resolved path of the part of word preceding its last '/'.
+func pathCandidates(c Cli, bucket, word string, bucketsOnly bool) []string {
+	dir := word[:strings.LastIndex(word, "/")+1]
+	partial := word[len(dir):]
+
+	items, err := c.Entries(bucket)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, item := range items {
+		if bucketsOnly && !strings.HasSuffix(item.Key, "/") {
+			continue
+		}
+
+		if strings.HasPrefix(item.Key, partial) {
+			candidates = append(candidates, dir+item.Key)
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
+
+// commonPrefix returns the longest prefix shared by all the strings.
+func commonPrefix(list []string) string {
+	prefix := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
+
+// nextArg returns the first argument of the line and the rest of the line.
+// Arguments are separated by spaces, unless they are quoted.
+func nextArg(line string) (string, string) {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" {
+		return "", ""
+	}
+
+	if q := line[0]; q == '"' || q == '\'' {
+		if end := strings.IndexByte(line[1:], q); end >= 0 {
+			return line[1 : end+1], line[end+2:]
+		}
+	}
+
+	end := strings.IndexAny(line, " \t")
+	if end < 0 {
+		return line, ""
+	}
+
+	return line[:end], line[end:]
+}
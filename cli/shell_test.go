@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliShell(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testShell(t, app)
+}
+
+func TestCliRPCShell(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testShell(t, app)
+}
+
+func testShell(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"users/john", `{"name": "John"}`,
+		"users/jack", `{"name": "Jack"}`,
+		"users/admins/root", `"Root"`,
+	})
+	require.NoError(t, err)
+
+	s := shellCmd{App: app}
+	run := func(lines ...string) string {
+		s.In = strings.NewReader(strings.Join(lines, "\n"))
+		out.Reset()
+		err := s.Shell(nil, nil)
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	t.Run("Navigation", func(t *testing.T) {
+		require.Equal(t, "/users/\n", run("cd users", "pwd"))
+		require.Equal(t, "john\njack\nadmins/\n", run("ls"))
+		require.Equal(t, "root\n", run("ls admins"))
+		require.Equal(t, "users/\n", run("ls /"))
+		require.Equal(t, "/users/admins/\n", run("cd admins/", "pwd"))
+		require.Equal(t, "/\n", run("cd ../..", "pwd"))
+		require.Equal(t, "Error: not found\n/\n", run("cd unknown", "pwd"))
+		require.Equal(t, "", run("cd users", "exit", "pwd"))
+	})
+
+	t.Run("Tree", func(t *testing.T) {
+		require.Equal(t, "/\n└── users/\n    ├── john\n    ├── jack\n    └── admins/\n        └── root\n\n2 buckets, 3 items\n", run("tree /"))
+		require.Equal(t, "users/admins/\n└── root\n\n0 buckets, 1 item\n", run("tree admins"))
+	})
+
+	t.Run("Items", func(t *testing.T) {
+		require.Equal(t, "\"Root\"\n", run("get admins/root"))
+		require.Equal(t, "Root\n", run("get -o raw admins/root"))
+		require.Equal(t, "Item \"users/bob\" successfully saved.\n", run(`put bob {"name": "Bob"}`))
+		require.JSONEq(t, `{"name": "Bob"}`, run("get /users/bob"))
+		run(`put quoted '"a b"'`)
+		require.Equal(t, "\"a b\"\n", run("get quoted"))
+		require.Equal(t, "Item \"users/bob\" successfully deleted.\n", run("rm ../users/bob"))
+		require.Equal(t, "Error: not found\n", run("get bob"))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		require.Equal(t, "Error: Unknown command \"mv\", type help to list the available commands\n", run("mv a b"))
+		require.Equal(t, "Error: Usage: put PATH VALUE\n", run("put a"))
+		s.history = nil
+		require.Equal(t, "   1  pwd\n   2  history\n", run("pwd", "", "history")[len("/users/\n"):])
+	})
+
+	t.Run("Completion", func(t *testing.T) {
+		s.Dir = ""
+
+		line, pos, ok := s.complete("tr", 2, '\t')
+		require.True(t, ok)
+		require.Equal(t, "tree ", line)
+		require.Equal(t, 5, pos)
+
+		line, _, ok = s.complete("get us", 6, '\t')
+		require.True(t, ok)
+		require.Equal(t, "get users/", line)
+
+		line, _, ok = s.complete("get users/j", 11, '\t')
+		require.True(t, ok)
+		require.Equal(t, "get users/j", line)
+
+		line, _, ok = s.complete("get users/a", 11, '\t')
+		require.True(t, ok)
+		require.Equal(t, "get users/admins/", line)
+
+		line, _, ok = s.complete("get users/jo", 12, '\t')
+		require.True(t, ok)
+		require.Equal(t, "get users/john ", line)
+
+		line, _, ok = s.complete("cd users/", 9, '\t')
+		require.True(t, ok)
+		require.Equal(t, "cd users/admins/", line)
+
+		_, _, ok = s.complete("get users/z", 11, '\t')
+		require.False(t, ok)
+
+		_, _, ok = s.complete("get users/j", 11, 'a')
+		require.False(t, ok)
+	})
+}
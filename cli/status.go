@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultAdminAddr = "127.0.0.1:5658"
+	statusTimeout    = 2 * time.Second
+	stopTimeout      = 10 * time.Second
+)
+
+// NewServerStatusCmd creates a "Server status" cli command
+func NewServerStatusCmd(a *app) *cobra.Command {
+	statusCmd := serverStatusCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "status",
+		Short: "Show the health of a running server",
+		Long: `Show the health of a running server.
+The PID of a local server is read from brazier.pid in the data directory.
+The gRPC health service is queried through the socket, or through --host if set.
+The /healthz and /readyz endpoints are queried on the admin address.`,
+		// the databases are locked by the server, even if it doesn't answer
+		PersistentPreRunE: a.initServerCli,
+		RunE:              statusCmd.Status,
+	}
+
+	cmd.Flags().StringVar(&statusCmd.App.Config.Admin.Address, "admin-addr", defaultAdminAddr, "Admin address of the server, empty to skip the HTTP checks")
+	return &cmd
+}
+
+type serverStatusCmd struct {
+	App *app
+}
+
+func (s *serverStatusCmd) Status(cmd *cobra.Command, args []string) error {
+	var pid int
+	if s.App.Client.Host == "" {
+		var err error
+		pid, err = runningPID(filepath.Join(s.App.DataDir, defaultPIDName))
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.App.conn == nil {
+		if pid != 0 {
+			return fmt.Errorf("A server is running with PID %d but doesn't answer on %s", pid, s.App.SocketPath)
+		}
+		return errors.New("No server is running")
+	}
+
+	if pid != 0 {
+		fmt.Fprintf(s.App.Out, "PID: %d\n", pid)
+	}
+
+	healthy := s.checkRPC()
+
+	if s.App.Config.Admin.Address != "" {
+		client := http.Client{Timeout: statusTimeout}
+		for _, endpoint := range []string{"/healthz", "/readyz"} {
+			healthy = s.checkHTTP(&client, endpoint) && healthy
+		}
+	}
+
+	if !healthy {
+		return errors.New("Server is not ready")
+	}
+
+	return nil
+}
+
+func (s *serverStatusCmd) checkRPC() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(s.App.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		fmt.Fprintf(s.App.Out, "gRPC: unreachable (%s)\n", grpc.ErrorDesc(err))
+		return false
+	}
+
+	fmt.Fprintf(s.App.Out, "gRPC: %s\n", resp.Status)
+	return resp.Status == healthpb.HealthCheckResponse_SERVING
+}
+
+func (s *serverStatusCmd) checkHTTP(client *http.Client, endpoint string) bool {
+	resp, err := client.Get("http://" + s.App.Config.Admin.Address + endpoint)
+	if err != nil {
+		fmt.Fprintf(s.App.Out, "HTTP %s: unreachable (%s)\n", endpoint, err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+	fmt.Fprintf(s.App.Out, "HTTP %s: %s\n", endpoint, strings.TrimSpace(line))
+	return resp.StatusCode == http.StatusOK
+}
+
+// NewServerStopCmd creates a "Server stop" cli command
+func NewServerStopCmd(a *app) *cobra.Command {
+	stopCmd := serverStopCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "stop",
+		Short: "Stop the server running on the data directory",
+		Long: `Stop the server running on the data directory.
+The server is found with brazier.pid and receives SIGTERM, the command returns once it is stopped.`,
+		// the databases are locked by the server
+		PersistentPreRunE: stopCmd.PreRun,
+		RunE:              stopCmd.Stop,
+	}
+
+	cmd.Flags().DurationVar(&stopCmd.Timeout, "wait", stopTimeout, "maximum duration to wait for the server to stop")
+	return &cmd
+}
+
+type serverStopCmd struct {
+	App     *app
+	Timeout time.Duration
+}
+
+// PreRun finds the data directory without opening the store or connecting to the server.
+func (s *serverStopCmd) PreRun(cmd *cobra.Command, args []string) error {
+	s.App.Client.loadEnv()
+	err := s.App.applyProfile()
+	if err != nil {
+		return err
+	}
+
+	if s.App.Client.Host != "" {
+		return errors.New("Only local servers can be stopped")
+	}
+
+	if s.App.Client.Socket != "" {
+		s.App.DataDir = filepath.Dir(s.App.Client.Socket)
+		return nil
+	}
+
+	return s.App.initDataDir()
+}
+
+func (s *serverStopCmd) Stop(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	path := filepath.Join(s.App.DataDir, defaultPIDName)
+	pid, err := runningPID(path)
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		return errors.New("No server is running")
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	err = p.Signal(syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.Timeout)
+	for {
+		pid, err := runningPID(path)
+		if err != nil {
+			return err
+		}
+		if pid == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("The server with PID %d didn't stop after %s", pid, s.Timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	fmt.Fprintln(s.App.Out, "Server stopped.")
+	return nil
+}
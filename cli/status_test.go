@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerStatus(t *testing.T) {
+	t.Run("NotRunning", func(t *testing.T) {
+		app, cleanup := testableApp(t)
+		defer cleanup()
+
+		s := serverStatusCmd{App: app}
+		err := s.Status(nil, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("NoDataDir", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "brazier")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		dataDir := filepath.Join(dir, "data")
+		a := app{Out: new(bytes.Buffer), DataDir: dataDir, ProfilesPath: filepath.Join(dir, profilesFile)}
+		cmd := NewServerStatusCmd(&a)
+		err = cmd.PersistentPreRunE(cmd, nil)
+		require.NoError(t, err)
+		err = cmd.RunE(cmd, nil)
+		require.EqualError(t, err, "No server is running")
+
+		// the data directory isn't created
+		_, err = os.Stat(dataDir)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("NotAnswering", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "brazier")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		defer hungServer(t, dir)()
+
+		a := app{Out: new(bytes.Buffer), DataDir: dir, ProfilesPath: filepath.Join(dir, profilesFile)}
+		cmd := NewServerStatusCmd(&a)
+		err = cmd.PersistentPreRunE(cmd, nil)
+		require.NoError(t, err)
+		err = cmd.RunE(cmd, nil)
+		require.EqualError(t, err, fmt.Sprintf("A server is running with PID %d but doesn't answer on %s", os.Getpid(), a.SocketPath))
+		require.Nil(t, a.Store)
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		app, cleanup := testableAppRPC(t)
+		defer cleanup()
+
+		var err error
+		s := serverStatusCmd{App: app}
+		// readiness is set once all the servers are launched
+		for i := 0; i < 50; i++ {
+			app.Out = new(bytes.Buffer)
+			err = s.Status(nil, nil)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("PID: %d\ngRPC: SERVING\n", os.Getpid()), app.Out.(*bytes.Buffer).String())
+	})
+}
+
+// hungServer simulates a server holding the PID file and the databases of the directory
+// without answering on its socket.
+func hungServer(t *testing.T, dir string) func() {
+	pf, err := lockPIDFile(filepath.Join(dir, defaultPIDName))
+	require.NoError(t, err)
+	db, err := bolt.Open(filepath.Join(dir, defaultDBName), 0644, nil)
+	require.NoError(t, err)
+	l, err := net.Listen("unix", filepath.Join(dir, defaultSocketName))
+	require.NoError(t, err)
+
+	return func() {
+		l.Close()
+		db.Close()
+		pf.Release()
+	}
+}
+
+func TestServerStop(t *testing.T) {
+	srv, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	out := new(bytes.Buffer)
+	s := serverStopCmd{App: &app{Out: out, DataDir: srv.DataDir}, Timeout: 5 * time.Second}
+	err := s.Stop(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Server stopped.\n", out.String())
+
+	_, err = os.Stat(filepath.Join(srv.DataDir, defaultPIDName))
+	require.True(t, os.IsNotExist(err))
+
+	err = s.Stop(nil, nil)
+	require.EqualError(t, err, "No server is running")
+}
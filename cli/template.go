@@ -0,0 +1,445 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/store"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+// NewTemplateCmd creates a "Template" cli command
+func NewTemplateCmd(a *app) *cobra.Command {
+	templateCmd := templateCmd{
+		App: a,
+		Err: os.Stderr,
+	}
+
+	cmd := cobra.Command{
+		Use:   "template IN OUT",
+		Short: "Render a template with values from the store",
+		Long: `Render a Go text/template file with values from the store and write the result to OUT.
+OUT is only written if its content changes, the reload command being run after it is written.
+
+Functions available in the template:
+  key PATH                  value of an item, strings without quotes and other values as compact JSON
+  keyOrDefault PATH VALUE   same as key, VALUE being returned if the item doesn't exist
+  get PATH                  value of an item, decoded from JSON
+  ls PATH                   items of a bucket, with a Key and a Value field as returned by key
+  tree PATH                 items of a bucket and of its sub-buckets, their keys being relative paths
+  buckets PATH              names of the sub-buckets of a bucket
+  json STRING               decodes a JSON string
+  toJSON VALUE              encodes a value as JSON
+
+With --watch, the items and buckets used by the template are read again periodically
+and the template is rendered again when they change. --watch requires a running
+server. With --dry-run, OUT isn't written
+and the difference between its content and the rendered template is printed.`,
+		Example: `brazier template nginx.conf.tmpl /etc/nginx/nginx.conf --reload "nginx -s reload"
+brazier template app.yml.tmpl app.yml --watch --interval 10s
+brazier template app.yml.tmpl app.yml --dry-run`,
+		RunE: templateCmd.Template,
+	}
+
+	cmd.Flags().BoolVar(&templateCmd.Watch, "watch", false, "render the template again when the values it uses change")
+	cmd.Flags().DurationVar(&templateCmd.Interval, "interval", defaultPollInterval, "delay between two reads of the values, with --watch")
+	cmd.Flags().StringVar(&templateCmd.Reload, "reload", "", "shell command run after OUT is written")
+	cmd.Flags().BoolVar(&templateCmd.DryRun, "dry-run", false, "print the changes instead of writing OUT")
+	return &cmd
+}
+
+type templateCmd struct {
+	App      *app
+	Err      io.Writer
+	Watch    bool
+	Interval time.Duration
+	Reload   string
+	DryRun   bool
+
+	// signals stopping the watch mode, listened to by Template if nil
+	signals chan os.Signal
+	// ticks triggers the reads of the values with --watch, a ticker is used if nil
+	ticks <-chan time.Time
+}
+
+func (s *templateCmd) Template(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if s.Watch && s.DryRun {
+		return errors.New("--watch and --dry-run can't be used together")
+	}
+
+	if s.Watch && s.Interval <= 0 {
+		return errors.New("The interval must be positive")
+	}
+
+	// the local store is locked as long as it is open, other commands couldn't change the values
+	if _, local := s.App.Cli.(*cli); s.Watch && local {
+		return errors.New("--watch requires a running server, start one with \"brazier server\"")
+	}
+
+	in, out := args[0], args[1]
+
+	text, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	r := templateRenderer{cli: s.App.Cli}
+	tmpl, err := template.New(filepath.Base(in)).Funcs(r.funcs()).Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	data, err := r.render(tmpl)
+	if err != nil {
+		return err
+	}
+
+	if s.DryRun {
+		return s.printDiff(out, data)
+	}
+
+	err = s.write(out, data)
+	if err != nil || !s.Watch {
+		return err
+	}
+
+	if s.signals == nil {
+		s.signals = make(chan os.Signal, 1)
+		signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(s.signals)
+	}
+
+	poll := s.ticks
+	if poll == nil {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.signals:
+			return nil
+		case <-poll:
+		}
+
+		if !r.changed() {
+			continue
+		}
+
+		// rendering errors are reported without stopping, the file keeps its previous content
+		data, err = r.render(tmpl)
+		if err == nil {
+			err = s.write(out, data)
+		}
+		if err != nil {
+			fmt.Fprintf(s.Err, "%s\n", err)
+		}
+	}
+}
+
+// write saves the rendered template if it differs from the content of the file, then runs the reload command.
+func (s *templateCmd) write(path string, data []byte) error {
+	current, err := ioutil.ReadFile(path)
+	if err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode()
+	}
+
+	// the file is replaced at once so that it is never read half written
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	fmt.Fprintf(s.App.Out, "File \"%s\" successfully rendered.\n", path)
+
+	if s.Reload == "" {
+		return nil
+	}
+
+	reload := exec.Command("sh", "-c", s.Reload)
+	reload.Stdout = s.App.Out
+	reload.Stderr = s.Err
+	err = reload.Run()
+	if err != nil {
+		return fmt.Errorf("Reload command failed: %s", err)
+	}
+
+	return nil
+}
+
+// printDiff prints the changes between the content of the file and the rendered template as a unified diff.
+func (s *templateCmd) printDiff(path string, data []byte) error {
+	current, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil && bytes.Equal(current, data) {
+		fmt.Fprintln(s.App.Out, "No changes.")
+		return nil
+	}
+
+	return difflib.WriteUnifiedDiff(s.App.Out, difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(current)),
+		B:        difflib.SplitLines(string(data)),
+		FromFile: path,
+		ToFile:   path + " (rendered)",
+		Context:  3,
+	})
+}
+
+// templateItem is an item returned by the ls and tree template functions.
+type templateItem struct {
+	Key   string
+	Value string
+}
+
+// templateRenderer provides the template functions and records the paths they read,
+// with the values they returned, to find out when the template must be rendered again.
+type templateRenderer struct {
+	cli Cli
+	// snapshot of every dependency, indexed by the name of the function and the path
+	deps map[string]string
+}
+
+func (r *templateRenderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"key":          r.key,
+		"keyOrDefault": r.keyOrDefault,
+		"get":          r.get,
+		"ls":           r.ls,
+		"tree":         r.tree,
+		"buckets":      r.buckets,
+		"json":         decodeTemplateJSON,
+		"toJSON":       encodeTemplateJSON,
+	}
+}
+
+func (r *templateRenderer) render(tmpl *template.Template) ([]byte, error) {
+	r.deps = make(map[string]string)
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// changed reads the dependencies again and returns true if one of them changed.
+// Errors are considered changes, they are then reported by the rendering.
+func (r *templateRenderer) changed() bool {
+	for dep, snapshot := range r.deps {
+		i := strings.IndexByte(dep, ' ')
+		current, err := r.snapshot(dep[:i], dep[i+1:])
+		if err != nil || current != snapshot {
+			return true
+		}
+	}
+
+	return false
+}
+
+// snapshot reads a dependency again and returns its current state.
+func (r *templateRenderer) snapshot(kind, path string) (string, error) {
+	switch kind {
+	case "item":
+		item, err := r.cli.Item(path)
+		return itemSnapshot(item, err)
+	case "buckets":
+		names, err := r.bucketNames(path)
+		return strings.Join(names, "/"), err
+	}
+
+	items, err := r.items(path, kind == "tree")
+	return itemsSnapshot(items), err
+}
+
+// itemSnapshot returns the state of an item, a missing item having its own state.
+func itemSnapshot(item *brazier.Item, err error) (string, error) {
+	if err == store.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "=" + string(item.Data), nil
+}
+
+func itemsSnapshot(items []templateItem) string {
+	var buf bytes.Buffer
+	for _, item := range items {
+		fmt.Fprintf(&buf, "%q=%q\n", item.Key, item.Value)
+	}
+	return buf.String()
+}
+
+// record saves the state of a dependency, built from the value returned to the template.
+func (r *templateRenderer) record(kind, path, snapshot string) {
+	r.deps[kind+" "+path] = snapshot
+}
+
+func (r *templateRenderer) item(path string) (*brazier.Item, error) {
+	item, err := r.cli.Item(path)
+	snapshot, serr := itemSnapshot(item, err)
+	if serr == nil {
+		r.record("item", path, snapshot)
+	}
+
+	return item, err
+}
+
+func (r *templateRenderer) key(path string) (string, error) {
+	item, err := r.item(path)
+	if err == store.ErrNotFound {
+		return "", fmt.Errorf("Item \"%s\" not found", path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return rawValue(item.Data), nil
+}
+
+func (r *templateRenderer) keyOrDefault(path, value string) (string, error) {
+	item, err := r.item(path)
+	if err == store.ErrNotFound {
+		return value, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return rawValue(item.Data), nil
+}
+
+func (r *templateRenderer) get(path string) (interface{}, error) {
+	item, err := r.item(path)
+	if err == store.ErrNotFound {
+		return nil, fmt.Errorf("Item \"%s\" not found", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	err = json.Unmarshal(item.Data, &v)
+	return v, err
+}
+
+func (r *templateRenderer) ls(path string) ([]templateItem, error) {
+	items, err := r.items(path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	r.record("ls", path, itemsSnapshot(items))
+	return items, nil
+}
+
+func (r *templateRenderer) tree(path string) ([]templateItem, error) {
+	items, err := r.items(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r.record("tree", path, itemsSnapshot(items))
+	return items, nil
+}
+
+func (r *templateRenderer) buckets(path string) ([]string, error) {
+	names, err := r.bucketNames(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.record("buckets", path, strings.Join(names, "/"))
+	return names, nil
+}
+
+// items returns the items of the bucket, their keys being relative to it.
+func (r *templateRenderer) items(path string, recursive bool) ([]templateItem, error) {
+	path = bucketPath(path)
+	prefix := strings.TrimPrefix(path, "/")
+
+	var items []templateItem
+	err := r.cli.Walk(path, recursive, func(p string, depth int, item *brazier.Item) error {
+		if !strings.HasSuffix(p, "/") {
+			items = append(items, templateItem{
+				Key:   strings.TrimPrefix(p, prefix),
+				Value: rawValue(item.Data),
+			})
+		}
+		return nil
+	})
+
+	return items, err
+}
+
+func (r *templateRenderer) bucketNames(path string) ([]string, error) {
+	buckets, err := r.cli.Buckets(bucketPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(buckets))
+	for i := range buckets {
+		names[i] = strings.TrimSuffix(buckets[i].Key, "/")
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func decodeTemplateJSON(s string) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func encodeTemplateJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplate = `name: {{ key "config/name" }}
+port: {{ keyOrDefault "config/port" "80" }}
+{{ with get "config/db" }}db: {{ .host }}:{{ .port }}{{ end }}
+{{ range ls "servers/" }}{{ with json .Value }}server {{ .host }} weight={{ .weight }}
+{{ end }}{{ end }}{{ range tree "config/" }}{{ .Key }}={{ .Value }}
+{{ end }}buckets:{{ range buckets "/" }} {{ . }}{{ end }}
+`
+
+func TestCliTemplate(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testTemplateCmd(t, app)
+}
+
+func TestCliRPCTemplate(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testTemplateCmd(t, app)
+}
+
+func testTemplateCmd(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	dir, err := ioutil.TempDir("", "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "app.tmpl")
+	err = ioutil.WriteFile(in, []byte(testTemplate), 0644)
+	require.NoError(t, err)
+	target := filepath.Join(dir, "app.conf")
+
+	p := NewPutCmd(app)
+	err = p.RunE(nil, []string{
+		"config/name", `"app"`,
+		"config/db", `{"host": "localhost", "port": 5432}`,
+		"servers/a", `{"host": "10.0.0.1", "weight": 1}`,
+		"servers/b", `{"host": "10.0.0.2", "weight": 2}`,
+	})
+	require.NoError(t, err)
+
+	expected := `name: app
+port: 80
+db: localhost:5432
+server 10.0.0.1 weight=1
+server 10.0.0.2 weight=2
+name=app
+db={"host":"localhost","port":5432}
+buckets: config servers
+`
+
+	t.Run("Render", func(t *testing.T) {
+		s := templateCmd{App: app, Err: ioutil.Discard, Reload: "echo reloaded"}
+		out.Reset()
+		err := s.Template(nil, []string{in, target})
+		require.NoError(t, err)
+		require.Equal(t, "File \""+target+"\" successfully rendered.\nreloaded\n", out.String())
+
+		data, err := ioutil.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data))
+
+		// the file isn't written nor reloaded if it doesn't change
+		out.Reset()
+		err = s.Template(nil, []string{in, target})
+		require.NoError(t, err)
+		require.Equal(t, "", out.String())
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		s := templateCmd{App: app, DryRun: true}
+		out.Reset()
+		err := s.Template(nil, []string{in, target})
+		require.NoError(t, err)
+		require.Equal(t, "No changes.\n", out.String())
+
+		err = p.RunE(nil, []string{"config/port", `8080`})
+		require.NoError(t, err)
+
+		out.Reset()
+		err = s.Template(nil, []string{in, target})
+		require.NoError(t, err)
+		require.Contains(t, out.String(), "--- "+target+"\n+++ "+target+" (rendered)\n")
+		require.Contains(t, out.String(), "-port: 80\n+port: 8080\n")
+		require.Contains(t, out.String(), "+port=8080\n")
+
+		// the file is left untouched
+		data, err := ioutil.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data))
+
+		err = app.Cli.Delete("config/port")
+		require.NoError(t, err)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		s := templateCmd{App: app}
+		err := s.Template(nil, []string{in})
+		require.EqualError(t, err, "Wrong number of arguments")
+
+		s = templateCmd{App: app, Watch: true, DryRun: true}
+		err = s.Template(nil, []string{in, target})
+		require.EqualError(t, err, "--watch and --dry-run can't be used together")
+
+		bad := filepath.Join(dir, "bad.tmpl")
+		err = ioutil.WriteFile(bad, []byte(`{{ key "config/unknown" }}`), 0644)
+		require.NoError(t, err)
+
+		s = templateCmd{App: app}
+		err = s.Template(nil, []string{bad, filepath.Join(dir, "bad.conf")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Item \"config/unknown\" not found")
+
+		s = templateCmd{App: app, Reload: "exit 1"}
+		err = s.Template(nil, []string{in, filepath.Join(dir, "other.conf")})
+		require.EqualError(t, err, "Reload command failed: exit status 1")
+	})
+}
+
+// countingCli counts the reads of the store.
+type countingCli struct {
+	Cli
+	reads int
+}
+
+func (c *countingCli) Item(path string) (*brazier.Item, error) {
+	c.reads++
+	return c.Cli.Item(path)
+}
+
+func (c *countingCli) Walk(path string, recursive bool, fn store.WalkFunc) error {
+	c.reads++
+	return c.Cli.Walk(path, recursive, fn)
+}
+
+func (c *countingCli) Buckets(path string) ([]brazier.Item, error) {
+	c.reads++
+	return c.Cli.Buckets(path)
+}
+
+func TestTemplateRendererReads(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{"config/name", `"app"`, "config/db", `{"host": "db", "port": 5432}`, "servers/a", `{"host": "a", "weight": 1}`})
+	require.NoError(t, err)
+
+	c := countingCli{Cli: app.Cli}
+	r := templateRenderer{cli: &c}
+	tmpl, err := template.New("test").Funcs(r.funcs()).Parse(testTemplate)
+	require.NoError(t, err)
+
+	// every dependency is read once, its state being built from the value returned to the template
+	_, err = r.render(tmpl)
+	require.NoError(t, err)
+	require.Equal(t, 6, c.reads)
+	require.Len(t, r.deps, 6)
+	require.False(t, r.changed())
+	require.Equal(t, 12, c.reads)
+
+	err = p.RunE(nil, []string{"config/port", `8080`})
+	require.NoError(t, err)
+	require.True(t, r.changed())
+}
+
+func TestCliTemplateWatch(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "app.tmpl")
+	err = ioutil.WriteFile(in, []byte(`{{ range ls "servers" }}{{ .Key }} {{ end }}`), 0644)
+	require.NoError(t, err)
+	target := filepath.Join(dir, "app.conf")
+
+	s := templateCmd{App: app, Watch: true, Interval: time.Second}
+	err = s.Template(nil, []string{in, target})
+	require.EqualError(t, err, "--watch requires a running server, start one with \"brazier server\"")
+	_, err = os.Stat(target)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestCliRPCTemplateWatch(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "app.tmpl")
+	err = ioutil.WriteFile(in, []byte(`{{ range ls "servers" }}{{ .Key }} {{ end }}`), 0644)
+	require.NoError(t, err)
+	target := filepath.Join(dir, "app.conf")
+
+	p := NewPutCmd(app)
+	err = p.RunE(nil, []string{"servers/a", `1`})
+	require.NoError(t, err)
+
+	var out syncBuffer
+	app.Out = &out
+	reloads := filepath.Join(dir, "reloads")
+
+	ticks := make(chan time.Time)
+	s := templateCmd{
+		App:      app,
+		Err:      ioutil.Discard,
+		Watch:    true,
+		Interval: time.Second,
+		Reload:   "echo reload >> " + reloads,
+		signals:  make(chan os.Signal, 1),
+		ticks:    ticks,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Template(nil, []string{in, target})
+	}()
+
+	read := func(path string) string {
+		data, _ := ioutil.ReadFile(path)
+		return string(data)
+	}
+
+	for i := 0; i < 100 && (read(reloads) == "" || !strings.Contains(out.String(), "successfully rendered")); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.Equal(t, "a ", read(target))
+
+	// the values aren't read between two ticks
+	err = p.RunE(nil, []string{"servers/b", `2`, "other/c", `3`})
+	require.NoError(t, err)
+	ticks <- time.Now()
+	// the second tick is received once the first one is handled
+	ticks <- time.Now()
+
+	s.signals <- syscall.SIGTERM
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("template didn't stop")
+	}
+
+	require.Equal(t, "a b ", read(target))
+	require.Equal(t, "reload\nreload\n", read(reloads))
+	require.Equal(t, 2, strings.Count(out.String(), "successfully rendered"))
+}
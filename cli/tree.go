@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/asdine/brazier"
+	"github.com/spf13/cobra"
+)
+
+// NewTreeCmd creates a "Tree" cli command
+func NewTreeCmd(a *app) *cobra.Command {
+	treeCmd := treeCmd{
+		App: a,
+	}
+
+	cmd := cobra.Command{
+		Use:   "tree [PATH]",
+		Short: "Display the content of a bucket as a tree",
+		Long: `Display the buckets and the keys of a bucket and of all its children as a tree.
+The root bucket is displayed if no path is given.
+
+The tree is meant to be read, use "brazier get PATH/ -r -o keys" in scripts.`,
+		Example: `brazier tree
+brazier tree users/ --depth 2
+brazier tree --buckets-only`,
+		RunE: treeCmd.Tree,
+	}
+
+	cmd.Flags().IntVar(&treeCmd.Depth, "depth", 0, "maximum number of levels displayed, 0 for no limit")
+	cmd.Flags().BoolVar(&treeCmd.BucketsOnly, "buckets-only", false, "only display the buckets")
+	return &cmd
+}
+
+type treeCmd struct {
+	App         *app
+	Depth       int
+	BucketsOnly bool
+}
+
+func (s *treeCmd) Tree(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("Wrong number of arguments")
+	}
+
+	if s.Depth < 0 {
+		return errors.New("The depth must be positive")
+	}
+
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	return printTree(s.App.Out, s.App.Cli, bucketPath(path), s.Depth, s.BucketsOnly)
+}
+
+// printTree writes the content of the bucket as an ASCII tree, followed by the number
+// of buckets and items displayed.
+func printTree(w io.Writer, c Cli, path string, depth int, bucketsOnly bool) error {
+	var items []brazier.Item
+	var err error
+
+	if bucketsOnly {
+		items, err = c.Buckets(path)
+	} else {
+		items, err = walkTree(c, path, depth)
+	}
+	if err != nil {
+		return err
+	}
+
+	label := strings.TrimPrefix(path, "/")
+	if label == "" {
+		label = "/"
+	}
+	fmt.Fprintln(w, label)
+
+	t := treePrinter{w: w, maxDepth: depth}
+	t.print(items, "", 1)
+
+	summary := plural(t.buckets, "bucket")
+	if !bucketsOnly {
+		summary += ", " + plural(t.items, "item")
+	}
+
+	_, err = fmt.Fprintf(w, "\n%s\n", summary)
+	return err
+}
+
+// walkTree returns the keys of the content of the bucket up to the given depth,
+// nested the same way as Cli.Tree. The data of the items is not kept.
+func walkTree(c Cli, path string, depth int) ([]brazier.Item, error) {
+	var items []brazier.Item
+
+	// levels[d] receives the nodes of depth d. The walk visits the content of a
+	// bucket right after it, before any other node is added to its parent list.
+	levels := []*[]brazier.Item{&items}
+	err := c.WalkDepth(path, depth, func(p string, d int, item *brazier.Item) error {
+		levels = levels[:d+1]
+		list := levels[d]
+		*list = append(*list, brazier.Item{Key: item.Key})
+
+		if strings.HasSuffix(item.Key, "/") {
+			levels = append(levels, &(*list)[len(*list)-1].Children)
+		}
+		return nil
+	})
+
+	return items, err
+}
+
+type treePrinter struct {
+	w        io.Writer
+	maxDepth int
+	buckets  int
+	items    int
+}
+
+func (t *treePrinter) print(items []brazier.Item, indent string, depth int) {
+	if t.maxDepth > 0 && depth > t.maxDepth {
+		return
+	}
+
+	for i := range items {
+		branch, next := "├── ", "│   "
+		if i == len(items)-1 {
+			branch, next = "└── ", "    "
+		}
+
+		fmt.Fprintf(t.w, "%s%s%s\n", indent, branch, items[i].Key)
+
+		if strings.HasSuffix(items[i].Key, "/") {
+			t.buckets++
+			t.print(items[i].Children, indent+next, depth+1)
+		} else {
+			t.items++
+		}
+	}
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+
+	return fmt.Sprintf("%d %ss", n, word)
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCliTree(t *testing.T) {
+	app, cleanup := testableApp(t)
+	defer cleanup()
+
+	testTree(t, app)
+}
+
+func TestCliRPCTree(t *testing.T) {
+	app, cleanup := testableAppRPC(t)
+	defer cleanup()
+
+	testTree(t, app)
+}
+
+func testTree(t *testing.T, app *app) {
+	out := app.Out.(*bytes.Buffer)
+
+	p := NewPutCmd(app)
+	err := p.RunE(nil, []string{
+		"users/john", `{"name": "John"}`,
+		"users/admins/root", `"Root"`,
+		"users/admins/guests/bob", `"Bob"`,
+		"users/jack", `"Jack"`,
+		"config", `true`,
+	})
+	require.NoError(t, err)
+
+	tree := func(s *treeCmd, args ...string) string {
+		s.App = app
+		out.Reset()
+		err := s.Tree(nil, args)
+		require.NoError(t, err)
+		return out.String()
+	}
+
+	require.Equal(t, `/
+├── config
+└── users/
+    ├── john
+    ├── jack
+    └── admins/
+        ├── root
+        └── guests/
+            └── bob
+
+3 buckets, 5 items
+`, tree(&treeCmd{}))
+
+	require.Equal(t, `users/
+├── john
+├── jack
+└── admins/
+
+1 bucket, 2 items
+`, tree(&treeCmd{Depth: 1}, "users"))
+
+	require.Equal(t, `users/
+└── admins/
+    └── guests/
+
+2 buckets
+`, tree(&treeCmd{BucketsOnly: true}, "/users/"))
+
+	require.Equal(t, `users/
+└── admins/
+
+1 bucket
+`, tree(&treeCmd{BucketsOnly: true, Depth: 1}, "users/"))
+
+	s := treeCmd{App: app}
+	err = s.Tree(nil, []string{"unknown/"})
+	require.Error(t, err)
+
+	s.Depth = -1
+	err = s.Tree(nil, nil)
+	require.EqualError(t, err, "The depth must be positive")
+}
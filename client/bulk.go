@@ -0,0 +1,62 @@
+package client
+
+import (
+	"io"
+
+	"github.com/asdine/brazier/rpc"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+	"golang.org/x/net/context"
+)
+
+// BulkPut opens a stream used to save large amounts of items efficiently.
+// The server saves the items in large batches, creating the missing buckets.
+// The stream stays open until Close or Abort is called or the context is done,
+// the timeout and the retries configured on the client don't apply.
+func (c *Client) BulkPut(ctx context.Context) (*BulkWriter, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.bucket.BulkPut(ctx)
+	if err != nil {
+		cancel()
+		return nil, rpc.StoreError(err)
+	}
+
+	return &BulkWriter{stream: stream, cancel: cancel}, nil
+}
+
+// A BulkWriter sends items to the server.
+type BulkWriter struct {
+	stream proto.Bucket_BulkPutClient
+	cancel context.CancelFunc
+}
+
+// Put sends an item to the server. Data that isn't valid JSON is saved as a JSON string.
+// Items that can't be saved are reported by Close, an error is only returned
+// if the stream is broken.
+func (w *BulkWriter) Put(path string, data []byte) error {
+	err := w.stream.Send(&proto.NewItem{Path: path, Value: data})
+	if err == io.EOF {
+		// the stream was closed by the server, the actual error is returned by CloseAndRecv
+		_, err = w.stream.CloseAndRecv()
+	}
+
+	return rpc.StoreError(err)
+}
+
+// Close ends the stream and returns the summary of the bulk write.
+func (w *BulkWriter) Close() (*store.BulkResult, error) {
+	defer w.cancel()
+
+	s, err := w.stream.CloseAndRecv()
+	if err != nil {
+		return nil, rpc.StoreError(err)
+	}
+
+	return rpc.BulkResult(s), nil
+}
+
+// Abort cancels the stream, the server drops the items it didn't save yet.
+// The items already saved by a previous batch are kept.
+func (w *BulkWriter) Abort() {
+	w.cancel()
+}
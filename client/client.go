@@ -0,0 +1,434 @@
+// Package client provides a Go client for the Brazier gRPC service.
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/rpc"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Dial connects to a Brazier server listening on the given TCP address.
+// TLS is used if configured with WithTLS.
+func Dial(addr string, opts ...Option) (*Client, error) {
+	o := newOptions(opts...)
+
+	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+	if o.tlsConfig != nil {
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(o.tlsConfig))}
+	}
+
+	return dial(addr, o, dialOpts)
+}
+
+// DialSocket connects to a Brazier server listening on the unix socket at the given path.
+func DialSocket(path string, opts ...Option) (*Client, error) {
+	o := newOptions(opts...)
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", path, timeout)
+		}),
+	}
+
+	return dial("", o, dialOpts)
+}
+
+func dial(target string, o *options, dialOpts []grpc.DialOption) (*Client, error) {
+	if o.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(&tokenCredentials{
+			token:  o.token,
+			secure: o.tlsConfig != nil,
+		}))
+	}
+
+	ctx := context.Background()
+	if o.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.dialTimeout)
+		defer cancel()
+		dialOpts = append(dialOpts, grpc.WithBlock())
+	}
+
+	conn, err := grpc.DialContext(ctx, target, append(dialOpts, o.dialOpts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newClient(conn, o)
+	c.ownConn = true
+	return c, nil
+}
+
+// New returns a client using an existing connection.
+// The connection is not closed by Close. TLS and dial options are ignored.
+func New(conn *grpc.ClientConn, opts ...Option) *Client {
+	return newClient(conn, newOptions(opts...))
+}
+
+func newClient(conn *grpc.ClientConn, o *options) *Client {
+	return &Client{
+		conn:   conn,
+		bucket: proto.NewBucketClient(conn),
+		opts:   o,
+	}
+}
+
+// Client is a Brazier gRPC client.
+// Errors returned by the server are translated to store errors, e.g. store.ErrNotFound.
+// Idempotent calls failing with a transient error are retried with an exponential backoff.
+type Client struct {
+	conn    *grpc.ClientConn
+	bucket  proto.BucketClient
+	opts    *options
+	ownConn bool
+}
+
+// CreateBucket creates a bucket at the given path.
+// It is not retried.
+func (c *Client) CreateBucket(ctx context.Context, path string) error {
+	return c.once(ctx, func(ctx context.Context) error {
+		_, err := c.bucket.Create(ctx, &proto.Selector{Path: path})
+		return err
+	})
+}
+
+// Put saves the data at the given path. Data that isn't valid JSON is saved as a JSON string.
+func (c *Client) Put(ctx context.Context, path string, data []byte) error {
+	return c.do(ctx, func(ctx context.Context) error {
+		_, err := c.bucket.Put(ctx, &proto.NewItem{Path: path, Value: data})
+		return err
+	})
+}
+
+// PutIf saves the data at the given path if the item was last modified at updatedAt,
+// as returned by Get, or if it doesn't exist and updatedAt is zero.
+// It returns store.ErrConflict otherwise. It is not retried.
+func (c *Client) PutIf(ctx context.Context, path string, data []byte, updatedAt time.Time) error {
+	var n int64
+	if !updatedAt.IsZero() {
+		n = updatedAt.UnixNano()
+	}
+
+	return c.once(ctx, func(ctx context.Context) error {
+		_, err := c.bucket.Put(ctx, &proto.NewItem{Path: path, Value: data, Conditional: true, UpdatedAt: n})
+		return err
+	})
+}
+
+// PutJSON marshals v to JSON and saves it at the given path.
+func (c *Client) PutJSON(ctx context.Context, path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.Put(ctx, path, data)
+}
+
+// Get the item saved at the given path.
+func (c *Client) Get(ctx context.Context, path string) (*brazier.Item, error) {
+	var item *proto.Item
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = c.bucket.Get(ctx, &proto.Selector{Path: path})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i := brazier.Item{Key: item.Key, Data: item.Value}
+	if item.UpdatedAt != 0 {
+		i.UpdatedAt = time.Unix(0, item.UpdatedAt)
+	}
+
+	return &i, nil
+}
+
+// GetJSON unmarshals the data saved at the given path into v.
+func (c *Client) GetJSON(ctx context.Context, path string, v interface{}) error {
+	item, err := c.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(item.Data, v)
+}
+
+// Delete the item saved at the given path. It is not retried.
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.once(ctx, func(ctx context.Context) error {
+		_, err := c.bucket.Delete(ctx, &proto.Selector{Path: path})
+		return err
+	})
+}
+
+// List the items of the bucket at the given path.
+func (c *Client) List(ctx context.Context, path string) ([]brazier.Item, error) {
+	return c.list(ctx, &proto.Selector{Path: path})
+}
+
+// Tree returns the items of the bucket at the given path, and all its children buckets.
+// Buckets are returned as items with a key ending with a '/' and their content as children,
+// the same way as store.Tree.
+func (c *Client) Tree(ctx context.Context, path string) ([]brazier.Item, error) {
+	return c.list(ctx, &proto.Selector{Path: path, Recursive: true})
+}
+
+// Entries returns the items of the bucket at the given path followed by the buckets
+// directly under it, whose keys end with a '/'.
+func (c *Client) Entries(ctx context.Context, path string) ([]brazier.Item, error) {
+	return c.list(ctx, &proto.Selector{Path: path, Buckets: true})
+}
+
+// Buckets returns the buckets under the bucket at the given path, as items with a key ending
+// with a '/' and the buckets under them as children, the same way as store.Children.
+func (c *Client) Buckets(ctx context.Context, path string) ([]brazier.Item, error) {
+	return c.list(ctx, &proto.Selector{Path: path, Recursive: true, BucketsOnly: true})
+}
+
+func (c *Client) list(ctx context.Context, sel *proto.Selector) ([]brazier.Item, error) {
+	var tree *proto.Tree
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		tree, err = c.bucket.List(ctx, sel)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items(tree.Children), nil
+}
+
+// Walk streams the content of the bucket at the given path and calls fn for every node,
+// in the same order as store.Walk. The content is never loaded entirely in memory.
+// Walk stops at the first error returned by fn and returns it.
+// The call is only retried if no node was received.
+func (c *Client) Walk(ctx context.Context, path string, recursive bool, fn store.WalkFunc) error {
+	return c.walk(ctx, &proto.Selector{Path: path, Recursive: recursive}, fn)
+}
+
+// WalkDepth is like Walk with recursive set, but only the given number of levels is
+// sent by the server, the content of the buckets beyond being skipped.
+// A zero depth means no limit.
+func (c *Client) WalkDepth(ctx context.Context, path string, depth int, fn store.WalkFunc) error {
+	return c.walk(ctx, &proto.Selector{Path: path, Recursive: true, Depth: int32(depth)}, fn)
+}
+
+// walk streams the selected nodes. The configured timeout applies to the wait for every
+// node rather than to the whole stream, which can last as long as the content is received.
+func (c *Client) walk(ctx context.Context, sel *proto.Selector, fn store.WalkFunc) error {
+	var received bool
+
+	return c.retry(ctx, func(ctx context.Context) error {
+		// releases the stream if fn stops the walk
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		idle := newIdleTimer(c.opts.timeout, cancel)
+		defer idle.stop()
+
+		idle.start()
+		stream, err := c.bucket.StreamList(ctx, sel)
+		if err != nil {
+			return idle.err(err)
+		}
+
+		for {
+			idle.start()
+			node, err := stream.Recv()
+			idle.stop()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				err = idle.err(err)
+				if received {
+					return permanent{err}
+				}
+				return err
+			}
+
+			received = true
+			i := item(node)
+			err = fn(node.Path, int(node.Depth), &i)
+			if err != nil {
+				return permanent{err}
+			}
+		}
+	})
+}
+
+// Conn returns the underlying connection, e.g. to call other services of the server.
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// Close the connection if it was opened by the client.
+func (c *Client) Close() error {
+	if !c.ownConn {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+// do calls fn, retrying it with an exponential backoff as long as it returns a transient error.
+// The configured timeout applies to the whole call, retries included.
+func (c *Client) do(ctx context.Context, fn func(context.Context) error) error {
+	if c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+
+	return c.retry(ctx, fn)
+}
+
+// retry calls fn, retrying it with an exponential backoff as long as it returns a transient error.
+func (c *Client) retry(ctx context.Context, fn func(context.Context) error) error {
+	backoff := c.opts.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if p, ok := err.(permanent); ok {
+			return rpc.StoreError(p.err)
+		}
+		if err == nil || attempt >= c.opts.retries || !isTransient(err) {
+			return rpc.StoreError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return rpc.StoreError(err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// once calls fn like do but never retries it, for calls that aren't idempotent:
+// a retry of a call already applied by the server would fail, e.g. with store.ErrAlreadyExists.
+func (c *Client) once(ctx context.Context, fn func(context.Context) error) error {
+	return c.do(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+		if err != nil {
+			return permanent{err}
+		}
+		return nil
+	})
+}
+
+// idleTimer cancels a stream when no message is received within the timeout.
+// A zero timeout disables it.
+type idleTimer struct {
+	timeout time.Duration
+	timer   *time.Timer
+	expired int32
+}
+
+func newIdleTimer(timeout time.Duration, cancel context.CancelFunc) *idleTimer {
+	t := idleTimer{timeout: timeout}
+	if timeout > 0 {
+		t.timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&t.expired, 1)
+			cancel()
+		})
+		t.timer.Stop()
+	}
+
+	return &t
+}
+
+// start waiting for a message.
+func (t *idleTimer) start() {
+	if t.timer != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+// stop waiting, once a message is received.
+func (t *idleTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// err replaces the error caused by the cancellation of the stream by a timeout error.
+func (t *idleTimer) err(err error) error {
+	if atomic.LoadInt32(&t.expired) == 1 {
+		return status.Errorf(codes.DeadlineExceeded, "no message received for %s", t.timeout)
+	}
+
+	return err
+}
+
+// permanent wraps an error that must not be retried.
+type permanent struct {
+	err error
+}
+
+func (p permanent) Error() string {
+	return p.err.Error()
+}
+
+// isTransient returns true if the call might succeed if retried.
+func isTransient(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+
+	return false
+}
+
+// items converts a list of nodes to a list of items.
+func items(nodes []*proto.Node) []brazier.Item {
+	list := make([]brazier.Item, len(nodes))
+	for i, n := range nodes {
+		list[i] = item(n)
+
+		if n.Children != nil {
+			list[i].Children = items(n.Children)
+		}
+	}
+
+	return list
+}
+
+func item(n *proto.Node) brazier.Item {
+	i := brazier.Item{
+		Key:  n.Key,
+		Data: n.Value,
+	}
+
+	if n.UpdatedAt != 0 {
+		i.UpdatedAt = time.Unix(0, n.UpdatedAt)
+	}
+
+	return i
+}
@@ -0,0 +1,338 @@
+package client_test
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/client"
+	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/rpc"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func newServer(t *testing.T, opts ...grpc.ServerOption) (*store.Store, string, func()) {
+	s := store.NewStore(mock.NewRegistry(mock.NewBackend()))
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer(opts...)
+	proto.RegisterBucketServer(srv, &rpc.Server{Store: s})
+	go srv.Serve(l)
+
+	return s, l.Addr().String(), srv.Stop
+}
+
+func TestClient(t *testing.T) {
+	s, addr, stop := newServer(t)
+	defer stop()
+
+	c, err := client.Dial(addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx := context.Background()
+
+	t.Run("CreateBucket", func(t *testing.T) {
+		err := c.CreateBucket(ctx, "a/")
+		require.NoError(t, err)
+		err = c.CreateBucket(ctx, "a/")
+		require.Equal(t, store.ErrAlreadyExists, err)
+	})
+
+	t.Run("PutGet", func(t *testing.T) {
+		err := c.Put(ctx, "a/key", []byte("hello"))
+		require.NoError(t, err)
+		item, err := c.Get(ctx, "a/key")
+		require.NoError(t, err)
+		require.Equal(t, "key", item.Key)
+		require.Equal(t, []byte(`"hello"`), item.Data)
+
+		_, err = c.Get(ctx, "a/unknown")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("PutIf", func(t *testing.T) {
+		err := c.PutIf(ctx, "cas/key", []byte("hello"), time.Time{})
+		require.NoError(t, err)
+		item, err := c.Get(ctx, "cas/key")
+		require.NoError(t, err)
+		require.False(t, item.UpdatedAt.IsZero())
+
+		err = c.PutIf(ctx, "cas/key", []byte("other"), time.Time{})
+		require.Equal(t, store.ErrConflict, err)
+
+		err = c.PutIf(ctx, "cas/key", []byte("other"), item.UpdatedAt)
+		require.NoError(t, err)
+
+		err = c.PutIf(ctx, "cas/key", []byte("again"), item.UpdatedAt)
+		require.Equal(t, store.ErrConflict, err)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		type user struct {
+			Name string `json:"name"`
+			Age  int    `json:"age"`
+		}
+
+		err := c.PutJSON(ctx, "users/john", &user{Name: "John", Age: 10})
+		require.NoError(t, err)
+
+		var u user
+		err = c.GetJSON(ctx, "users/john", &u)
+		require.NoError(t, err)
+		require.Equal(t, user{Name: "John", Age: 10}, u)
+
+		err = c.PutJSON(ctx, "users/jack", make(chan int))
+		require.Error(t, err)
+	})
+
+	t.Run("ListTree", func(t *testing.T) {
+		_, err := s.Put("a/b/c", []byte(`"data"`))
+		require.NoError(t, err)
+
+		items, err := c.List(ctx, "a/")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.Equal(t, "key", items[0].Key)
+
+		items, err = c.Tree(ctx, "a/")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "b/", items[1].Key)
+		require.Len(t, items[1].Children, 1)
+		require.Equal(t, []byte(`"data"`), items[1].Children[0].Data)
+
+		items, err = c.Entries(ctx, "a/")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "key", items[0].Key)
+		require.Equal(t, "b/", items[1].Key)
+		require.Nil(t, items[1].Children)
+		require.False(t, items[0].UpdatedAt.IsZero())
+		require.True(t, items[1].UpdatedAt.IsZero())
+
+		err = s.CreateBucket("tree/a/b/")
+		require.NoError(t, err)
+
+		items, err = c.Buckets(ctx, "tree/")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.Equal(t, "a/", items[0].Key)
+		require.Len(t, items[0].Children, 1)
+		require.Equal(t, "b/", items[0].Children[0].Key)
+
+		_, err = c.List(ctx, "z/")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("Walk", func(t *testing.T) {
+		var paths []string
+		err := c.Walk(ctx, "a/", true, func(path string, depth int, item *brazier.Item) error {
+			paths = append(paths, path)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a/key", "a/b/", "a/b/c"}, paths)
+
+		paths = nil
+		err = c.WalkDepth(ctx, "a/", 1, func(path string, depth int, item *brazier.Item) error {
+			paths = append(paths, path)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a/key", "a/b/"}, paths)
+
+		err = c.Walk(ctx, "a/", true, func(path string, depth int, item *brazier.Item) error {
+			return store.ErrForbidden
+		})
+		require.Equal(t, store.ErrForbidden, err)
+	})
+
+	t.Run("BulkPut", func(t *testing.T) {
+		w, err := c.BulkPut(ctx)
+		require.NoError(t, err)
+		err = w.Put("bulk/a", []byte("hello"))
+		require.NoError(t, err)
+		err = w.Put("bulk/", []byte("hello"))
+		require.NoError(t, err)
+
+		res, err := w.Close()
+		require.NoError(t, err)
+		require.Equal(t, 2, res.Received)
+		require.Equal(t, 1, res.Saved)
+		require.Equal(t, store.ErrForbidden, res.Failures[0].Err)
+
+		item, err := c.Get(ctx, "bulk/a")
+		require.NoError(t, err)
+		require.Equal(t, []byte(`"hello"`), item.Data)
+
+		w, err = c.BulkPut(ctx)
+		require.NoError(t, err)
+		err = w.Put("bulk/aborted", []byte("hello"))
+		require.NoError(t, err)
+		w.Abort()
+		_, err = c.Get(ctx, "bulk/aborted")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := c.Delete(ctx, "a/key")
+		require.NoError(t, err)
+		err = c.Delete(ctx, "a/key")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	var calls, failures int
+	_, addr, stop := newServer(t, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		calls++
+		if failures > 0 {
+			failures--
+			return nil, grpc.Errorf(codes.Unavailable, "unavailable")
+		}
+		return handler(ctx, req)
+	}))
+	defer stop()
+
+	c, err := client.Dial(addr, client.WithRetry(1, time.Millisecond))
+	require.NoError(t, err)
+	defer c.Close()
+
+	failures = 2
+	err = c.Put(context.Background(), "a/key", []byte("data"))
+	require.Equal(t, codes.Unavailable, grpc.Code(err))
+	require.Equal(t, 2, calls)
+
+	err = c.Put(context.Background(), "a/key", []byte("data"))
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	// non transient errors are not retried
+	c, err = client.Dial(addr, client.WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Get(context.Background(), "a/unknown")
+	require.Equal(t, store.ErrNotFound, err)
+	require.Equal(t, 4, calls)
+
+	// calls that are not idempotent are not retried
+	failures = 1
+	err = c.CreateBucket(context.Background(), "b/")
+	require.Equal(t, codes.Unavailable, grpc.Code(err))
+	require.Equal(t, 5, calls)
+
+	failures = 1
+	err = c.Delete(context.Background(), "a/key")
+	require.Equal(t, codes.Unavailable, grpc.Code(err))
+	require.Equal(t, 6, calls)
+}
+
+func TestTimeout(t *testing.T) {
+	c, err := client.DialSocket(filepath.Join("unknown", "brazier.sock"), client.WithTimeout(50*time.Millisecond), client.WithRetry(100, time.Millisecond))
+	require.NoError(t, err)
+	defer c.Close()
+
+	start := time.Now()
+	err = c.Put(context.Background(), "a/b", []byte("data"))
+	require.Error(t, err)
+	require.True(t, time.Since(start) < time.Second)
+}
+
+func TestStreamTimeout(t *testing.T) {
+	var delay int64
+	s, addr, stop := newServer(t, grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		time.Sleep(time.Duration(atomic.LoadInt64(&delay)))
+		return handler(srv, ss)
+	}))
+	defer stop()
+
+	for i := 0; i < 3; i++ {
+		_, err := s.Put(fmt.Sprintf("a/key%d", i), []byte(`"data"`))
+		require.NoError(t, err)
+	}
+
+	c, err := client.Dial(addr, client.WithTimeout(100*time.Millisecond), client.WithRetry(0, 0))
+	require.NoError(t, err)
+	defer c.Close()
+
+	// the timeout doesn't apply to the whole stream
+	var n int
+	err = c.Walk(context.Background(), "a/", false, func(path string, depth int, item *brazier.Item) error {
+		n++
+		time.Sleep(60 * time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	// but to the wait for every message
+	atomic.StoreInt64(&delay, int64(300*time.Millisecond))
+	err = c.Walk(context.Background(), "a/", false, func(path string, depth int, item *brazier.Item) error {
+		return nil
+	})
+	require.Equal(t, codes.DeadlineExceeded, grpc.Code(err))
+}
+
+func TestDialTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	start := time.Now()
+	_, err = client.Dial(addr, client.WithDialTimeout(50*time.Millisecond))
+	require.Error(t, err)
+	require.True(t, time.Since(start) < time.Second)
+}
+
+func TestToken(t *testing.T) {
+	var tokens []string
+	_, addr, stop := newServer(t, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		tokens = append(tokens, md[rpc.TokenMetadataKey]...)
+		return handler(ctx, req)
+	}))
+	defer stop()
+
+	c, err := client.Dial(addr, client.WithToken("secret"), client.WithDialTimeout(time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+
+	err = c.CreateBucket(context.Background(), "a/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer secret"}, tokens)
+}
+
+func TestNew(t *testing.T) {
+	_, addr, stop := newServer(t)
+	defer stop()
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := client.New(conn)
+	require.Equal(t, conn, c.Conn())
+	err = c.CreateBucket(context.Background(), "a/")
+	require.NoError(t, err)
+
+	// the connection is not owned by the client
+	err = c.Close()
+	require.NoError(t, err)
+	err = c.CreateBucket(context.Background(), "b/")
+	require.NoError(t, err)
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/asdine/brazier/rpc"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = 100 * time.Millisecond
+	maxBackoff     = 2 * time.Second
+)
+
+// An Option configures the client.
+type Option func(*options)
+
+// WithTLS connects to the server using the given TLS configuration.
+// It can be created with tlsutil.ClientConfig.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithTimeout sets the maximum duration of every call, retries included.
+// For streamed calls like Walk, it is the maximum duration of the wait for every message
+// instead, so that large contents can be received.
+// A zero duration means no timeout, which is the default.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithDialTimeout makes Dial and DialSocket wait until the connection is established,
+// and fail if it takes longer than d. By default, the connection is established in the background.
+func WithDialTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = d
+	}
+}
+
+// WithToken sends the given token to authenticate every call.
+// The token is sent in clear text unless TLS is enabled.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithRetry sets the number of times a call failing with a transient error is retried,
+// and the delay before the first retry. The delay is doubled after each retry.
+// By default, calls are retried 3 times, starting after 100ms.
+// A zero value disables the retries.
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithDialOptions passes options to grpc.Dial.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOpts = append(o.dialOpts, opts...)
+	}
+}
+
+type options struct {
+	tlsConfig   *tls.Config
+	timeout     time.Duration
+	dialTimeout time.Duration
+	token       string
+	retries     int
+	backoff     time.Duration
+	dialOpts    []grpc.DialOption
+}
+
+func newOptions(opts ...Option) *options {
+	o := options{
+		retries: defaultRetries,
+		backoff: defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &o
+}
+
+// tokenCredentials sends a token with every call.
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		rpc.TokenMetadataKey: "Bearer " + t.token,
+	}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
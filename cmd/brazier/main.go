@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/asdine/brazier/cli"
 )
@@ -9,6 +11,10 @@ import (
 func main() {
 	cmd := cli.New()
 	if err := cmd.Execute(); err != nil {
+		if e, ok := err.(*cli.ExitError); ok {
+			fmt.Fprintln(os.Stderr, e)
+			os.Exit(e.Code)
+		}
 		log.Fatal(err)
 	}
 }
@@ -1,25 +1,86 @@
 package config
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"time"
 
 	"github.com/ghodss/yaml"
 )
 
 // Config is the main configuration
 type Config struct {
-	HTTP HTTP
-	RPC  RPC
+	HTTP  HTTP
+	RPC   RPC
+	Admin Admin
 }
 
 // HTTP configuration
 type HTTP struct {
-	Address string
+	Address         string
+	TLS             TLS
+	ReadTimeout     Duration
+	WriteTimeout    Duration
+	IdleTimeout     Duration
+	ShutdownTimeout Duration
 }
 
-// RPC configuration
+// RPC configuration. When a token is set, clients connecting to the address must send it.
 type RPC struct {
 	Address string
+	TLS     TLS
+	Token   string
+}
+
+// Admin configuration. The admin listener serves the metrics,
+// it is disabled if the address is empty.
+type Admin struct {
+	Address string
+}
+
+// TLS configuration. TLS is enabled when a certificate is provided
+// and client certificates are verified when a client CA is provided.
+type TLS struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// Enabled returns true if a certificate is configured.
+func (t *TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// Duration is a time.Duration that can be written as a string, e.g. "10s" or "1m30s".
+type Duration time.Duration
+
+// UnmarshalJSON decodes a duration from a string or from a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		var n int64
+		err = json.Unmarshal(data, &n)
+		if err != nil {
+			return err
+		}
+		*d = Duration(n)
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
 }
 
 // FromFile reads the configuration from a file
@@ -0,0 +1,106 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yml")
+	err = ioutil.WriteFile(path, []byte(`
+HTTP:
+  Address: ":8080"
+  ReadTimeout: 10s
+  WriteTimeout: 1m30s
+  IdleTimeout: 1000000000
+RPC:
+  Address: ":8081"
+Admin:
+  Address: "127.0.0.1:8082"
+`), 0600)
+	require.NoError(t, err)
+
+	var cfg config.Config
+	err = config.FromFile(path, &cfg)
+	require.NoError(t, err)
+	require.Equal(t, ":8080", cfg.HTTP.Address)
+	require.Equal(t, config.Duration(10*time.Second), cfg.HTTP.ReadTimeout)
+	require.Equal(t, config.Duration(90*time.Second), cfg.HTTP.WriteTimeout)
+	require.Equal(t, config.Duration(time.Second), cfg.HTTP.IdleTimeout)
+	require.Zero(t, cfg.HTTP.ShutdownTimeout)
+	require.Equal(t, ":8081", cfg.RPC.Address)
+	require.Equal(t, "127.0.0.1:8082", cfg.Admin.Address)
+
+	err = ioutil.WriteFile(path, []byte(`
+HTTP:
+  ReadTimeout: forever
+`), 0600)
+	require.NoError(t, err)
+	err = config.FromFile(path, &cfg)
+	require.Error(t, err)
+}
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sub", "profiles.yml")
+	p, err := config.LoadProfiles(path)
+	require.NoError(t, err)
+	require.Empty(t, p.Current)
+	require.Empty(t, p.Profiles)
+
+	p.Current = "prod"
+	p.Profiles["local"] = config.Profile{DataDir: "/var/lib/brazier"}
+	p.Profiles["prod"] = config.Profile{
+		Host:    "brazier.example.com:5657",
+		TLS:     true,
+		Token:   "secret",
+		Timeout: config.Duration(5 * time.Second),
+	}
+	err = p.Save(path)
+	require.NoError(t, err)
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	loaded, err := config.LoadProfiles(path)
+	require.NoError(t, err)
+	require.Equal(t, p, loaded)
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "ClientCert")
+}
+
+func TestProfileValidate(t *testing.T) {
+	valid := []config.Profile{
+		{DataDir: "/data"},
+		{Socket: "/data/brazier.sock"},
+		{Host: "localhost:5657", TLS: true, Token: "secret"},
+	}
+	for _, p := range valid {
+		require.NoError(t, p.Validate())
+	}
+
+	invalid := []config.Profile{
+		{},
+		{DataDir: "/data", Host: "localhost:5657"},
+		{Socket: "/data/brazier.sock", Token: "secret"},
+	}
+	for _, p := range invalid {
+		require.Error(t, p.Validate())
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+)
+
+// Profiles is the client configuration, it holds the connection profiles of the cli.
+type Profiles struct {
+	// Current is the profile used when none is selected.
+	Current  string             `json:",omitempty"`
+	Profiles map[string]Profile `json:",omitempty"`
+}
+
+// Profile describes how the cli reaches the store: through a data directory,
+// the socket of a local server or a remote host.
+type Profile struct {
+	DataDir    string   `json:",omitempty"`
+	Socket     string   `json:",omitempty"`
+	Host       string   `json:",omitempty"`
+	TLS        bool     `json:",omitempty"`
+	CACert     string   `json:",omitempty"`
+	ClientCert string   `json:",omitempty"`
+	ClientKey  string   `json:",omitempty"`
+	Token      string   `json:",omitempty"`
+	Timeout    Duration `json:",omitempty"`
+}
+
+// Validate checks that the profile names exactly one data directory, socket or host.
+func (p *Profile) Validate() error {
+	var n int
+	for _, s := range []string{p.DataDir, p.Socket, p.Host} {
+		if s != "" {
+			n++
+		}
+	}
+
+	switch {
+	case n == 0:
+		return errors.New("A profile needs a data directory, a socket or a host")
+	case n > 1:
+		return errors.New("A profile can't have more than one data directory, socket or host")
+	case p.Host == "" && (p.TLS || p.CACert != "" || p.ClientCert != "" || p.ClientKey != "" || p.Token != ""):
+		return errors.New("TLS and token settings require a host")
+	}
+
+	return nil
+}
+
+// LoadProfiles reads the profiles from a file. No profiles are returned if the file doesn't exist.
+func LoadProfiles(path string) (*Profiles, error) {
+	var p Profiles
+
+	err := FromFile(path, &p)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if p.Profiles == nil {
+		p.Profiles = make(map[string]Profile)
+	}
+
+	return &p, nil
+}
+
+// Save writes the profiles to a file. The file is only readable by its owner
+// because profiles can contain tokens.
+func (p *Profiles) Save(path string) error {
+	content, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0600)
+}
@@ -0,0 +1,184 @@
+package brazier
+
+import "golang.org/x/net/context"
+
+// A ContextBucket is a Bucket whose operations can be cancelled using a context.
+type ContextBucket interface {
+	Bucket
+
+	// SaveContext is like Save but stops if the context is done.
+	SaveContext(ctx context.Context, key string, data []byte) (*Item, error)
+	// GetContext is like Get but stops if the context is done.
+	GetContext(ctx context.Context, key string) (*Item, error)
+	// DeleteContext is like Delete but stops if the context is done.
+	DeleteContext(ctx context.Context, key string) error
+	// PageContext is like Page but stops if the context is done.
+	PageContext(ctx context.Context, page int, perPage int) ([]Item, error)
+	// IterateContext calls fn for every item of the bucket, in the same order as Page.
+	// It stops at the first error returned by fn, or when the context is done,
+	// and returns the error.
+	IterateContext(ctx context.Context, fn func(*Item) error) error
+}
+
+// A ContextBackend is a Backend whose operations can be cancelled using a context.
+type ContextBackend interface {
+	Backend
+
+	// BucketContext is like Bucket but stops if the context is done.
+	BucketContext(ctx context.Context, nodes ...string) (Bucket, error)
+}
+
+// A ContextRegistry is a Registry whose operations can be cancelled using a context.
+type ContextRegistry interface {
+	Registry
+
+	// CreateContext is like Create but stops if the context is done.
+	CreateContext(ctx context.Context, nodes ...string) error
+	// BucketContext is like Bucket but stops if the context is done.
+	BucketContext(ctx context.Context, nodes ...string) (Bucket, error)
+	// ChildrenContext is like Children but stops if the context is done.
+	ChildrenContext(ctx context.Context, nodes ...string) ([]Item, error)
+}
+
+// BucketWithContext returns b if it implements ContextBucket, otherwise
+// it returns an adapter checking the context before every operation
+// and between the items during an iteration.
+func BucketWithContext(b Bucket) ContextBucket {
+	if cb, ok := b.(ContextBucket); ok {
+		return cb
+	}
+
+	return &contextBucket{b}
+}
+
+type contextBucket struct {
+	Bucket
+}
+
+func (b *contextBucket) SaveContext(ctx context.Context, key string, data []byte) (*Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Save(key, data)
+}
+
+func (b *contextBucket) GetContext(ctx context.Context, key string) (*Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Get(key)
+}
+
+func (b *contextBucket) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.Delete(key)
+}
+
+func (b *contextBucket) PageContext(ctx context.Context, page int, perPage int) ([]Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Page(page, perPage)
+}
+
+// IterateContext uses the Iterator interface if implemented by the bucket,
+// otherwise all the items are fetched using Page.
+func (b *contextBucket) IterateContext(ctx context.Context, fn func(*Item) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if it, ok := b.Bucket.(Iterator); ok {
+		return it.Iterate(func(i *Item) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			return fn(i)
+		})
+	}
+
+	items, err := b.Page(1, -1)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err = fn(&items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackendWithContext returns b if it implements ContextBackend, otherwise
+// it returns an adapter checking the context before every operation.
+func BackendWithContext(b Backend) ContextBackend {
+	if cb, ok := b.(ContextBackend); ok {
+		return cb
+	}
+
+	return &contextBackend{b}
+}
+
+type contextBackend struct {
+	Backend
+}
+
+func (b *contextBackend) BucketContext(ctx context.Context, nodes ...string) (Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Bucket(nodes...)
+}
+
+// RegistryWithContext returns r if it implements ContextRegistry, otherwise
+// it returns an adapter checking the context before every operation.
+func RegistryWithContext(r Registry) ContextRegistry {
+	if cr, ok := r.(ContextRegistry); ok {
+		return cr
+	}
+
+	return &contextRegistry{r}
+}
+
+type contextRegistry struct {
+	Registry
+}
+
+func (r *contextRegistry) CreateContext(ctx context.Context, nodes ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.Create(nodes...)
+}
+
+func (r *contextRegistry) BucketContext(ctx context.Context, nodes ...string) (Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.Bucket(nodes...)
+}
+
+func (r *contextRegistry) ChildrenContext(ctx context.Context, nodes ...string) ([]Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.Children(nodes...)
+}
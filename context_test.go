@@ -0,0 +1,69 @@
+package brazier_test
+
+import (
+	"testing"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestBucketWithContext(t *testing.T) {
+	b := mock.NewBucket("a")
+	for _, k := range []string{"a", "b", "c"} {
+		_, err := b.Save(k, []byte("Data"))
+		require.NoError(t, err)
+	}
+
+	cb := brazier.BucketWithContext(b)
+	require.Equal(t, cb, brazier.BucketWithContext(cb))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	item, err := cb.GetContext(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, "a", item.Key)
+
+	var keys []string
+	err = cb.IterateContext(ctx, func(i *brazier.Item) error {
+		keys = append(keys, i.Key)
+		if len(keys) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, []string{"a", "b"}, keys)
+	require.True(t, b.IterateInvoked)
+
+	_, err = cb.SaveContext(ctx, "d", []byte("Data"))
+	require.Equal(t, context.Canceled, err)
+	_, err = cb.PageContext(ctx, 1, -1)
+	require.Equal(t, context.Canceled, err)
+	err = cb.DeleteContext(ctx, "a")
+	require.Equal(t, context.Canceled, err)
+	require.False(t, b.DeleteInvoked)
+}
+
+func TestRegistryWithContext(t *testing.T) {
+	r := brazier.RegistryWithContext(mock.NewRegistry(mock.NewBackend()))
+
+	err := r.CreateContext(context.Background(), "a")
+	require.NoError(t, err)
+	b, err := r.BucketContext(context.Background(), "a")
+	require.NoError(t, err)
+	b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = r.CreateContext(ctx, "b")
+	require.Equal(t, context.Canceled, err)
+	_, err = r.BucketContext(ctx, "a")
+	require.Equal(t, context.Canceled, err)
+	_, err = r.ChildrenContext(ctx)
+	require.Equal(t, context.Canceled, err)
+
+	_, err = brazier.BackendWithContext(mock.NewBackend()).BucketContext(ctx, "a")
+	require.Equal(t, context.Canceled, err)
+}
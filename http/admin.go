@@ -0,0 +1,97 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/store"
+)
+
+// NewAdminServer returns an HTTP server exposing the administration endpoints.
+// It is meant to listen on a separate address from the API.
+// The returned server implements the brazier.Readiness interface.
+func NewAdminServer(h *AdminHandler, opts ...ServerOption) brazier.Server {
+	srv := newServer(opts...)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	srv.srv.Server.Handler = mux
+
+	return &adminServer{server: srv, handler: h}
+}
+
+type adminServer struct {
+	*server
+	handler *AdminHandler
+}
+
+func (s *adminServer) SetReady(ready bool) {
+	s.handler.SetReady(ready)
+}
+
+// AdminHandler serves the administration endpoints.
+// /healthz answers 200 if the store is able to serve requests,
+// /readyz answers 200 if the server is ready and the store is healthy
+// and /metrics serves the metrics, if configured.
+type AdminHandler struct {
+	// Store is checked by the health endpoints, it is considered healthy if nil.
+	Store *store.Store
+
+	// Metrics serves the /metrics endpoint, it is disabled if nil.
+	Metrics http.Handler
+
+	ready int32
+}
+
+// SetReady marks the server as ready or not ready.
+func (h *AdminHandler) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+
+	atomic.StoreInt32(&h.ready, v)
+}
+
+// Ready returns true if the server is marked as ready.
+func (h *AdminHandler) Ready() bool {
+	return atomic.LoadInt32(&h.ready) == 1
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/healthz":
+		h.healthz(w, r)
+	case r.URL.Path == "/readyz":
+		h.readyz(w, r)
+	case r.URL.Path == "/metrics" && h.Metrics != nil:
+		h.Metrics.ServeHTTP(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (h *AdminHandler) healthz(w http.ResponseWriter, r *http.Request) {
+	if h.Store != nil {
+		err := h.Store.Check()
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "unhealthy: %s\n", err)
+			return
+		}
+	}
+
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *AdminHandler) readyz(w http.ResponseWriter, r *http.Request) {
+	if !h.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "not ready")
+		return
+	}
+
+	h.healthz(w, r)
+}
@@ -0,0 +1,103 @@
+package http_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier"
+	brazierHttp "github.com/asdine/brazier/http"
+	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Run("NoMetrics", func(t *testing.T) {
+		h := brazierHttp.AdminHandler{}
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		h := brazierHttp.AdminHandler{
+			Metrics: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("metrics"))
+			}),
+		}
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "metrics", w.Body.String())
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("GET", "/a/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+type checkedRegistry struct {
+	*mock.Registry
+	err error
+}
+
+func (r *checkedRegistry) Check() error {
+	return r.err
+}
+
+func TestAdminHealth(t *testing.T) {
+	r := checkedRegistry{Registry: mock.NewRegistry(mock.NewBackend())}
+	h := brazierHttp.AdminHandler{
+		Store: store.NewStore(&r),
+	}
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, get("/healthz"))
+	require.Equal(t, http.StatusServiceUnavailable, get("/readyz"))
+
+	h.SetReady(true)
+	require.True(t, h.Ready())
+	require.Equal(t, http.StatusOK, get("/readyz"))
+
+	r.err = errors.New("unavailable")
+	require.Equal(t, http.StatusServiceUnavailable, get("/healthz"))
+	require.Equal(t, http.StatusServiceUnavailable, get("/readyz"))
+
+	r.err = nil
+	h.SetReady(false)
+	require.Equal(t, http.StatusOK, get("/healthz"))
+	require.Equal(t, http.StatusServiceUnavailable, get("/readyz"))
+}
+
+func TestAdminServer(t *testing.T) {
+	var h brazierHttp.AdminHandler
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := brazierHttp.NewAdminServer(&h)
+	go srv.Serve(l)
+	defer srv.Stop(time.Millisecond)
+
+	srv.(brazier.Readiness).SetReady(true)
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
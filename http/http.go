@@ -6,30 +6,104 @@ import (
 	"net/http"
 	"strings"
 
-	graceful "gopkg.in/tylerb/graceful.v1"
-
 	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/json"
+	"github.com/asdine/brazier/query"
 	"github.com/asdine/brazier/store"
 )
 
-// NewServer returns a configured HTTP server
-func NewServer(r *store.Store) brazier.Server {
-	http.Handle("/", &Handler{Store: r})
-	srv := graceful.Server{
-		Server: &http.Server{},
+// Limits of the queries given with the jq parameter. Queries exceeding them are
+// answered with a 400, they can't use an unbounded amount of memory on the server.
+const (
+	maxQueryDepth  = 32
+	maxQueryValues = 100000
+	maxQuerySize   = 4 << 20
+)
+
+// NewHandler returns an http.Handler serving the store.
+// It can be mounted on any ServeMux, e.g. to embed the Brazier API in an existing application.
+func NewHandler(r *store.Store, opts ...HandlerOption) http.Handler {
+	h := Handler{
+		Store: r,
+	}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	var handler http.Handler = &h
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		handler = h.middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// A HandlerOption configures the Handler.
+type HandlerOption func(*Handler)
+
+// A Middleware wraps an http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// WithPrefix serves the store under the given base path.
+// Requests outside of the base path are answered with a 404.
+func WithPrefix(prefix string) HandlerOption {
+	return func(h *Handler) {
+		h.Prefix = prefix
+	}
+}
+
+// ReadOnly rejects every request modifying the store.
+func ReadOnly() HandlerOption {
+	return func(h *Handler) {
+		h.ReadOnly = true
+	}
+}
+
+// WithMiddleware wraps the handler with the given middlewares.
+// The first middleware is the outermost one.
+func WithMiddleware(middlewares ...Middleware) HandlerOption {
+	return func(h *Handler) {
+		h.middlewares = append(h.middlewares, middlewares...)
 	}
+}
 
-	return &srv
+// WithLogger sets the logger used to report internal errors.
+func WithLogger(logger *log.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.Logger = logger
+	}
 }
 
 // Handler is the main http handler
 type Handler struct {
 	Store *store.Store
+
+	// Prefix is the base path under which the store is served.
+	Prefix string
+
+	// ReadOnly rejects PUT and DELETE requests.
+	ReadOnly bool
+
+	// Logger is used to report internal errors.
+	// The standard logger is used if nil.
+	Logger *log.Logger
+
+	middlewares []Middleware
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	rawPath := r.URL.EscapedPath()
+	rawPath, ok := h.stripPrefix(r.URL.EscapedPath())
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if h.ReadOnly && (r.Method == "PUT" || r.Method == "DELETE") {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
 	switch r.Method {
 	case "PUT":
@@ -43,6 +117,33 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *Handler) stripPrefix(rawPath string) (string, bool) {
+	prefix := strings.Trim(h.Prefix, "/")
+	if prefix == "" {
+		return rawPath, true
+	}
+
+	prefix = "/" + prefix
+	if rawPath == prefix {
+		return "/", true
+	}
+
+	if !strings.HasPrefix(rawPath, prefix+"/") {
+		return "", false
+	}
+
+	return rawPath[len(prefix):], true
+}
+
+func (h *Handler) logError(err error) {
+	if h.Logger != nil {
+		h.Logger.Print(err)
+		return
+	}
+
+	log.Print(err)
+}
+
 func (h *Handler) putItem(w http.ResponseWriter, r *http.Request, rawPath string) {
 	if r.ContentLength == 0 {
 		w.WriteHeader(http.StatusBadRequest)
@@ -53,14 +154,14 @@ func (h *Handler) putItem(w http.ResponseWriter, r *http.Request, rawPath string
 	_, err := buffer.ReadFrom(r.Body)
 	r.Body.Close()
 	if err != nil {
-		log.Print(err)
+		h.logError(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	_, err = h.Store.Put(rawPath, json.ToValidJSON(buffer.Bytes()))
+	_, err = h.Store.PutContext(r.Context(), rawPath, json.ToValidJSON(buffer.Bytes()))
 	if err != nil {
-		log.Print(err)
+		h.logError(err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -69,44 +170,73 @@ func (h *Handler) putItem(w http.ResponseWriter, r *http.Request, rawPath string
 }
 
 func (h *Handler) getNode(w http.ResponseWriter, r *http.Request, rawPath string) {
+	var q *query.Query
+	if expr := r.URL.Query().Get("jq"); expr != "" {
+		var err error
+		q, err = query.Parse(expr,
+			query.WithMaxDepth(maxQueryDepth),
+			query.WithMaxValues(maxQueryValues),
+			query.WithMaxSize(maxQuerySize),
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var data []byte
 	if !strings.HasSuffix(rawPath, "/") {
-		item, err := h.Store.Get(rawPath)
+		item, err := h.Store.GetContext(r.Context(), rawPath)
 		if err != nil {
 			if err == store.ErrNotFound {
 				w.WriteHeader(http.StatusNotFound)
 				return
 			}
-			log.Print(err)
+			h.logError(err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(item.Data)
-		return
-	}
-
-	var items []brazier.Item
-	var err error
-	if r.URL.Query().Get("recursive") != "" {
-		items, err = h.Store.Tree(rawPath)
+		data = item.Data
 	} else {
-		items, err = h.Store.List(rawPath, 1, -1)
-	}
-	if err != nil {
-		if err == store.ErrNotFound {
+		var items []brazier.Item
+		var err error
+		if r.URL.Query().Get("recursive") != "" {
+			items, err = h.Store.TreeContext(r.Context(), rawPath)
+		} else {
+			items, err = h.Store.ListContext(r.Context(), rawPath, 1, -1)
+		}
+		if err != nil {
+			if err == store.ErrNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			h.logError(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data, err = json.MarshalList(items)
+		if err != nil {
+			h.logError(err)
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
 	}
 
-	data, err := json.MarshalList(items)
-	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusNotFound)
-		return
+	if q != nil {
+		results, err := q.Run(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// each result is written on its own line, as jq does
+		var buf bytes.Buffer
+		for _, result := range results {
+			buf.Write(result)
+			buf.WriteByte('\n')
+		}
+		data = buf.Bytes()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -114,10 +244,10 @@ func (h *Handler) getNode(w http.ResponseWriter, r *http.Request, rawPath string
 }
 
 func (h *Handler) deleteItem(w http.ResponseWriter, r *http.Request, rawPath string) {
-	err := h.Store.Delete(rawPath)
+	err := h.Store.DeleteContext(r.Context(), rawPath)
 	if err != nil {
 		if err != store.ErrNotFound {
-			log.Print(err)
+			h.logError(err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
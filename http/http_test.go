@@ -4,13 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	brazierHttp "github.com/asdine/brazier/http"
 	"github.com/asdine/brazier/mock"
 	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/tlsutil"
+	"github.com/asdine/brazier/tlsutil/tlstest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -204,6 +213,64 @@ func TestTree(t *testing.T) {
 	require.Equal(t, http.StatusNotFound, w.Code)
 }
 
+func TestQuery(t *testing.T) {
+	var h brazierHttp.Handler
+
+	registry := mock.NewRegistry(mock.NewBackend())
+	h.Store = store.NewStore(registry)
+
+	_, err := h.Store.Put("/config/app", []byte(`{"servers": [{"host": "a", "port": 80}, {"host": "b", "port": 8080}]}`))
+	require.NoError(t, err)
+	_, err = h.Store.Put("/config/name", []byte(`"brazier"`))
+	require.NoError(t, err)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", target, nil)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/config/app?jq=" + url.QueryEscape(".servers[0].host"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Equal(t, "\"a\"\n", w.Body.String())
+
+	w = get("/config/app?jq=" + url.QueryEscape(".servers[] | select(.port > 100) | {host}"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "{\"host\":\"b\"}\n", w.Body.String())
+
+	w = get("/config/?jq=" + url.QueryEscape(".[].key"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "\"app\"\n\"name\"\n", w.Body.String())
+
+	w = get("/config/app?jq=" + url.QueryEscape(".servers |"))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "invalid query: unexpected end of expression\n", w.Body.String())
+
+	w = get("/config/name?jq=" + url.QueryEscape(".host"))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = get("/config/unknown?jq=.")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// expensive queries are rejected
+	w = get("/config/name?jq=" + url.QueryEscape(strings.Repeat("[", 40)+"."+strings.Repeat("]", 40)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "invalid query: expression nested more than 32 levels\n", w.Body.String())
+
+	tens := "(.,.,.,.,.,.,.,.,.,.)"
+	w = get("/config/name?jq=" + url.QueryEscape(strings.Repeat(tens+"|", 5)+tens))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "the query produces more than 100000 values\n", w.Body.String())
+
+	_, err = h.Store.Put("/config/large", []byte(`"`+strings.Repeat("a", 1024)+`"`))
+	require.NoError(t, err)
+	w = get("/config/large?jq=" + url.QueryEscape(strings.Repeat(tens+"|", 3)+tens))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "the results of the query exceed 4194304 bytes\n", w.Body.String())
+}
+
 func TestBadRequests(t *testing.T) {
 	var h brazierHttp.Handler
 
@@ -277,3 +344,169 @@ func TestNestedBuckets(t *testing.T) {
 		require.Equal(t, http.StatusOK, w.Code)
 	})
 }
+
+func TestServerTLS(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := tlstest.NewCA(dir)
+	require.NoError(t, err)
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+
+	kp, err := tlsutil.NewKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	serverCfg, err := tlsutil.ServerConfig(kp, "")
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	s := store.NewStore(mock.NewRegistry(mock.NewBackend()))
+	_, err = s.Put("a/b", []byte(`"value"`))
+	require.NoError(t, err)
+
+	srv := brazierHttp.NewServer(s, brazierHttp.WithTLS(serverCfg))
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+
+	clientCfg, err := tlsutil.ClientConfig(ca.CertFile, "", "")
+	require.NoError(t, err)
+	client := http.Client{
+		Transport: &http.Transport{TLSClientConfig: clientCfg},
+	}
+
+	resp, err := client.Get("https://" + l.Addr().String() + "/a/b")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `"value"`, string(body))
+
+	// unknown authority
+	_, err = http.Get("https://" + l.Addr().String() + "/a/b")
+	require.Error(t, err)
+}
+
+func TestNewHandler(t *testing.T) {
+	s := store.NewStore(mock.NewRegistry(mock.NewBackend()))
+	_, err := s.Put("a/b", []byte(`"value"`))
+	require.NoError(t, err)
+
+	t.Run("Prefix", func(t *testing.T) {
+		h := brazierHttp.NewHandler(s, brazierHttp.WithPrefix("/kv/"))
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/kv/a/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `"value"`, w.Body.String())
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("GET", "/kv/a/", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("GET", "/a/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("GET", "/kva/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		mux := http.NewServeMux()
+		mux.Handle("/kv/", h)
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("GET", "/kv/a/b", nil)
+		mux.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		h := brazierHttp.NewHandler(s, brazierHttp.ReadOnly())
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("PUT", "/a/c", bytes.NewReader([]byte("value")))
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("DELETE", "/a/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("GET", "/a/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		var calls []string
+		mw := func(name string) brazierHttp.Middleware {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, name)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+
+		h := brazierHttp.NewHandler(s, brazierHttp.WithMiddleware(mw("first"), mw("second")))
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/a/b", nil)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, []string{"first", "second"}, calls)
+	})
+
+	t.Run("Logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := brazierHttp.NewHandler(s, brazierHttp.WithLogger(log.New(&buf, "", 0)))
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("PUT", "/a/", bytes.NewReader([]byte("value")))
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Equal(t, "forbidden\n", buf.String())
+	})
+}
+
+func TestNewServer(t *testing.T) {
+	s := store.NewStore(mock.NewRegistry(mock.NewBackend()))
+	_, err := s.Put("a/b", []byte(`"value"`))
+	require.NoError(t, err)
+
+	// each server has its own mux
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:")
+		require.NoError(t, err)
+
+		srv := brazierHttp.NewServer(
+			s,
+			brazierHttp.WithReadTimeout(time.Second),
+			brazierHttp.WithShutdownTimeout(time.Second),
+			brazierHttp.WithHandlerOptions(brazierHttp.ReadOnly()),
+		)
+		go srv.Serve(l)
+
+		resp, err := http.Get("http://" + l.Addr().String() + "/a/b")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		r, _ := http.NewRequest("DELETE", "http://"+l.Addr().String()+"/a/b", nil)
+		resp, err = http.DefaultClient.Do(r)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+		srv.Stop(time.Millisecond)
+	}
+}
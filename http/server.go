@@ -0,0 +1,109 @@
+package http
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	graceful "gopkg.in/tylerb/graceful.v1"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/store"
+)
+
+// NewServer returns a configured HTTP server.
+// Each server uses its own ServeMux, the store is served at the root path.
+func NewServer(r *store.Store, opts ...ServerOption) brazier.Server {
+	srv := newServer(opts...)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", NewHandler(r, srv.handlerOpts...))
+	srv.srv.Server.Handler = mux
+
+	return srv
+}
+
+func newServer(opts ...ServerOption) *server {
+	srv := server{
+		srv: &graceful.Server{
+			Server: &http.Server{},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&srv)
+	}
+
+	return &srv
+}
+
+// A ServerOption configures the HTTP server.
+type ServerOption func(*server)
+
+// WithTLS serves HTTPS using the given TLS configuration.
+func WithTLS(cfg *tls.Config) ServerOption {
+	return func(s *server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// WithHandlerOptions configures the handler serving the store.
+func WithHandlerOptions(opts ...HandlerOption) ServerOption {
+	return func(s *server) {
+		s.handlerOpts = append(s.handlerOpts, opts...)
+	}
+}
+
+// WithReadTimeout sets the maximum duration for reading an entire request.
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(s *server) {
+		s.srv.Server.ReadTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the response.
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(s *server) {
+		s.srv.Server.WriteTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the maximum amount of time to wait for the next request
+// when keep-alives are enabled.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *server) {
+		s.srv.Server.IdleTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets the maximum duration to wait for active connections
+// when the server is stopped. It overrides the timeout passed to Stop.
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return func(s *server) {
+		s.shutdownTimeout = d
+	}
+}
+
+type server struct {
+	srv             *graceful.Server
+	tlsConfig       *tls.Config
+	handlerOpts     []HandlerOption
+	shutdownTimeout time.Duration
+}
+
+func (s *server) Serve(l net.Listener) error {
+	if s.tlsConfig != nil {
+		l = tls.NewListener(l, s.tlsConfig)
+	}
+
+	return s.srv.Serve(l)
+}
+
+func (s *server) Stop(timeout time.Duration) {
+	if s.shutdownTimeout > 0 {
+		timeout = s.shutdownTimeout
+	}
+
+	s.srv.Stop(timeout)
+}
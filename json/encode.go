@@ -65,3 +65,15 @@ func ToValidJSON(data []byte) []byte {
 	out[j] = '"'
 	return out
 }
+
+// ToJSONString converts data to a JSON string, even if it is valid JSON.
+func ToJSONString(data []byte) []byte {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// a string can always be encoded
+	enc.Encode(string(data))
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
@@ -40,6 +40,19 @@ func TestToValidJSON(t *testing.T) {
 	}
 }
 
+func TestToJSONString(t *testing.T) {
+	tests := map[string]string{
+		`text`:            `"text"`,
+		`{"a": "<b>"}`:    `"{\"a\": \"<b>\"}"`,
+		"line\nbreak\\": `"line\nbreak\\"`,
+		`5`:               `"5"`,
+	}
+
+	for in, out := range tests {
+		require.Equal(t, out, string(json.ToJSONString([]byte(in))))
+	}
+}
+
 func BenchmarkToValidJSON(b *testing.B) {
 	invalidJSON := []byte(`
 
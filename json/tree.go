@@ -0,0 +1,129 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// NewTreeWriter returns a TreeWriter writing to w.
+func NewTreeWriter(w io.Writer) *TreeWriter {
+	return &TreeWriter{
+		w:      w,
+		counts: []int{0},
+	}
+}
+
+// A TreeWriter writes a tree of items as an indented JSON list, one node at a time,
+// without having to load the whole tree in memory.
+// Nodes must be written in tree order, a bucket being followed by its content.
+// The output is the same as MarshalListPretty.
+type TreeWriter struct {
+	w io.Writer
+	// number of elements written at each opened level, the first one being the root list.
+	counts []int
+	err    error
+}
+
+// WriteItem writes an item at the given depth.
+func (t *TreeWriter) WriteItem(depth int, key string, data []byte) error {
+	if !t.open(depth, key) {
+		return t.err
+	}
+
+	var value []byte
+	if len(data) == 0 {
+		value = []byte("null")
+	} else {
+		value, t.err = json.MarshalIndent(json.RawMessage(data), indent(depth)+"  ", "  ")
+		if t.err != nil {
+			return t.err
+		}
+	}
+
+	t.write(string(value), "\n", indent(depth), "}")
+	return t.err
+}
+
+// WriteBucket writes a bucket at the given depth. Its content must be written
+// right after with a depth increased by one.
+func (t *TreeWriter) WriteBucket(depth int, key string) error {
+	if t.open(depth, key) {
+		t.counts = append(t.counts, 0)
+	}
+
+	return t.err
+}
+
+// Close ends the list. It must be called once all the nodes are written.
+func (t *TreeWriter) Close() error {
+	t.closeLevels(0)
+
+	if t.counts[0] == 0 {
+		t.write("[]")
+	} else {
+		t.write("\n]")
+	}
+
+	return t.err
+}
+
+// open closes the levels deeper than depth then writes the beginning of an object
+// until its value.
+func (t *TreeWriter) open(depth int, key string) bool {
+	if t.err != nil {
+		return false
+	}
+
+	if depth < 0 || depth >= len(t.counts) {
+		t.err = errors.New("invalid node depth")
+		return false
+	}
+
+	t.closeLevels(depth)
+
+	if t.counts[depth] > 0 {
+		t.write(",\n")
+	} else {
+		t.write("[\n")
+	}
+	t.counts[depth]++
+
+	k, err := json.Marshal(key)
+	if err != nil {
+		t.err = err
+		return false
+	}
+
+	ind := indent(depth)
+	t.write(ind, "{\n", ind, `  "key": `, string(k), ",\n", ind, `  "value": `)
+	return t.err == nil
+}
+
+// closeLevels closes the buckets opened deeper than depth.
+func (t *TreeWriter) closeLevels(depth int) {
+	for len(t.counts)-1 > depth {
+		level := len(t.counts) - 1
+		if t.counts[level] == 0 {
+			t.write("null")
+		} else {
+			t.write("\n", indent(level)[2:], "]")
+		}
+		t.write("\n", indent(level-1), "}")
+		t.counts = t.counts[:level]
+	}
+}
+
+func (t *TreeWriter) write(s ...string) {
+	if t.err != nil {
+		return
+	}
+
+	_, t.err = io.WriteString(t.w, strings.Join(s, ""))
+}
+
+// indent returns the indentation of the objects at the given depth.
+func indent(depth int) string {
+	return strings.Repeat(" ", 2+4*depth)
+}
@@ -0,0 +1,70 @@
+package json_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/json"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTree writes the items using a TreeWriter.
+func writeTree(t *testing.T, tw *json.TreeWriter, items []brazier.Item, depth int) {
+	for _, i := range items {
+		if strings.HasSuffix(i.Key, "/") {
+			require.NoError(t, tw.WriteBucket(depth, i.Key))
+			writeTree(t, tw, i.Children, depth+1)
+			continue
+		}
+
+		require.NoError(t, tw.WriteItem(depth, i.Key, i.Data))
+	}
+}
+
+func TestTreeWriter(t *testing.T) {
+	tests := map[string][]brazier.Item{
+		"Empty": nil,
+		"Flat": {
+			{Key: "k1", Data: []byte(`"Data1"`)},
+			{Key: "k2", Data: []byte(`{"a": [1, 2], "b": {"c": true}}`)},
+		},
+		"Nested": {
+			{Key: "k1", Data: []byte(`"Data1"`)},
+			{Key: "b1/", Children: []brazier.Item{
+				{Key: "k1", Data: []byte(`"Data1"`)},
+				{Key: "b2/", Children: []brazier.Item{
+					{Key: "k<1>", Data: []byte(`{"a": 1}`)},
+				}},
+				{Key: "b3/"},
+				{Key: "k2", Data: []byte(`10`)},
+			}},
+			{Key: "b4/", Children: []brazier.Item{
+				{Key: "b5/", Children: []brazier.Item{
+					{Key: "k1", Data: []byte(`[1, 2]`)},
+				}},
+			}},
+			{Key: "b6/"},
+		},
+	}
+
+	for name, items := range tests {
+		t.Run(name, func(t *testing.T) {
+			expected, err := json.MarshalListPretty(items)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			tw := json.NewTreeWriter(&buf)
+			writeTree(t, tw, items, 0)
+			require.NoError(t, tw.Close())
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+
+	t.Run("InvalidDepth", func(t *testing.T) {
+		var buf bytes.Buffer
+		tw := json.NewTreeWriter(&buf)
+		require.Error(t, tw.WriteItem(1, "k1", []byte(`1`)))
+	})
+}
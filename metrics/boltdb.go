@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A BoltDB database able to report its statistics.
+// It is implemented by boltdb.Backend and boltdb.Registry.
+type BoltDB interface {
+	Stats() (*boltdb.Stats, error)
+}
+
+// RegisterBoltDB collects the statistics of the given database.
+// The name is used as the value of the db label.
+func (m *Metrics) RegisterBoltDB(name string, db BoltDB) error {
+	return m.Registry.Register(newBoltCollector(name, db))
+}
+
+func newBoltCollector(name string, db BoltDB) *boltCollector {
+	labels := prometheus.Labels{"db": name}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "boltdb", name), help, nil, labels)
+	}
+
+	return &boltCollector{
+		db:           db,
+		size:         desc("file_size_bytes", "Size of the database file."),
+		txs:          desc("transactions_total", "Number of read transactions started."),
+		openTxs:      desc("open_transactions", "Number of currently open read transactions."),
+		freePages:    desc("freelist_free_pages", "Number of free pages on the freelist."),
+		pendingPages: desc("freelist_pending_pages", "Number of pending pages on the freelist."),
+		freeAlloc:    desc("freelist_free_bytes", "Bytes allocated in free pages."),
+		freelistUsed: desc("freelist_inuse_bytes", "Bytes used by the freelist."),
+	}
+}
+
+type boltCollector struct {
+	db BoltDB
+
+	size         *prometheus.Desc
+	txs          *prometheus.Desc
+	openTxs      *prometheus.Desc
+	freePages    *prometheus.Desc
+	pendingPages *prometheus.Desc
+	freeAlloc    *prometheus.Desc
+	freelistUsed *prometheus.Desc
+}
+
+func (c *boltCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.txs
+	ch <- c.openTxs
+	ch <- c.freePages
+	ch <- c.pendingPages
+	ch <- c.freeAlloc
+	ch <- c.freelistUsed
+}
+
+func (c *boltCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.db.Stats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.size, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.txs, prometheus.CounterValue, float64(stats.TxN))
+	ch <- prometheus.MustNewConstMetric(c.openTxs, prometheus.GaugeValue, float64(stats.OpenTxN))
+	ch <- prometheus.MustNewConstMetric(c.freePages, prometheus.GaugeValue, float64(stats.FreePageN))
+	ch <- prometheus.MustNewConstMetric(c.pendingPages, prometheus.GaugeValue, float64(stats.PendingPageN))
+	ch <- prometheus.MustNewConstMetric(c.freeAlloc, prometheus.GaugeValue, float64(stats.FreeAlloc))
+	ch <- prometheus.MustNewConstMetric(c.freelistUsed, prometheus.GaugeValue, float64(stats.FreelistInuse))
+}
@@ -0,0 +1,160 @@
+// Package metrics collects Prometheus metrics about the store, the HTTP API
+// and the gRPC API.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asdine/brazier/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const namespace = "brazier"
+
+// New creates a set of metrics registered in their own Prometheus registry.
+func New() *Metrics {
+	m := Metrics{
+		Registry: prometheus.NewRegistry(),
+		storeOps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operations_total",
+			Help:      "Number of store operations by operation and outcome.",
+		}, []string{"op", "outcome"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of store operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests by method and status code.",
+		}, []string{"method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		rpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "calls_total",
+			Help:      "Number of gRPC calls by method and status code.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "call_duration_seconds",
+			Help:      "Duration of gRPC calls.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	m.Registry.MustRegister(
+		m.storeOps,
+		m.storeDuration,
+		m.httpRequests,
+		m.httpDuration,
+		m.rpcCalls,
+		m.rpcDuration,
+	)
+
+	return &m
+}
+
+// Metrics collects the Brazier metrics.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	storeOps      *prometheus.CounterVec
+	storeDuration *prometheus.HistogramVec
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	rpcCalls      *prometheus.CounterVec
+	rpcDuration   *prometheus.HistogramVec
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// Observe records a store operation. It implements the store.Observer interface.
+func (m *Metrics) Observe(op string, d time.Duration, err error) {
+	m.storeOps.WithLabelValues(op, outcome(err)).Inc()
+	m.storeDuration.WithLabelValues(op).Observe(d.Seconds())
+}
+
+// HTTPMiddleware records the method and the status code of every request.
+func (m *Metrics) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(&sw, r)
+
+		m.httpRequests.WithLabelValues(r.Method, strconv.Itoa(sw.status)).Inc()
+		m.httpDuration.WithLabelValues(r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// UnaryServerInterceptor records the method and the status code of every unary gRPC call.
+func (m *Metrics) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observeCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+// StreamServerInterceptor records the method and the status code of every streaming gRPC call.
+func (m *Metrics) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	m.observeCall(info.FullMethod, start, err)
+	return err
+}
+
+func (m *Metrics) observeCall(method string, start time.Time, err error) {
+	m.rpcCalls.WithLabelValues(method, grpc.Code(err).String()).Inc()
+	m.rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func outcome(err error) string {
+	switch err {
+	case nil:
+		return "success"
+	case store.ErrNotFound:
+		return "not_found"
+	case store.ErrAlreadyExists:
+		return "already_exists"
+	case store.ErrForbidden:
+		return "forbidden"
+	case store.ErrIsBucket:
+		return "is_bucket"
+	case store.ErrConflict:
+		return "conflict"
+	default:
+		return "error"
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,131 @@
+package metrics_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier/metrics"
+	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/metrics", nil)
+	m.Handler().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, err := ioutil.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestObserve(t *testing.T) {
+	m := metrics.New()
+
+	m.Observe(store.OpPut, time.Millisecond, nil)
+	m.Observe(store.OpPut, time.Millisecond, nil)
+	m.Observe(store.OpGet, time.Millisecond, store.ErrNotFound)
+	m.Observe(store.OpDelete, time.Millisecond, errors.New("failure"))
+
+	out := scrape(t, m)
+	require.Contains(t, out, `brazier_store_operations_total{op="put",outcome="success"} 2`)
+	require.Contains(t, out, `brazier_store_operations_total{op="get",outcome="not_found"} 1`)
+	require.Contains(t, out, `brazier_store_operations_total{op="delete",outcome="error"} 1`)
+	require.Contains(t, out, `brazier_store_operation_duration_seconds_count{op="put"} 2`)
+}
+
+func TestStoreObserver(t *testing.T) {
+	m := metrics.New()
+	s := store.NewStore(nil)
+	s.Observer = m
+
+	_, err := s.Get("/")
+	require.Equal(t, store.ErrForbidden, err)
+
+	out := scrape(t, m)
+	require.Contains(t, out, `brazier_store_operations_total{op="get",outcome="forbidden"} 1`)
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	m := metrics.New()
+
+	h := m.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, method := range []string{"GET", "GET", "DELETE"} {
+		r, _ := http.NewRequest(method, "/a", nil)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	out := scrape(t, m)
+	require.Contains(t, out, `brazier_http_requests_total{method="GET",status="200"} 2`)
+	require.Contains(t, out, `brazier_http_requests_total{method="DELETE",status="404"} 1`)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := metrics.New()
+
+	info := grpc.UnaryServerInfo{FullMethod: "/proto.Bucket/Get"}
+	_, err := m.UnaryServerInterceptor(context.Background(), nil, &info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	_, err = m.UnaryServerInterceptor(context.Background(), nil, &info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, grpc.Errorf(codes.NotFound, "not found")
+	})
+	require.Error(t, err)
+
+	out := scrape(t, m)
+	require.Contains(t, out, `brazier_grpc_calls_total{code="OK",method="/proto.Bucket/Get"} 1`)
+	require.Contains(t, out, `brazier_grpc_calls_total{code="NotFound",method="/proto.Bucket/Get"} 1`)
+}
+
+type boltDB struct {
+	err error
+}
+
+func (b *boltDB) Stats() (*boltdb.Stats, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &boltdb.Stats{
+		Stats: bolt.Stats{TxN: 5, FreePageN: 2},
+		Size:  32768,
+	}, nil
+}
+
+func TestRegisterBoltDB(t *testing.T) {
+	m := metrics.New()
+
+	err := m.RegisterBoltDB("registry", &boltDB{})
+	require.NoError(t, err)
+
+	err = m.RegisterBoltDB("backend", &boltDB{})
+	require.NoError(t, err)
+
+	err = m.RegisterBoltDB("backend", &boltDB{})
+	require.Error(t, err)
+
+	out := scrape(t, m)
+	require.Contains(t, out, `brazier_boltdb_file_size_bytes{db="registry"} 32768`)
+	require.Contains(t, out, `brazier_boltdb_file_size_bytes{db="backend"} 32768`)
+	require.Contains(t, out, `brazier_boltdb_transactions_total{db="backend"} 5`)
+	require.Contains(t, out, `brazier_boltdb_freelist_free_pages{db="backend"} 2`)
+}
@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"time"
+
 	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/store"
 )
@@ -15,15 +17,16 @@ func NewBucket(name string) *Bucket {
 
 // Bucket is a mock implementation of a bucket.
 type Bucket struct {
-	Name          string
-	data          map[string]*brazier.Item
-	index         []*brazier.Item
-	Children      []*Bucket
-	SaveInvoked   bool
-	GetInvoked    bool
-	DeleteInvoked bool
-	PageInvoked   bool
-	CloseInvoked  bool
+	Name           string
+	data           map[string]*brazier.Item
+	index          []*brazier.Item
+	Children       []*Bucket
+	SaveInvoked    bool
+	GetInvoked     bool
+	DeleteInvoked  bool
+	PageInvoked    bool
+	IterateInvoked bool
+	CloseInvoked   bool
 }
 
 // Save user data to the bucket. Returns an Item.
@@ -41,10 +44,26 @@ func (b *Bucket) Save(key string, data []byte) (*brazier.Item, error) {
 	} else {
 		item.Data = data
 	}
+	item.UpdatedAt = time.Now()
 
 	return item, nil
 }
 
+// SaveIf saves user data to the bucket if the item was last modified at updatedAt,
+// zero if it doesn't exist. Returns store.ErrConflict otherwise.
+func (b *Bucket) SaveIf(key string, data []byte, updatedAt time.Time) (*brazier.Item, error) {
+	var current time.Time
+	if item, ok := b.data[key]; ok {
+		current = item.UpdatedAt
+	}
+
+	if !current.Equal(updatedAt) {
+		return nil, store.ErrConflict
+	}
+
+	return b.Save(key, data)
+}
+
 // Get an item by key.
 func (b *Bucket) Get(key string) (*brazier.Item, error) {
 	b.GetInvoked = true
@@ -60,8 +79,14 @@ func (b *Bucket) Get(key string) (*brazier.Item, error) {
 func (b *Bucket) Delete(key string) error {
 	b.DeleteInvoked = true
 
-	if _, ok := b.data[key]; ok {
+	if item, ok := b.data[key]; ok {
 		delete(b.data, key)
+		for i := range b.index {
+			if b.index[i] == item {
+				b.index = append(b.index[:i], b.index[i+1:]...)
+				break
+			}
+		}
 		return nil
 	}
 
@@ -103,6 +128,21 @@ func (b *Bucket) Page(page int, perPage int) ([]brazier.Item, error) {
 	return items, nil
 }
 
+// Iterate calls fn for every item of the bucket.
+func (b *Bucket) Iterate(fn func(*brazier.Item) error) error {
+	b.IterateInvoked = true
+
+	for _, item := range b.index {
+		i := *item
+		err := fn(&i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Close bucket.
 func (b *Bucket) Close() error {
 	b.CloseInvoked = true
@@ -1,9 +1,11 @@
 package mock_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/mock"
 	"github.com/asdine/brazier/store"
 	"github.com/stretchr/testify/require"
@@ -157,3 +159,35 @@ func TestBucketPage(t *testing.T) {
 	require.Equal(t, "A", list[0].Key)
 	require.Equal(t, "T", list[19].Key)
 }
+
+func TestBucketIterate(t *testing.T) {
+	b := mock.NewBucket("a")
+
+	for i := 0; i < 20; i++ {
+		_, err := b.Save(fmt.Sprintf("%c", i+65), []byte("Data"))
+		require.NoError(t, err)
+	}
+
+	var keys []string
+	err := b.Iterate(func(item *brazier.Item) error {
+		keys = append(keys, item.Key)
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, b.IterateInvoked)
+	require.Len(t, keys, 20)
+	require.Equal(t, "A", keys[0])
+	require.Equal(t, "T", keys[19])
+
+	stop := errors.New("stop")
+	keys = nil
+	err = b.Iterate(func(item *brazier.Item) error {
+		keys = append(keys, item.Key)
+		if len(keys) == 5 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, stop, err)
+	require.Len(t, keys, 5)
+}
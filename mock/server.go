@@ -19,6 +19,7 @@ func NewServer(r *store.Store) brazier.Server {
 type Server struct {
 	ServeInvoked bool
 	StopInvoked  bool
+	Ready        bool
 	quit         chan struct{}
 }
 
@@ -34,3 +35,8 @@ func (s *Server) Stop(time.Duration) {
 	s.StopInvoked = true
 	s.quit <- struct{}{}
 }
+
+// SetReady marks the mock server as ready or not ready
+func (s *Server) SetReady(ready bool) {
+	s.Ready = ready
+}
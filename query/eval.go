@@ -0,0 +1,650 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// A node of the expression returns the values produced from its input.
+type node interface {
+	eval(st *state, v interface{}) ([]interface{}, error)
+}
+
+// state is shared by the nodes during an evaluation.
+type state struct {
+	// maxValues is the maximum number of values produced, 0 for no limit.
+	maxValues int
+	values    int
+	// err is set once the limit is exceeded, the evaluation fails even if
+	// the error is ignored by a node, e.g. by the '?' suffix.
+	err error
+}
+
+// produce accounts for n values about to be produced by a node.
+func (st *state) produce(n int) error {
+	if st.maxValues <= 0 || st.err != nil {
+		return st.err
+	}
+
+	st.values += n
+	if st.values > st.maxValues {
+		st.err = fmt.Errorf("the query produces more than %d values", st.maxValues)
+	}
+
+	return st.err
+}
+
+type identityNode struct{}
+
+func (n *identityNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	return []interface{}{v}, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	return []interface{}{n.value}, nil
+}
+
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	r, err := index(v, n.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{r}, nil
+}
+
+type pipeNode struct {
+	left, right node
+}
+
+func (n *pipeNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, l := range left {
+		r, err := n.right.eval(st, l)
+		if err != nil {
+			return nil, err
+		}
+		if err := st.produce(len(r)); err != nil {
+			return nil, err
+		}
+		out = append(out, r...)
+	}
+
+	return out, nil
+}
+
+type commaNode struct {
+	left, right node
+}
+
+func (n *commaNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(left, right...), nil
+}
+
+type iterateNode struct{}
+
+func (n *iterateNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		if err := st.produce(len(v)); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case *object:
+		if err := st.produce(len(v.keys)); err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(v.keys))
+		for i, k := range v.keys {
+			out[i] = v.values[k]
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+}
+
+// indexNode returns the field or the element of the value of target
+// designated by the values of the index, both evaluated with the same input.
+type indexNode struct {
+	target, index node
+}
+
+func (n *indexNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := n.index.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := st.produce(len(targets) * len(indexes)); err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, t := range targets {
+		for _, i := range indexes {
+			r, err := index(t, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+	}
+
+	return out, nil
+}
+
+// index returns the field of an object or the element of an array. Indexing null returns null.
+func index(v, i interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		switch i.(type) {
+		case string, json.Number, float64, nil:
+			return nil, nil
+		}
+	case *object:
+		if k, ok := i.(string); ok {
+			r, _ := v.get(k)
+			return r, nil
+		}
+	case []interface{}:
+		if f, ok := toFloat(i); ok {
+			idx := int(f)
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx < 0 || idx >= len(v) {
+				return nil, nil
+			}
+			return v[idx], nil
+		}
+	}
+
+	if s, ok := i.(string); ok {
+		return nil, fmt.Errorf("cannot index %s with %q", typeName(v), s)
+	}
+
+	return nil, fmt.Errorf("cannot index %s with %s", typeName(v), typeName(i))
+}
+
+type sliceNode struct {
+	target, from, to node
+}
+
+func (n *sliceNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	targets, err := n.target.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	froms, err := n.bound(st, n.from, v)
+	if err != nil {
+		return nil, err
+	}
+
+	tos, err := n.bound(st, n.to, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := st.produce(len(targets) * len(froms) * len(tos)); err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, t := range targets {
+		for _, from := range froms {
+			for _, to := range tos {
+				r, err := slice(t, from, to)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, r)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (n *sliceNode) bound(st *state, b node, v interface{}) ([]interface{}, error) {
+	if b == nil {
+		return []interface{}{nil}, nil
+	}
+
+	return b.eval(st, v)
+}
+
+// slice returns a part of an array or of a string. Null bounds designate the ends.
+func slice(v, from, to interface{}) (interface{}, error) {
+	var length int
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		length = len(v)
+	case string:
+		length = utf8.RuneCountInString(v)
+	default:
+		return nil, fmt.Errorf("cannot slice %s", typeName(v))
+	}
+
+	start, err := sliceBound(from, 0, length)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := sliceBound(to, length, length)
+	if err != nil {
+		return nil, err
+	}
+
+	if end < start {
+		end = start
+	}
+
+	if s, ok := v.(string); ok {
+		return string([]rune(s)[start:end]), nil
+	}
+
+	return v.([]interface{})[start:end], nil
+}
+
+func sliceBound(b interface{}, def, length int) (int, error) {
+	if b == nil {
+		return def, nil
+	}
+
+	f, ok := toFloat(b)
+	if !ok {
+		return 0, fmt.Errorf("cannot slice with %s", typeName(b))
+	}
+
+	i := int(f)
+	if i < 0 {
+		i += length
+	}
+
+	switch {
+	case i < 0:
+		return 0, nil
+	case i > length:
+		return length, nil
+	}
+
+	return i, nil
+}
+
+// tryNode ignores the errors of its expression, as the '?' suffix.
+type tryNode struct {
+	n node
+}
+
+func (n *tryNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	out, err := n.n.eval(st, v)
+	if err != nil {
+		return nil, nil
+	}
+
+	return out, nil
+}
+
+type arrayNode struct {
+	n node
+}
+
+func (n *arrayNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	if n.n == nil {
+		return []interface{}{[]interface{}{}}, nil
+	}
+
+	list, err := n.n.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if list == nil {
+		list = []interface{}{}
+	}
+
+	return []interface{}{list}, nil
+}
+
+type objectEntry struct {
+	key, value node
+}
+
+// objectNode builds objects. When keys or values produce several results,
+// an object is built for each combination.
+type objectNode struct {
+	entries []objectEntry
+}
+
+func (n *objectNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	objects := []*object{newObject()}
+
+	for _, e := range n.entries {
+		keys, err := e.key.eval(st, v)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := e.value.eval(st, v)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := st.produce(len(objects) * len(keys) * len(values)); err != nil {
+			return nil, err
+		}
+
+		var next []*object
+		for _, o := range objects {
+			for _, k := range keys {
+				s, ok := k.(string)
+				if !ok {
+					return nil, fmt.Errorf("object keys must be strings, not %s", typeName(k))
+				}
+
+				for _, value := range values {
+					c := o.copy()
+					c.set(s, value)
+					next = append(next, c)
+				}
+			}
+		}
+		objects = next
+	}
+
+	out := make([]interface{}, len(objects))
+	for i, o := range objects {
+		out[i] = o
+	}
+
+	return out, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := st.produce(len(left) * len(right)); err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, r := range right {
+		for _, l := range left {
+			c := compare(l, r)
+
+			var b bool
+			switch n.op {
+			case "==":
+				b = c == 0
+			case "!=":
+				b = c != 0
+			case "<":
+				b = c < 0
+			case "<=":
+				b = c <= 0
+			case ">":
+				b = c > 0
+			case ">=":
+				b = c >= 0
+			}
+			out = append(out, b)
+		}
+	}
+
+	return out, nil
+}
+
+// logicNode evaluates 'and' and 'or', the right side is only evaluated when needed.
+type logicNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	left, err := n.left.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, l := range left {
+		if truthy(l) != n.and {
+			out = append(out, !n.and)
+			continue
+		}
+
+		right, err := n.right.eval(st, v)
+		if err != nil {
+			return nil, err
+		}
+		if err := st.produce(len(right)); err != nil {
+			return nil, err
+		}
+		for _, r := range right {
+			out = append(out, truthy(r))
+		}
+	}
+
+	return out, nil
+}
+
+type negateNode struct {
+	n node
+}
+
+func (n *negateNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	values, err := n.n.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]interface{}, len(values))
+	for i, value := range values {
+		f, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", typeName(value))
+		}
+		out[i] = -f
+	}
+
+	return out, nil
+}
+
+type callNode struct {
+	name string
+	fn   func(st *state, v interface{}, arg node) ([]interface{}, error)
+	arg  node
+}
+
+func (n *callNode) eval(st *state, v interface{}) ([]interface{}, error) {
+	out, err := n.fn(st, v, n.arg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.name, err)
+	}
+
+	return out, nil
+}
+
+type builtin struct {
+	arg bool
+	fn  func(st *state, v interface{}, arg node) ([]interface{}, error)
+}
+
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"select": {true, builtinSelect},
+		"map":    {true, builtinMap},
+		"has":    {true, builtinHas},
+		"length": {false, builtinLength},
+		"keys":   {false, builtinKeys},
+		"type":   {false, builtinType},
+		"not":    {false, builtinNot},
+		"empty":  {false, builtinEmpty},
+	}
+}
+
+func builtinSelect(st *state, v interface{}, arg node) ([]interface{}, error) {
+	conds, err := arg.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, c := range conds {
+		if truthy(c) {
+			out = append(out, v)
+		}
+	}
+
+	return out, nil
+}
+
+func builtinMap(st *state, v interface{}, arg node) ([]interface{}, error) {
+	values, err := (&iterateNode{}).eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []interface{}{}
+	for _, value := range values {
+		r, err := arg.eval(st, value)
+		if err != nil {
+			return nil, err
+		}
+		if err := st.produce(len(r)); err != nil {
+			return nil, err
+		}
+		list = append(list, r...)
+	}
+
+	return []interface{}{list}, nil
+}
+
+func builtinHas(st *state, v interface{}, arg node) ([]interface{}, error) {
+	keys, err := arg.eval(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	for _, k := range keys {
+		switch v := v.(type) {
+		case *object:
+			s, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot check whether an object has a key of type %s", typeName(k))
+			}
+			_, found := v.get(s)
+			out = append(out, found)
+		case []interface{}:
+			f, ok := toFloat(k)
+			if !ok {
+				return nil, fmt.Errorf("cannot check whether an array has a key of type %s", typeName(k))
+			}
+			out = append(out, f >= 0 && int(f) < len(v))
+		default:
+			return nil, fmt.Errorf("cannot check whether %s has a key", typeName(v))
+		}
+	}
+
+	return out, nil
+}
+
+func builtinLength(st *state, v interface{}, arg node) ([]interface{}, error) {
+	var l float64
+
+	switch v := v.(type) {
+	case nil:
+	case json.Number, float64:
+		f, _ := toFloat(v)
+		if f < 0 {
+			f = -f
+		}
+		l = f
+	case string:
+		l = float64(utf8.RuneCountInString(v))
+	case []interface{}:
+		l = float64(len(v))
+	case *object:
+		l = float64(len(v.keys))
+	default:
+		return nil, fmt.Errorf("%s has no length", typeName(v))
+	}
+
+	return []interface{}{l}, nil
+}
+
+func builtinKeys(st *state, v interface{}, arg node) ([]interface{}, error) {
+	switch v := v.(type) {
+	case *object:
+		return []interface{}{stringList(sortedKeys(v))}, nil
+	case []interface{}:
+		keys := make([]interface{}, len(v))
+		for i := range v {
+			keys[i] = float64(i)
+		}
+		return []interface{}{keys}, nil
+	}
+
+	return nil, fmt.Errorf("%s has no keys", typeName(v))
+}
+
+func builtinType(st *state, v interface{}, arg node) ([]interface{}, error) {
+	return []interface{}{typeName(v)}, nil
+}
+
+func builtinNot(st *state, v interface{}, arg node) ([]interface{}, error) {
+	return []interface{}{!truthy(v)}, nil
+}
+
+func builtinEmpty(st *state, v interface{}, arg node) ([]interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,484 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokIdent
+	tokString
+	tokNumber
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits the expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{tokString, expr[i : end+1], i})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(expr) && strings.IndexByte("0123456789.eE", expr[end]) >= 0 {
+				if (expr[end] == 'e' || expr[end] == 'E') && end+1 < len(expr) && (expr[end+1] == '-' || expr[end+1] == '+') {
+					end++
+				}
+				end++
+			}
+			tokens = append(tokens, token{tokNumber, expr[i:end], i})
+			i = end
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i
+			for end < len(expr) && (expr[end] == '_' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{tokIdent, expr[i:end], i})
+			i = end
+		default:
+			if i+1 < len(expr) {
+				if op := expr[i : i+2]; op == "==" || op == "!=" || op == "<=" || op == ">=" {
+					tokens = append(tokens, token{tokPunct, op, i})
+					i += 2
+					continue
+				}
+			}
+
+			if strings.IndexByte(".[](){}|,:?<>-", c) < 0 {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{tokPunct, string(c), i})
+			i++
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// parser is a recursive descent parser. From the lowest to the highest precedence:
+// pipe '|', comma ',', 'or', 'and', comparisons, then terms followed by suffixes.
+type parser struct {
+	tokens []token
+	pos    int
+	// depth is the current nesting of the sub-expressions, limited by maxDepth
+	// unless it is zero.
+	depth    int
+	maxDepth int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given punctuation or keyword.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+
+	return fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func (p *parser) parsePipe() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		return nil, fmt.Errorf("expression nested more than %d levels", p.maxDepth)
+	}
+
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("|") {
+		right, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		left = &pipeNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseComma() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(",") {
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = &commaNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: false, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("and") {
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parsePostfix()
+			if err != nil {
+				return nil, err
+			}
+			return &compareNode{op, left, right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("?"):
+			n = &tryNode{n}
+		case p.peek().text == "[" && p.peek().kind == tokPunct:
+			p.next()
+			n, err = p.parseBrackets(n)
+		case p.peek().text == "." && p.peek().kind == tokPunct:
+			next := p.tokens[p.pos+1]
+			if next.kind != tokIdent && next.kind != tokString && next.text != "[" {
+				return n, nil
+			}
+			p.next()
+			n, err = p.parseDot(n)
+		default:
+			return n, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseDot parses what follows a '.' applied to n: a field name, a quoted field name or brackets.
+func (p *parser) parseDot(n node) (node, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tokIdent:
+		p.next()
+		return &pipeNode{n, &fieldNode{t.text}}, nil
+	case t.kind == tokString:
+		p.next()
+		s, err := unquote(t)
+		if err != nil {
+			return nil, err
+		}
+		return &pipeNode{n, &fieldNode{s}}, nil
+	case p.accept("["):
+		return p.parseBrackets(n)
+	}
+
+	return n, nil
+}
+
+// parseBrackets parses the iteration, index or slice applied to n, once '[' is consumed.
+func (p *parser) parseBrackets(n node) (node, error) {
+	if p.accept("]") {
+		return &pipeNode{n, &iterateNode{}}, nil
+	}
+
+	var from, to node
+	var err error
+
+	if !p.accept(":") {
+		from, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.accept("]") {
+			return &indexNode{n, from}, nil
+		}
+
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.accept("]") {
+		to, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &sliceNode{n, from, to}, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokString:
+		s, err := unquote(t)
+		if err != nil {
+			return nil, err
+		}
+		return &literalNode{s}, nil
+	case tokNumber:
+		if _, err := json.Number(t.text).Float64(); err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return &literalNode{json.Number(t.text)}, nil
+	case tokIdent:
+		return p.parseIdent(t)
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch t.text {
+	case ".":
+		return p.parseDot(&identityNode{})
+	case "-":
+		n, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return &negateNode{n}, nil
+	case "(":
+		n, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case "[":
+		if p.accept("]") {
+			return &arrayNode{}, nil
+		}
+		n, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return &arrayNode{n}, p.expect("]")
+	case "{":
+		return p.parseObject()
+	}
+
+	p.pos--
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseIdent(t token) (node, error) {
+	switch t.text {
+	case "true":
+		return &literalNode{true}, nil
+	case "false":
+		return &literalNode{false}, nil
+	case "null":
+		return &literalNode{nil}, nil
+	}
+
+	b, ok := builtins[t.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", t.text, t.pos)
+	}
+
+	var arg node
+	if p.accept("(") {
+		var err error
+		arg, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(")")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (arg != nil) != b.arg {
+		if b.arg {
+			return nil, fmt.Errorf("%s requires an argument", t.text)
+		}
+		return nil, fmt.Errorf("%s doesn't take an argument", t.text)
+	}
+
+	return &callNode{name: t.text, fn: b.fn, arg: arg}, nil
+}
+
+// parseObject parses an object construction, once '{' is consumed.
+// Keys are names, strings or expressions in parentheses, {name} being short for {name: .name}.
+func (p *parser) parseObject() (node, error) {
+	var o objectNode
+
+	if p.accept("}") {
+		return &o, nil
+	}
+
+	for {
+		var e objectEntry
+		t := p.next()
+
+		switch {
+		case t.kind == tokIdent:
+			e.key = &literalNode{t.text}
+		case t.kind == tokString:
+			s, err := unquote(t)
+			if err != nil {
+				return nil, err
+			}
+			e.key = &literalNode{s}
+		case t.kind == tokPunct && t.text == "(":
+			key, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			err = p.expect(")")
+			if err != nil {
+				return nil, err
+			}
+			e.key = key
+		default:
+			p.pos--
+			return nil, p.unexpected()
+		}
+
+		if p.accept(":") {
+			value, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			e.value = value
+		} else if lit, ok := e.key.(*literalNode); ok {
+			e.value = &fieldNode{lit.value.(string)}
+		} else {
+			return nil, p.unexpected()
+		}
+
+		o.entries = append(o.entries, e)
+
+		if p.accept("}") {
+			return &o, nil
+		}
+
+		err := p.expect(",")
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func unquote(t token) (string, error) {
+	var s string
+	err := json.Unmarshal([]byte(t.text), &s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s at position %d", t.text, t.pos)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,123 @@
+// Package query implements a subset of the jq language to extract and transform JSON values.
+//
+// Supported expressions:
+//
+//	.                      the input
+//	.foo, ."foo", .["foo"] the field of an object, null if it doesn't exist
+//	.[0], .[-1]            the element of an array, negative indexes start from the end
+//	.[1:3], .[:2], .[2:]   a part of an array or of a string
+//	.[]                    every element of an array or value of an object
+//	a | b                  the results of a are the inputs of b
+//	a, b                   the results of a followed by the results of b
+//	[a]                    an array of the results of a
+//	{a: .b, "c": .d, e}    an object, {e} being short for {e: .e}
+//	==, !=, <, <=, >, >=   comparisons
+//	and, or                boolean operators, false and null being false
+//	a?                     a without its errors
+//	select(f), map(f), has(key), length, keys, type, not, empty
+package query
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Query is a compiled expression.
+type Query struct {
+	root      node
+	maxDepth  int
+	maxValues int
+	maxSize   int
+}
+
+// An Option limits the resources used by a query, e.g. when it is given by a remote client.
+type Option func(*Query)
+
+// WithMaxDepth limits the nesting of the parentheses, brackets, braces and function
+// arguments of the expression. Deeper expressions are rejected by Parse.
+func WithMaxDepth(n int) Option {
+	return func(q *Query) {
+		q.maxDepth = n
+	}
+}
+
+// WithMaxValues limits the number of values produced while running the query, the
+// intermediate values included, e.g. the elements of an iterated array.
+// Run fails once the limit is exceeded.
+func WithMaxValues(n int) Option {
+	return func(q *Query) {
+		q.maxValues = n
+	}
+}
+
+// WithMaxSize limits the total size of the encoded results of Run, in bytes.
+func WithMaxSize(n int) Option {
+	return func(q *Query) {
+		q.maxSize = n
+	}
+}
+
+// Parse compiles an expression. There is no limit unless options are given.
+func Parse(expr string, opts ...Option) (*Query, error) {
+	var q Query
+	for _, opt := range opts {
+		opt(&q)
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+
+	p := parser{tokens: tokens, maxDepth: q.maxDepth}
+	if p.peek().kind == tokEOF {
+		return nil, errors.New("invalid query: empty expression")
+	}
+
+	root, err := p.parsePipe()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+
+	q.root = root
+	return &q, nil
+}
+
+// Run applies the query to a JSON value and returns the results, encoded as compact JSON.
+func (q *Query) Run(data []byte) ([][]byte, error) {
+	v, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	st := state{maxValues: q.maxValues}
+	values, err := q.root.eval(&st, v)
+	if st.err != nil {
+		return nil, st.err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var size int
+	out := make([][]byte, len(values))
+	for i, value := range values {
+		var buf bytes.Buffer
+		err = encode(&buf, value)
+		if err != nil {
+			return nil, err
+		}
+
+		size += buf.Len()
+		if q.maxSize > 0 && size > q.maxSize {
+			return nil, fmt.Errorf("the results of the query exceed %d bytes", q.maxSize)
+		}
+		out[i] = buf.Bytes()
+	}
+
+	return out, nil
+}
@@ -0,0 +1,172 @@
+package query_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asdine/brazier/query"
+	"github.com/stretchr/testify/require"
+)
+
+const doc = `{
+	"name": "prod",
+	"servers": [
+		{"host": "a.example.com", "port": 80, "tags": ["web"]},
+		{"host": "b.example.com", "port": 8080, "tags": []},
+		{"host": "c.example.com", "port": 443, "tags": ["web", "tls"]}
+	],
+	"limits": {"cpu": 2, "memory": 1024},
+	"html": "<b>&</b>",
+	"empty": null
+}`
+
+func run(t *testing.T, expr, data string) string {
+	q, err := query.Parse(expr)
+	require.NoError(t, err)
+
+	out, err := q.Run([]byte(data))
+	require.NoError(t, err)
+
+	results := make([]string, len(out))
+	for i, r := range out {
+		results[i] = string(r)
+	}
+
+	return strings.Join(results, "\n")
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr, expected string
+	}{
+		{`.`, `{"name":"prod","servers":[{"host":"a.example.com","port":80,"tags":["web"]},{"host":"b.example.com","port":8080,"tags":[]},{"host":"c.example.com","port":443,"tags":["web","tls"]}],"limits":{"cpu":2,"memory":1024},"html":"<b>&</b>","empty":null}`},
+		{`.name`, `"prod"`},
+		{`."name"`, `"prod"`},
+		{`.["name"]`, `"prod"`},
+		{`.missing`, `null`},
+		{`.missing.deeper`, `null`},
+		{`.empty[0]`, `null`},
+		{`.html`, `"<b>&</b>"`},
+		{`.servers[0].host`, `"a.example.com"`},
+		{`.servers[-1].port`, `443`},
+		{`.servers[10]`, `null`},
+		{`.servers[1:].[0].host`, `"b.example.com"`},
+		{`.servers[:1] | length`, `1`},
+		{`.servers[-2:] | map(.port)`, `[8080,443]`},
+		{`.name[1:3]`, `"ro"`},
+		{`.servers[].host`, "\"a.example.com\"\n\"b.example.com\"\n\"c.example.com\""},
+		{`.limits[]`, "2\n1024"},
+		{`.servers[] | select(.port > 100) | .host`, "\"b.example.com\"\n\"c.example.com\""},
+		{`.servers[] | select(.port == 80 or .port == 443) | .port`, "80\n443"},
+		{`.servers[] | select(.tags | length > 0 and has(1)) | .host`, `"c.example.com"`},
+		{`[.servers[] | select(.tags[0] == "web") | .port]`, `[80,443]`},
+		{`.servers[0] | {host, p: .port, "first tag": .tags[0]}`, `{"host":"a.example.com","p":80,"first tag":"web"}`},
+		{`{(.name): .limits.cpu}`, `{"prod":2}`},
+		{`{name, tag: .servers[2].tags[]}`, "{\"name\":\"prod\",\"tag\":\"web\"}\n{\"name\":\"prod\",\"tag\":\"tls\"}"},
+		{`.name, .limits.cpu`, "\"prod\"\n2"},
+		{`.limits | keys`, `["cpu","memory"]`},
+		{`.servers | keys`, `[0,1,2]`},
+		{`.limits | has("cpu"), has("disk")`, "true\nfalse"},
+		{`[.name, .limits, .servers, .empty, 1, true] | map(type)`, `["string","object","array","null","number","boolean"]`},
+		{`.empty | not`, `true`},
+		{`.servers[] | empty`, ``},
+		{`[]`, `[]`},
+		{`{}`, `{}`},
+		{`-.limits.cpu`, `-2`},
+		{`.name.foo?`, ``},
+		{`.servers[].tags[0]?`, "\"web\"\nnull\n\"web\""},
+		{`(.limits.cpu, .limits.memory) >= 2`, "true\ntrue"},
+		{`.servers[0].port != 80`, `false`},
+		{`null < false and false < 0 and 0 < "" and "" < [] and [] < {}`, `true`},
+		{`1.5e3`, `1.5e3`},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, run(t, test.expr, doc), test.expr)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	parseErrors := []struct {
+		expr, err string
+	}{
+		{``, `invalid query: empty expression`},
+		{`.foo |`, `invalid query: unexpected end of expression`},
+		{`.foo bar`, `invalid query: unexpected "bar" at position 5`},
+		{`.[1`, `invalid query: unexpected end of expression`},
+		{`.foo ; .bar`, `invalid query: unexpected character ';' at position 5`},
+		{`"abc`, `invalid query: unterminated string at position 0`},
+		{`foo`, `invalid query: unknown function "foo" at position 0`},
+		{`select`, `invalid query: select requires an argument`},
+		{`length(.)`, `invalid query: length doesn't take an argument`},
+		{`{.a}`, `invalid query: unexpected "." at position 1`},
+	}
+
+	for _, test := range parseErrors {
+		_, err := query.Parse(test.expr)
+		require.EqualError(t, err, test.err, test.expr)
+	}
+
+	runErrors := []struct {
+		expr, err string
+	}{
+		{`.name.foo`, `cannot index string with "foo"`},
+		{`.servers.host`, `cannot index array with "host"`},
+		{`.limits[0]`, `cannot index object with number`},
+		{`.name[]`, `cannot iterate over string`},
+		{`.limits[1:]`, `cannot slice object`},
+		{`{(.limits): 1}`, `object keys must be strings, not object`},
+		{`.name | keys`, `keys: string has no keys`},
+		{`-.name`, `cannot negate string`},
+	}
+
+	for _, test := range runErrors {
+		q, err := query.Parse(test.expr)
+		require.NoError(t, err, test.expr)
+		_, err = q.Run([]byte(doc))
+		require.EqualError(t, err, test.err, test.expr)
+	}
+
+	q, err := query.Parse(`.`)
+	require.NoError(t, err)
+	_, err = q.Run([]byte(`{"a": 1`))
+	require.Error(t, err)
+	_, err = q.Run([]byte(`1 2`))
+	require.Error(t, err)
+}
+
+func TestLimits(t *testing.T) {
+	_, err := query.Parse(`[{a: (.b)}]`, query.WithMaxDepth(3))
+	require.NoError(t, err)
+	_, err = query.Parse(`[{a: ([.b])}]`, query.WithMaxDepth(3))
+	require.EqualError(t, err, "invalid query: expression nested more than 3 levels")
+
+	tens := `(.,.,.,.,.,.,.,.,.,.)`
+	q, err := query.Parse(tens+"|"+tens, query.WithMaxValues(120))
+	require.NoError(t, err)
+	out, err := q.Run([]byte(`1`))
+	require.NoError(t, err)
+	require.Len(t, out, 100)
+
+	q, err = query.Parse(tens+"|"+tens+"|"+tens, query.WithMaxValues(1000))
+	require.NoError(t, err)
+	_, err = q.Run([]byte(`1`))
+	require.EqualError(t, err, "the query produces more than 1000 values")
+
+	// the limit applies to the ignored errors too
+	q, err = query.Parse("("+tens+"|"+tens+"|"+tens+")?", query.WithMaxValues(1000))
+	require.NoError(t, err)
+	_, err = q.Run([]byte(`1`))
+	require.EqualError(t, err, "the query produces more than 1000 values")
+
+	q, err = query.Parse(`.servers[].host`, query.WithMaxSize(32))
+	require.NoError(t, err)
+	_, err = q.Run([]byte(doc))
+	require.EqualError(t, err, "the results of the query exceed 32 bytes")
+
+	q, err = query.Parse(`.servers[0].host`, query.WithMaxSize(32))
+	require.NoError(t, err)
+	out, err = q.Run([]byte(doc))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte(`"a.example.com"`)}, out)
+}
@@ -0,0 +1,291 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Values are decoded as nil, bool, json.Number, string, []interface{} and *object,
+// so that numbers and the order of the fields are preserved.
+
+// object is a JSON object whose fields keep their order.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]interface{})}
+}
+
+func (o *object) get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// set adds or replaces a field, a replaced field keeps its position.
+func (o *object) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *object) copy() *object {
+	c := object{
+		keys:   append([]string(nil), o.keys...),
+		values: make(map[string]interface{}, len(o.values)),
+	}
+	for k, v := range o.values {
+		c.values[k] = v
+	}
+
+	return &c
+}
+
+// decode decodes a JSON value.
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON value")
+	}
+
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err = dec.Token()
+		return list, err
+	case json.Delim('{'):
+		o := newObject()
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.set(k.(string), v)
+		}
+		_, err = dec.Token()
+		return o, err
+	}
+
+	return tok, nil
+}
+
+// encode encodes a value as compact JSON.
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err := encode(buf, e)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case *object:
+		buf.WriteByte('{')
+		for i, k := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err := encode(buf, k)
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			err = encode(buf, v.values[k])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case float64:
+		buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		return nil
+	}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	// remove the new line added by the encoder
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// typeName returns the name of the JSON type of the value.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case *object:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+// toFloat returns the value of a number.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// truthy returns false for false and null, true otherwise.
+func truthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return v != nil && (!ok || b)
+}
+
+// typeRank orders the types as jq does: null < false < true < numbers < strings < arrays < objects.
+func typeRank(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case json.Number, float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+
+	return 6
+}
+
+// compare returns -1, 0 or 1 if a is lower than, equal to or greater than b.
+func compare(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return sign(ra - rb)
+	}
+
+	switch a := a.(type) {
+	case json.Number, float64:
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		switch bs := b.(string); {
+		case a < bs:
+			return -1
+		case a > bs:
+			return 1
+		}
+		return 0
+	case []interface{}:
+		bl := b.([]interface{})
+		for i := 0; i < len(a) && i < len(bl); i++ {
+			if c := compare(a[i], bl[i]); c != 0 {
+				return c
+			}
+		}
+		return sign(len(a) - len(bl))
+	case *object:
+		bo := b.(*object)
+		ka, kb := sortedKeys(a), sortedKeys(bo)
+		if c := compare(stringList(ka), stringList(kb)); c != 0 {
+			return c
+		}
+		for _, k := range ka {
+			if c := compare(a.values[k], bo.values[k]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return 0
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+
+	return 0
+}
+
+func sortedKeys(o *object) []string {
+	keys := append([]string(nil), o.keys...)
+	sort.Strings(keys)
+	return keys
+}
+
+func stringList(list []string) []interface{} {
+	l := make([]interface{}, len(list))
+	for i, s := range list {
+		l[i] = s
+	}
+
+	return l
+}
@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// backupChunkSize is the maximum size of the chunks sent during a backup.
+const backupChunkSize = 64 * 1024
+
+// A Database is a database file managed by the server.
+// It is implemented by boltdb.Backend and boltdb.Registry.
+type Database interface {
+	// Path of the database file.
+	Path() string
+	// Size of the database file, in bytes.
+	Size() (int64, error)
+	// Backup writes a consistent copy of the database to w.
+	Backup(w io.Writer) (int64, error)
+	// Compact rewrites the database file to reclaim the unused space.
+	Compact() error
+}
+
+// Admin is the Brazier administration gRPC service.
+type Admin struct {
+	Store *store.Store
+
+	// Databases managed by the server, indexed by name.
+	Databases map[string]Database
+
+	// Listeners addresses, indexed by name.
+	Listeners map[string]string
+
+	// StartTime is used to compute the uptime.
+	StartTime time.Time
+
+	// TempDir is the directory of the copies made during backups,
+	// the default directory for temporary files if empty.
+	TempDir string
+}
+
+// Info returns the version of the server, its uptime and its listeners.
+func (a *Admin) Info(ctx context.Context, in *proto.Empty) (*proto.ServerInfo, error) {
+	info := proto.ServerInfo{
+		Version:   brazier.Version,
+		Uptime:    int64(time.Since(a.StartTime) / time.Second),
+		StartTime: a.StartTime.Unix(),
+	}
+
+	for _, name := range sortedKeys(a.Listeners) {
+		info.Listeners = append(info.Listeners, &proto.Listener{
+			Name:    name,
+			Address: a.Listeners[name],
+		})
+	}
+
+	return &info, nil
+}
+
+// Stats returns the number of buckets and items and the size of the databases.
+func (a *Admin) Stats(ctx context.Context, in *proto.Empty) (*proto.StoreStats, error) {
+	buckets, items, err := a.Store.Count(ctx)
+	if err != nil {
+		return nil, newError(err, "/")
+	}
+
+	stats := proto.StoreStats{
+		Buckets: int64(buckets),
+		Items:   int64(items),
+	}
+
+	for _, name := range a.databaseNames() {
+		db := a.Databases[name]
+		size, err := db.Size()
+		if err != nil {
+			return nil, newError(err, "")
+		}
+
+		stats.Databases = append(stats.Databases, &proto.Database{
+			Name: name,
+			Path: db.Path(),
+			Size: size,
+		})
+	}
+
+	return &stats, nil
+}
+
+// Compact compacts all the databases and returns their sizes before and after.
+func (a *Admin) Compact(ctx context.Context, in *proto.Empty) (*proto.Compaction, error) {
+	var c proto.Compaction
+
+	for _, name := range a.databaseNames() {
+		db := a.Databases[name]
+		before, err := db.Size()
+		if err != nil {
+			return nil, newError(err, "")
+		}
+
+		err = db.Compact()
+		if err != nil {
+			return nil, newError(err, "")
+		}
+
+		after, err := db.Size()
+		if err != nil {
+			return nil, newError(err, "")
+		}
+
+		c.Databases = append(c.Databases, &proto.CompactedDatabase{
+			Name:       name,
+			SizeBefore: before,
+			SizeAfter:  after,
+		})
+	}
+
+	return &c, nil
+}
+
+// Backup streams a consistent copy of the selected database.
+// The copy is written to a temporary file before being streamed, so that the database
+// isn't held by a client slowly receiving it.
+func (a *Admin) Backup(in *proto.BackupRequest, stream proto.Admin_BackupServer) error {
+	db, ok := a.Databases[in.Database]
+	if !ok {
+		return grpc.Errorf(codes.NotFound, "unknown database %q", in.Database)
+	}
+
+	f, err := ioutil.TempFile(a.TempDir, "brazier-backup-")
+	if err != nil {
+		return newError(err, "")
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = db.Backup(f)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return newError(err, "")
+	}
+
+	_, err = io.CopyBuffer(&chunkWriter{stream: stream}, f, make([]byte, backupChunkSize))
+	if err != nil {
+		return newError(err, "")
+	}
+
+	return nil
+}
+
+func (a *Admin) databaseNames() []string {
+	names := make([]string, 0, len(a.Databases))
+	for name := range a.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// chunkWriter sends the written data in chunks.
+type chunkWriter struct {
+	stream proto.Admin_BackupServer
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	var n int
+
+	for len(p) > 0 {
+		size := len(p)
+		if size > backupChunkSize {
+			size = backupChunkSize
+		}
+
+		err := w.stream.Send(&proto.Chunk{Data: p[:size]})
+		if err != nil {
+			return n, err
+		}
+
+		n += size
+		p = p[size:]
+	}
+
+	return n, nil
+}
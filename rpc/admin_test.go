@@ -0,0 +1,191 @@
+package rpc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier"
+	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/rpc"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+type database struct {
+	path      string
+	data      []byte
+	compacted bool
+	err       error
+	// file the last backup was written to
+	backupFile string
+}
+
+func (d *database) Path() string {
+	return d.path
+}
+
+func (d *database) Size() (int64, error) {
+	return int64(len(d.data)), d.err
+}
+
+func (d *database) Backup(w io.Writer) (int64, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if f, ok := w.(*os.File); ok {
+		d.backupFile = f.Name()
+	}
+
+	n, err := w.Write(d.data)
+	return int64(n), err
+}
+
+func (d *database) Compact() error {
+	if d.err != nil {
+		return d.err
+	}
+
+	d.compacted = true
+	d.data = d.data[:len(d.data)/2]
+	return nil
+}
+
+func TestAdmin(t *testing.T) {
+	s := store.NewStore(mock.NewRegistry(mock.NewBackend()))
+	_, err := s.Put("a/b/key", []byte(`"data"`))
+	require.NoError(t, err)
+	_, err = s.Put("a/key", []byte(`"data"`))
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	registry := database{path: "/data/registry.db", data: make([]byte, 100)}
+	backend := database{path: "/data/backend.db", data: bytes.Repeat([]byte("a"), 200*1024)}
+	admin := rpc.Admin{
+		Store: s,
+		Databases: map[string]rpc.Database{
+			"registry": &registry,
+			"backend":  &backend,
+		},
+		Listeners: map[string]string{
+			"rpc":  "127.0.0.1:5657",
+			"http": "127.0.0.1:5656",
+		},
+		StartTime: time.Now().Add(-time.Minute),
+		TempDir:   dir,
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(s, rpc.WithAdmin(&admin))
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := proto.NewAdminClient(conn)
+
+	t.Run("Info", func(t *testing.T) {
+		info, err := c.Info(context.Background(), new(proto.Empty))
+		require.NoError(t, err)
+		require.Equal(t, brazier.Version, info.Version)
+		require.True(t, info.Uptime >= 60)
+		require.Equal(t, admin.StartTime.Unix(), info.StartTime)
+		require.Len(t, info.Listeners, 2)
+		require.Equal(t, "http", info.Listeners[0].Name)
+		require.Equal(t, "127.0.0.1:5656", info.Listeners[0].Address)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		stats, err := c.Stats(context.Background(), new(proto.Empty))
+		require.NoError(t, err)
+		require.Equal(t, int64(2), stats.Buckets)
+		require.Equal(t, int64(2), stats.Items)
+		require.Len(t, stats.Databases, 2)
+		require.Equal(t, "backend", stats.Databases[0].Name)
+		require.Equal(t, "/data/backend.db", stats.Databases[0].Path)
+		require.Equal(t, int64(200*1024), stats.Databases[0].Size)
+	})
+
+	t.Run("Backup", func(t *testing.T) {
+		stream, err := c.Backup(context.Background(), &proto.BackupRequest{Database: "backend"})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		var chunks int
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			buf.Write(chunk.Data)
+			chunks++
+		}
+		require.Equal(t, backend.data, buf.Bytes())
+		require.True(t, chunks > 1)
+
+		// the backup is written to a temporary file, removed once it is sent
+		require.Equal(t, dir, filepath.Dir(backend.backupFile))
+		_, err = os.Stat(backend.backupFile)
+		require.True(t, os.IsNotExist(err))
+
+		stream, err = c.Backup(context.Background(), &proto.BackupRequest{Database: "unknown"})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.NotFound, grpc.Code(err))
+		require.Contains(t, rpc.StoreError(err).Error(), `unknown database "unknown"`)
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		res, err := c.Compact(context.Background(), new(proto.Empty))
+		require.NoError(t, err)
+		require.Len(t, res.Databases, 2)
+		require.Equal(t, "backend", res.Databases[0].Name)
+		require.Equal(t, int64(200*1024), res.Databases[0].SizeBefore)
+		require.Equal(t, int64(100*1024), res.Databases[0].SizeAfter)
+		require.True(t, registry.compacted)
+		require.True(t, backend.compacted)
+
+		registry.err = errors.New("failure")
+		_, err = c.Compact(context.Background(), new(proto.Empty))
+		require.Equal(t, codes.Internal, grpc.Code(err))
+	})
+
+	t.Run("Reflection", func(t *testing.T) {
+		stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		require.NoError(t, err)
+		err = stream.Send(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+		})
+		require.NoError(t, err)
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		stream.CloseSend()
+
+		var services []string
+		for _, s := range resp.GetListServicesResponse().Service {
+			services = append(services, s.Name)
+		}
+		require.Contains(t, services, "proto.Bucket")
+		require.Contains(t, services, "proto.Admin")
+	})
+}
@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// TokenMetadataKey is the metadata key used by clients to send their token.
+const TokenMetadataKey = "authorization"
+
+// healthPrefix is the prefix of the health service methods, which don't require authentication.
+const healthPrefix = "/grpc.health.v1.Health/"
+
+// WithToken requires the clients to send the given token in the authorization metadata,
+// as "Bearer TOKEN". Calls to the health service don't require it.
+// An empty token disables the authentication.
+func WithToken(token string) ServerOption {
+	return func(c *serverConfig) {
+		c.token = token
+	}
+}
+
+// WithInterceptors adds interceptors to the server. Interceptors are called in the order
+// they were added, after the authentication. Both interceptors can be nil.
+func WithInterceptors(unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) ServerOption {
+	return func(c *serverConfig) {
+		if unary != nil {
+			c.unary = append(c.unary, unary)
+		}
+
+		if stream != nil {
+			c.stream = append(c.stream, stream)
+		}
+	}
+}
+
+// interceptors returns the gRPC options chaining the authentication and the configured interceptors.
+func (c *serverConfig) interceptors() []grpc.ServerOption {
+	unary, stream := c.unary, c.stream
+	if c.token != "" {
+		a := tokenAuth{token: "Bearer " + c.token}
+		unary = append([]grpc.UnaryServerInterceptor{a.unary}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{a.stream}, stream...)
+	}
+
+	var opts []grpc.ServerOption
+	if len(unary) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(chainUnary(unary)))
+	}
+	if len(stream) > 0 {
+		opts = append(opts, grpc.StreamInterceptor(chainStream(stream)))
+	}
+
+	return opts
+}
+
+type tokenAuth struct {
+	token string
+}
+
+func (a *tokenAuth) check(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, healthPrefix) {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md[TokenMetadataKey] {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(a.token)) == 1 {
+			return nil
+		}
+	}
+
+	return grpc.Errorf(codes.Unauthenticated, "invalid or missing token")
+}
+
+func (a *tokenAuth) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	err := a.check(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *tokenAuth) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := a.check(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// chainUnary combines the interceptors into one, the first one being the outermost.
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, h := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, h)
+			}
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// chainStream combines the interceptors into one, the first one being the outermost.
+func chainStream(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, h := interceptors[i], next
+			next = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, h)
+			}
+		}
+
+		return next(srv, ss)
+	}
+}
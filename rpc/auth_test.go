@@ -0,0 +1,74 @@
+package rpc_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier/mock"
+	"github.com/asdine/brazier/rpc"
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestToken(t *testing.T) {
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(
+		store.NewStore(mock.NewRegistry(mock.NewBackend())),
+		rpc.WithToken("secret"),
+		rpc.WithInterceptors(interceptor("first"), nil),
+		rpc.WithInterceptors(interceptor("second"), nil),
+	)
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := proto.NewBucketClient(conn)
+
+	_, err = c.Create(context.Background(), &proto.Selector{Path: "a/"})
+	require.Equal(t, codes.Unauthenticated, grpc.Code(err))
+	require.Empty(t, calls)
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("authorization", "Bearer wrong"))
+	_, err = c.Create(ctx, &proto.Selector{Path: "a/"})
+	require.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	ctx = metadata.NewOutgoingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	_, err = c.Create(ctx, &proto.Selector{Path: "a/"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, calls)
+
+	stream, err := c.StreamList(context.Background(), &proto.Selector{Path: "a/"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	stream, err = c.StreamList(ctx, &proto.Selector{Path: "a/"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Error(t, err)
+	require.NotEqual(t, codes.Unauthenticated, grpc.Code(err))
+
+	// health checks don't require the token
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"github.com/asdine/brazier/rpc/proto"
+	"github.com/asdine/brazier/store"
+)
+
+// bulkSummary converts the result of a bulk write to a BulkSummary message.
+func bulkSummary(r *store.BulkResult) *proto.BulkSummary {
+	s := proto.BulkSummary{
+		Received: int64(r.Received),
+		Saved:    int64(r.Saved),
+	}
+
+	for _, f := range r.Failures {
+		_, reason := errorReason(f.Err)
+		s.Failures = append(s.Failures, &proto.BulkFailure{
+			Path:    f.Path,
+			Message: f.Err.Error(),
+			Reason:  reason,
+		})
+	}
+
+	return &s
+}
+
+// BulkResult converts a BulkSummary returned by the BulkPut rpc to a store.BulkResult.
+// Failures are translated to store errors the same way as StoreError.
+func BulkResult(s *proto.BulkSummary) *store.BulkResult {
+	r := store.BulkResult{
+		Received: int(s.Received),
+		Saved:    int(s.Saved),
+	}
+
+	for _, f := range s.Failures {
+		r.Failures = append(r.Failures, store.BulkFailure{
+			Path: f.Path,
+			Err:  reasonError(f.Reason, f.Message),
+		})
+	}
+
+	return &r
+}
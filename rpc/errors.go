@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/asdine/brazier/store"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies the errors returned by the Brazier services
+// in the error details.
+const errorDomain = "brazier"
+
+// Reasons attached to the error details, one per store error.
+const (
+	reasonNotFound      = "NOT_FOUND"
+	reasonAlreadyExists = "ALREADY_EXISTS"
+	reasonForbidden     = "FORBIDDEN"
+	reasonIsBucket      = "IS_BUCKET"
+	reasonConflict      = "CONFLICT"
+	reasonInternal      = "INTERNAL"
+)
+
+var storeErrors = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{store.ErrNotFound, codes.NotFound, reasonNotFound},
+	{store.ErrAlreadyExists, codes.AlreadyExists, reasonAlreadyExists},
+	{store.ErrForbidden, codes.PermissionDenied, reasonForbidden},
+	{store.ErrIsBucket, codes.FailedPrecondition, reasonIsBucket},
+	{store.ErrConflict, codes.FailedPrecondition, reasonConflict},
+}
+
+// errorReason returns the status code and the reason matching the store error.
+func errorReason(err error) (codes.Code, string) {
+	for _, e := range storeErrors {
+		if err == e.err {
+			return e.code, e.reason
+		}
+	}
+
+	return codes.Internal, reasonInternal
+}
+
+// reasonError returns the store error matching the reason,
+// or a plain error carrying the message if there is none.
+func reasonError(reason, message string) error {
+	for _, e := range storeErrors {
+		if reason == e.reason {
+			return e.err
+		}
+	}
+
+	return errors.New(message)
+}
+
+// newError converts a store error to a gRPC status error.
+// The reason and the path are attached as error details.
+// Context errors are converted to the matching status codes, without details.
+func newError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err {
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	code, reason := errorReason(err)
+	st := status.New(code, err.Error())
+	detailed, derr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+		Metadata: map[string]string{
+			"path": path,
+		},
+	})
+	if derr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// StoreError converts an error returned by a Brazier gRPC service
+// to the matching store error, using the reason attached to the error details.
+// Errors without details aren't store errors, whatever their status code:
+// internal errors are returned as plain errors carrying the status message,
+// other errors are returned unchanged.
+func StoreError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errorDomain {
+			continue
+		}
+
+		return reasonError(info.Reason, st.Message())
+	}
+
+	if st.Code() == codes.Internal {
+		return errors.New(st.Message())
+	}
+
+	return err
+}
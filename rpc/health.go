@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/asdine/brazier/store"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// bucketService is the name of the Bucket service, as reported by the health service.
+const bucketService = "proto.Bucket"
+
+// healthCheckInterval is the interval between two checks of the store.
+const healthCheckInterval = time.Second
+
+func newHealthServer(s *store.Store) *healthServer {
+	h := healthServer{
+		Server: health.NewServer(),
+		store:  s,
+		quit:   make(chan struct{}),
+	}
+	h.update()
+	go h.watchStore()
+	return &h
+}
+
+// healthServer implements the standard gRPC health service.
+// It reports NOT_SERVING until the server is ready, and when the store
+// is unable to serve requests. The store is checked periodically and on every
+// Check call, and the status is always set on the underlying health.Server
+// so that Check and Watch report the same status.
+type healthServer struct {
+	*health.Server
+	store *store.Store
+	quit  chan struct{}
+
+	mu    sync.Mutex
+	ready bool
+}
+
+func (h *healthServer) Check(ctx context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.update()
+	return h.Server.Check(ctx, in)
+}
+
+func (h *healthServer) setReady(ready bool) {
+	h.mu.Lock()
+	h.ready = ready
+	h.mu.Unlock()
+
+	h.update()
+}
+
+// update sets the status of the services from the readiness of the server
+// and the health of the store.
+func (h *healthServer) update() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if h.ready && h.store.Check() == nil {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	h.SetServingStatus("", status)
+	h.SetServingStatus(bucketService, status)
+}
+
+func (h *healthServer) watchStore() {
+	t := time.NewTicker(healthCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			h.update()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// stop stops checking the store and sets all the services as NOT_SERVING.
+func (h *healthServer) stop() {
+	close(h.quit)
+	h.Shutdown()
+}
@@ -0,0 +1,489 @@
+// Code generated by protoc-gen-go.
+// source: admin.proto
+// DO NOT EDIT!
+
+package proto
+
+import proto1 "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto1.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// Server informations.
+type ServerInfo struct {
+	Version string `protobuf:"bytes,1,opt,name=version" json:"version,omitempty"`
+	// Uptime in seconds.
+	Uptime int64 `protobuf:"varint,2,opt,name=uptime" json:"uptime,omitempty"`
+	// Start time as a unix timestamp.
+	StartTime int64       `protobuf:"varint,3,opt,name=startTime" json:"startTime,omitempty"`
+	Listeners []*Listener `protobuf:"bytes,4,rep,name=listeners" json:"listeners,omitempty"`
+}
+
+func (m *ServerInfo) Reset()                    { *m = ServerInfo{} }
+func (m *ServerInfo) String() string            { return proto1.CompactTextString(m) }
+func (*ServerInfo) ProtoMessage()               {}
+func (*ServerInfo) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
+
+func (m *ServerInfo) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *ServerInfo) GetUptime() int64 {
+	if m != nil {
+		return m.Uptime
+	}
+	return 0
+}
+
+func (m *ServerInfo) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *ServerInfo) GetListeners() []*Listener {
+	if m != nil {
+		return m.Listeners
+	}
+	return nil
+}
+
+// Address of a listener.
+type Listener struct {
+	Name    string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Address string `protobuf:"bytes,2,opt,name=address" json:"address,omitempty"`
+}
+
+func (m *Listener) Reset()                    { *m = Listener{} }
+func (m *Listener) String() string            { return proto1.CompactTextString(m) }
+func (*Listener) ProtoMessage()               {}
+func (*Listener) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{1} }
+
+func (m *Listener) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Listener) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+// Statistics of the store.
+type StoreStats struct {
+	Buckets   int64       `protobuf:"varint,1,opt,name=buckets" json:"buckets,omitempty"`
+	Items     int64       `protobuf:"varint,2,opt,name=items" json:"items,omitempty"`
+	Databases []*Database `protobuf:"bytes,3,rep,name=databases" json:"databases,omitempty"`
+}
+
+func (m *StoreStats) Reset()                    { *m = StoreStats{} }
+func (m *StoreStats) String() string            { return proto1.CompactTextString(m) }
+func (*StoreStats) ProtoMessage()               {}
+func (*StoreStats) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{2} }
+
+func (m *StoreStats) GetBuckets() int64 {
+	if m != nil {
+		return m.Buckets
+	}
+	return 0
+}
+
+func (m *StoreStats) GetItems() int64 {
+	if m != nil {
+		return m.Items
+	}
+	return 0
+}
+
+func (m *StoreStats) GetDatabases() []*Database {
+	if m != nil {
+		return m.Databases
+	}
+	return nil
+}
+
+// Database file informations.
+type Database struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Path string `protobuf:"bytes,2,opt,name=path" json:"path,omitempty"`
+	// Size in bytes.
+	Size int64 `protobuf:"varint,3,opt,name=size" json:"size,omitempty"`
+}
+
+func (m *Database) Reset()                    { *m = Database{} }
+func (m *Database) String() string            { return proto1.CompactTextString(m) }
+func (*Database) ProtoMessage()               {}
+func (*Database) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{3} }
+
+func (m *Database) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Database) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Database) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+// Result of a compaction.
+type Compaction struct {
+	Databases []*CompactedDatabase `protobuf:"bytes,1,rep,name=databases" json:"databases,omitempty"`
+}
+
+func (m *Compaction) Reset()                    { *m = Compaction{} }
+func (m *Compaction) String() string            { return proto1.CompactTextString(m) }
+func (*Compaction) ProtoMessage()               {}
+func (*Compaction) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{4} }
+
+func (m *Compaction) GetDatabases() []*CompactedDatabase {
+	if m != nil {
+		return m.Databases
+	}
+	return nil
+}
+
+// Database sizes before and after a compaction.
+type CompactedDatabase struct {
+	Name       string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	SizeBefore int64  `protobuf:"varint,2,opt,name=sizeBefore" json:"sizeBefore,omitempty"`
+	SizeAfter  int64  `protobuf:"varint,3,opt,name=sizeAfter" json:"sizeAfter,omitempty"`
+}
+
+func (m *CompactedDatabase) Reset()                    { *m = CompactedDatabase{} }
+func (m *CompactedDatabase) String() string            { return proto1.CompactTextString(m) }
+func (*CompactedDatabase) ProtoMessage()               {}
+func (*CompactedDatabase) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{5} }
+
+func (m *CompactedDatabase) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CompactedDatabase) GetSizeBefore() int64 {
+	if m != nil {
+		return m.SizeBefore
+	}
+	return 0
+}
+
+func (m *CompactedDatabase) GetSizeAfter() int64 {
+	if m != nil {
+		return m.SizeAfter
+	}
+	return 0
+}
+
+// The name of the database to backup.
+type BackupRequest struct {
+	Database string `protobuf:"bytes,1,opt,name=database" json:"database,omitempty"`
+}
+
+func (m *BackupRequest) Reset()                    { *m = BackupRequest{} }
+func (m *BackupRequest) String() string            { return proto1.CompactTextString(m) }
+func (*BackupRequest) ProtoMessage()               {}
+func (*BackupRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{6} }
+
+func (m *BackupRequest) GetDatabase() string {
+	if m != nil {
+		return m.Database
+	}
+	return ""
+}
+
+// Chunk of data.
+type Chunk struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Chunk) Reset()                    { *m = Chunk{} }
+func (m *Chunk) String() string            { return proto1.CompactTextString(m) }
+func (*Chunk) ProtoMessage()               {}
+func (*Chunk) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{7} }
+
+func (m *Chunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func init() {
+	proto1.RegisterType((*ServerInfo)(nil), "proto.ServerInfo")
+	proto1.RegisterType((*Listener)(nil), "proto.Listener")
+	proto1.RegisterType((*StoreStats)(nil), "proto.StoreStats")
+	proto1.RegisterType((*Database)(nil), "proto.Database")
+	proto1.RegisterType((*Compaction)(nil), "proto.Compaction")
+	proto1.RegisterType((*CompactedDatabase)(nil), "proto.CompactedDatabase")
+	proto1.RegisterType((*BackupRequest)(nil), "proto.BackupRequest")
+	proto1.RegisterType((*Chunk)(nil), "proto.Chunk")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Admin service
+
+type AdminClient interface {
+	// Get informations about the server
+	Info(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ServerInfo, error)
+	// Get statistics about the store
+	Stats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoreStats, error)
+	// Compact the databases
+	Compact(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Compaction, error)
+	// Stream a consistent copy of a database
+	Backup(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (Admin_BackupClient, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Info(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := grpc.Invoke(ctx, "/proto.Admin/Info", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Stats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoreStats, error) {
+	out := new(StoreStats)
+	err := grpc.Invoke(ctx, "/proto.Admin/Stats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Compact(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Compaction, error) {
+	out := new(Compaction)
+	err := grpc.Invoke(ctx, "/proto.Admin/Compact", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Backup(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (Admin_BackupClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Admin_serviceDesc.Streams[0], c.cc, "/proto.Admin/Backup", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminBackupClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Admin_BackupClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type adminBackupClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminBackupClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Admin service
+
+type AdminServer interface {
+	// Get informations about the server
+	Info(context.Context, *Empty) (*ServerInfo, error)
+	// Get statistics about the store
+	Stats(context.Context, *Empty) (*StoreStats, error)
+	// Compact the databases
+	Compact(context.Context, *Empty) (*Compaction, error)
+	// Stream a consistent copy of a database
+	Backup(*BackupRequest, Admin_BackupServer) error
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Admin/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Info(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Admin/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Stats(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Compact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Compact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Admin/Compact",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Compact(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Backup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BackupRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminServer).Backup(m, &adminBackupServer{stream})
+}
+
+type Admin_BackupServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type adminBackupServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminBackupServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Info",
+			Handler:    _Admin_Info_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Admin_Stats_Handler,
+		},
+		{
+			MethodName: "Compact",
+			Handler:    _Admin_Compact_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Backup",
+			Handler:       _Admin_Backup_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "admin.proto",
+}
+
+func init() { proto1.RegisterFile("admin.proto", fileDescriptor2) }
+
+var fileDescriptor2 = []byte{
+	// 427 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x92, 0xdb, 0x8a, 0xdb, 0x30,
+	0x10, 0x86, 0xed, 0xda, 0xce, 0xc6, 0x93, 0x2d, 0x65, 0xc5, 0x52, 0x8c, 0x5b, 0x4a, 0xd0, 0x55,
+	0x7a, 0x5a, 0x96, 0x2d, 0x94, 0xde, 0xee, 0xa1, 0x85, 0x42, 0xaf, 0x94, 0xbe, 0x80, 0x12, 0x4f,
+	0x58, 0xe3, 0xfa, 0x50, 0x69, 0xbc, 0xb0, 0xfb, 0x0a, 0x7d, 0xa7, 0x3e, 0x5b, 0x91, 0x2c, 0xc5,
+	0x09, 0x4d, 0xae, 0xa2, 0xff, 0x9f, 0x3f, 0xd6, 0x37, 0x33, 0x82, 0x99, 0x2c, 0xea, 0xb2, 0xb9,
+	0xe8, 0x54, 0x4b, 0x2d, 0x4b, 0xec, 0x4f, 0x3e, 0xa3, 0xc7, 0x0e, 0xf5, 0xe0, 0xf1, 0x3f, 0x21,
+	0xc0, 0x12, 0xd5, 0x03, 0xaa, 0xef, 0xcd, 0xa6, 0x65, 0x19, 0x9c, 0x3c, 0xa0, 0xd2, 0x65, 0xdb,
+	0x64, 0xe1, 0x3c, 0x5c, 0xa4, 0xc2, 0x4b, 0xf6, 0x12, 0x26, 0x7d, 0x47, 0x65, 0x8d, 0xd9, 0xb3,
+	0x79, 0xb8, 0x88, 0x84, 0x53, 0xec, 0x35, 0xa4, 0x9a, 0xa4, 0xa2, 0x9f, 0xa6, 0x14, 0xd9, 0xd2,
+	0x68, 0xb0, 0x8f, 0x90, 0xfe, 0x2a, 0x35, 0x61, 0x83, 0x4a, 0x67, 0xf1, 0x3c, 0x5a, 0xcc, 0xae,
+	0x5e, 0x0c, 0x37, 0x5f, 0xfc, 0x70, 0xbe, 0x18, 0x13, 0xfc, 0x0b, 0x4c, 0xbd, 0xcd, 0x18, 0xc4,
+	0x8d, 0xac, 0xd1, 0x71, 0xd8, 0xb3, 0xc1, 0x93, 0x45, 0xa1, 0x50, 0x6b, 0x4b, 0x91, 0x0a, 0x2f,
+	0x79, 0x05, 0xb0, 0xa4, 0x56, 0xe1, 0x92, 0x24, 0x69, 0x93, 0x5b, 0xf5, 0xeb, 0x0a, 0x49, 0xdb,
+	0xbf, 0x47, 0xc2, 0x4b, 0x76, 0x0e, 0x49, 0x49, 0x58, 0x6b, 0xd7, 0xc5, 0x20, 0x0c, 0x66, 0x21,
+	0x49, 0xae, 0xa4, 0x46, 0x9d, 0x45, 0x7b, 0x98, 0x77, 0xce, 0x17, 0x63, 0x82, 0x7f, 0x83, 0xa9,
+	0xb7, 0x0f, 0x62, 0x32, 0x88, 0x3b, 0x49, 0xf7, 0x8e, 0xd1, 0x9e, 0x8d, 0xa7, 0xcb, 0x27, 0x3f,
+	0x22, 0x7b, 0xe6, 0x77, 0x00, 0xb7, 0x6d, 0xdd, 0xc9, 0x35, 0x99, 0x09, 0x7f, 0xde, 0x85, 0x08,
+	0x2d, 0x44, 0xe6, 0x20, 0x5c, 0x0a, 0x8b, 0x43, 0x34, 0x08, 0x67, 0xff, 0xd5, 0x0f, 0x62, 0xbd,
+	0x01, 0x30, 0xd7, 0xde, 0xe0, 0xa6, 0x55, 0x7e, 0x8d, 0x3b, 0x8e, 0x5d, 0x65, 0xf9, 0x84, 0xd7,
+	0x1b, 0x42, 0xb5, 0x5d, 0xa5, 0x37, 0xf8, 0x7b, 0x78, 0x7e, 0x23, 0xd7, 0x55, 0xdf, 0x09, 0xfc,
+	0xdd, 0xa3, 0x26, 0x96, 0xc3, 0xd4, 0x43, 0xb8, 0x6b, 0xb6, 0x9a, 0xbf, 0x82, 0xe4, 0xf6, 0xbe,
+	0x6f, 0x2a, 0xc3, 0x61, 0x4c, 0x1b, 0x38, 0x15, 0xf6, 0x7c, 0xf5, 0x37, 0x84, 0xe4, 0xda, 0xbc,
+	0x4b, 0xf6, 0x16, 0x62, 0xfb, 0xec, 0x4e, 0x5d, 0x9f, 0x5f, 0xeb, 0x8e, 0x1e, 0xf3, 0x33, 0xa7,
+	0xc6, 0x77, 0xc9, 0x03, 0xf6, 0x0e, 0x92, 0x61, 0xb7, 0x47, 0xb2, 0xdb, 0xe5, 0xf3, 0x80, 0x7d,
+	0x80, 0x13, 0x37, 0x91, 0x23, 0xe9, 0x71, 0xea, 0x3c, 0x60, 0x97, 0x30, 0x19, 0x1a, 0x63, 0xe7,
+	0xae, 0xbc, 0xd7, 0x67, 0xee, 0x3f, 0x61, 0x1b, 0xe2, 0xc1, 0x65, 0xb8, 0x9a, 0x58, 0xe3, 0xd3,
+	0xbf, 0x01, 0x00, 0x88, 0xb3, 0x62, 0xd5, 0x5e, 0x03, 0x00, 0x00,
+}
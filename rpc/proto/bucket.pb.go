@@ -8,6 +8,7 @@ Package proto is a generated protocol buffer package.
 It is generated from these files:
 	bucket.proto
 	types.proto
+	admin.proto
 
 It has these top-level messages:
 	Empty
@@ -17,6 +18,16 @@ It has these top-level messages:
 	Item
 	Node
 	Tree
+	BulkSummary
+	BulkFailure
+	ServerInfo
+	Listener
+	StoreStats
+	Database
+	Compaction
+	CompactedDatabase
+	BackupRequest
+	Chunk
 */
 package proto
 
@@ -61,6 +72,12 @@ type BucketClient interface {
 	Get(ctx context.Context, in *Selector, opts ...grpc.CallOption) (*Item, error)
 	// Delete an item
 	Delete(ctx context.Context, in *Selector, opts ...grpc.CallOption) (*Empty, error)
+	// Stream the bucket content, one node at a time
+	StreamList(ctx context.Context, in *Selector, opts ...grpc.CallOption) (Bucket_StreamListClient, error)
+	// Stream the bucket content and the content of all its children, one node at a time
+	StreamTree(ctx context.Context, in *Selector, opts ...grpc.CallOption) (Bucket_StreamTreeClient, error)
+	// Put a stream of items, saved in large batches
+	BulkPut(ctx context.Context, opts ...grpc.CallOption) (Bucket_BulkPutClient, error)
 }
 
 type bucketClient struct {
@@ -116,6 +133,104 @@ func (c *bucketClient) Delete(ctx context.Context, in *Selector, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *bucketClient) StreamList(ctx context.Context, in *Selector, opts ...grpc.CallOption) (Bucket_StreamListClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Bucket_serviceDesc.Streams[0], c.cc, "/proto.Bucket/StreamList", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bucketStreamListClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Bucket_StreamListClient interface {
+	Recv() (*Node, error)
+	grpc.ClientStream
+}
+
+type bucketStreamListClient struct {
+	grpc.ClientStream
+}
+
+func (x *bucketStreamListClient) Recv() (*Node, error) {
+	m := new(Node)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *bucketClient) StreamTree(ctx context.Context, in *Selector, opts ...grpc.CallOption) (Bucket_StreamTreeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Bucket_serviceDesc.Streams[1], c.cc, "/proto.Bucket/StreamTree", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bucketStreamTreeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Bucket_StreamTreeClient interface {
+	Recv() (*Node, error)
+	grpc.ClientStream
+}
+
+type bucketStreamTreeClient struct {
+	grpc.ClientStream
+}
+
+func (x *bucketStreamTreeClient) Recv() (*Node, error) {
+	m := new(Node)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *bucketClient) BulkPut(ctx context.Context, opts ...grpc.CallOption) (Bucket_BulkPutClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Bucket_serviceDesc.Streams[2], c.cc, "/proto.Bucket/BulkPut", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bucketBulkPutClient{stream}
+	return x, nil
+}
+
+type Bucket_BulkPutClient interface {
+	Send(*NewItem) error
+	CloseAndRecv() (*BulkSummary, error)
+	grpc.ClientStream
+}
+
+type bucketBulkPutClient struct {
+	grpc.ClientStream
+}
+
+func (x *bucketBulkPutClient) Send(m *NewItem) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *bucketBulkPutClient) CloseAndRecv() (*BulkSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for Bucket service
 
 type BucketServer interface {
@@ -129,6 +244,12 @@ type BucketServer interface {
 	Get(context.Context, *Selector) (*Item, error)
 	// Delete an item
 	Delete(context.Context, *Selector) (*Empty, error)
+	// Stream the bucket content, one node at a time
+	StreamList(*Selector, Bucket_StreamListServer) error
+	// Stream the bucket content and the content of all its children, one node at a time
+	StreamTree(*Selector, Bucket_StreamTreeServer) error
+	// Put a stream of items, saved in large batches
+	BulkPut(Bucket_BulkPutServer) error
 }
 
 func RegisterBucketServer(s *grpc.Server, srv BucketServer) {
@@ -225,6 +346,74 @@ func _Bucket_Delete_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Bucket_StreamList_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Selector)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BucketServer).StreamList(m, &bucketStreamListServer{stream})
+}
+
+type Bucket_StreamListServer interface {
+	Send(*Node) error
+	grpc.ServerStream
+}
+
+type bucketStreamListServer struct {
+	grpc.ServerStream
+}
+
+func (x *bucketStreamListServer) Send(m *Node) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Bucket_StreamTree_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Selector)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BucketServer).StreamTree(m, &bucketStreamTreeServer{stream})
+}
+
+type Bucket_StreamTreeServer interface {
+	Send(*Node) error
+	grpc.ServerStream
+}
+
+type bucketStreamTreeServer struct {
+	grpc.ServerStream
+}
+
+func (x *bucketStreamTreeServer) Send(m *Node) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Bucket_BulkPut_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BucketServer).BulkPut(&bucketBulkPutServer{stream})
+}
+
+type Bucket_BulkPutServer interface {
+	SendAndClose(*BulkSummary) error
+	Recv() (*NewItem, error)
+	grpc.ServerStream
+}
+
+type bucketBulkPutServer struct {
+	grpc.ServerStream
+}
+
+func (x *bucketBulkPutServer) SendAndClose(m *BulkSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *bucketBulkPutServer) Recv() (*NewItem, error) {
+	m := new(NewItem)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Bucket_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.Bucket",
 	HandlerType: (*BucketServer)(nil),
@@ -250,22 +439,41 @@ var _Bucket_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Bucket_Delete_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamList",
+			Handler:       _Bucket_StreamList_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamTree",
+			Handler:       _Bucket_StreamTree_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "BulkPut",
+			Handler:       _Bucket_BulkPut_Handler,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "bucket.proto",
 }
 
 func init() { proto1.RegisterFile("bucket.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 153 bytes of a gzipped FileDescriptorProto
+	// 201 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x49, 0x2a, 0x4d, 0xce,
 	0x4e, 0x2d, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x05, 0x53, 0x52, 0xdc, 0x25, 0x95,
-	0x05, 0xa9, 0xc5, 0x10, 0x31, 0xa3, 0x2b, 0x8c, 0x5c, 0x6c, 0x4e, 0x60, 0x45, 0x42, 0x9a, 0x5c,
+	0x05, 0xa9, 0xc5, 0x10, 0x31, 0xa3, 0xe7, 0x4c, 0x5c, 0x6c, 0x4e, 0x60, 0x45, 0x42, 0x9a, 0x5c,
 	0x6c, 0xce, 0x45, 0xa9, 0x89, 0x25, 0xa9, 0x42, 0xfc, 0x10, 0x49, 0xbd, 0xe0, 0xd4, 0x9c, 0xd4,
 	0xe4, 0x92, 0xfc, 0x22, 0x29, 0x1e, 0xa8, 0x80, 0x6b, 0x6e, 0x41, 0x49, 0xa5, 0x12, 0x83, 0x90,
 	0x2a, 0x17, 0x73, 0x40, 0x69, 0x89, 0x10, 0x1f, 0x54, 0xd8, 0x2f, 0xb5, 0xdc, 0xb3, 0x24, 0x35,
 	0x17, 0x43, 0x99, 0x1a, 0x17, 0x8b, 0x4f, 0x66, 0x71, 0x09, 0xa6, 0x79, 0xdc, 0x50, 0x81, 0x90,
 	0xa2, 0xd4, 0x54, 0x88, 0x71, 0xee, 0xa9, 0x78, 0x94, 0x81, 0x0c, 0x57, 0x62, 0x00, 0x39, 0xd0,
-	0x25, 0x35, 0x27, 0x95, 0x08, 0x07, 0x26, 0xb1, 0x81, 0xb9, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff,
-	0xff, 0xb5, 0x65, 0xc5, 0xb6, 0x01, 0x01, 0x00, 0x00,
+	0x25, 0x35, 0x27, 0x95, 0x18, 0x07, 0xea, 0x71, 0x71, 0x05, 0x97, 0x14, 0xa5, 0x26, 0xe6, 0xe2,
+	0xb7, 0xdf, 0x2f, 0x3f, 0x25, 0x55, 0x89, 0xc1, 0x80, 0x11, 0xa1, 0x1e, 0xe4, 0x22, 0x22, 0xd4,
+	0x1b, 0x72, 0xb1, 0x3b, 0x95, 0xe6, 0x64, 0x63, 0x0b, 0x04, 0x21, 0x28, 0x1f, 0x24, 0x1f, 0x5c,
+	0x9a, 0x9b, 0x9b, 0x58, 0x54, 0xa9, 0xc4, 0xa0, 0xc1, 0x98, 0xc4, 0x06, 0x16, 0x36, 0x06, 0x0c,
+	0x00, 0x3f, 0x9e, 0xd7, 0xdd, 0x94, 0x01, 0x00, 0x00,
 }
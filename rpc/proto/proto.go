@@ -1,3 +1,3 @@
 package proto
 
-//go:generate protoc --go_out=plugins=grpc:. bucket.proto types.proto
+//go:generate protoc --go_out=plugins=grpc:. bucket.proto types.proto admin.proto
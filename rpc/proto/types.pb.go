@@ -26,6 +26,12 @@ func (*Empty) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
 type Selector struct {
 	Path      string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
 	Recursive bool   `protobuf:"varint,2,opt,name=recursive" json:"recursive,omitempty"`
+	// for non recursive selectors, also returns the buckets directly under the path.
+	Buckets bool `protobuf:"varint,3,opt,name=buckets" json:"buckets,omitempty"`
+	// only returns the buckets, recursively if recursive is set.
+	BucketsOnly bool `protobuf:"varint,4,opt,name=buckets_only,json=bucketsOnly" json:"buckets_only,omitempty"`
+	// for streamed recursive selectors, maximum number of levels sent, 0 for no limit.
+	Depth int32 `protobuf:"varint,5,opt,name=depth" json:"depth,omitempty"`
 }
 
 func (m *Selector) Reset()                    { *m = Selector{} }
@@ -47,6 +53,27 @@ func (m *Selector) GetRecursive() bool {
 	return false
 }
 
+func (m *Selector) GetBuckets() bool {
+	if m != nil {
+		return m.Buckets
+	}
+	return false
+}
+
+func (m *Selector) GetBucketsOnly() bool {
+	if m != nil {
+		return m.BucketsOnly
+	}
+	return false
+}
+
+func (m *Selector) GetDepth() int32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
 // Bucket to be created at the given path.
 type NewBucket struct {
 	Path string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
@@ -68,6 +95,10 @@ func (m *NewBucket) GetPath() string {
 type NewItem struct {
 	Path  string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// only save the item if it was last modified at updated_at.
+	Conditional bool `protobuf:"varint,3,opt,name=conditional" json:"conditional,omitempty"`
+	// unix time in nanoseconds of the expected last modification, 0 if the item must not exist.
+	UpdatedAt int64 `protobuf:"varint,4,opt,name=updated_at,json=updatedAt" json:"updated_at,omitempty"`
 }
 
 func (m *NewItem) Reset()                    { *m = NewItem{} }
@@ -89,10 +120,26 @@ func (m *NewItem) GetValue() []byte {
 	return nil
 }
 
+func (m *NewItem) GetConditional() bool {
+	if m != nil {
+		return m.Conditional
+	}
+	return false
+}
+
+func (m *NewItem) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
 // Item informations.
 type Item struct {
 	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// unix time in nanoseconds of the last modification, 0 if unknown.
+	UpdatedAt int64 `protobuf:"varint,3,opt,name=updated_at,json=updatedAt" json:"updated_at,omitempty"`
 }
 
 func (m *Item) Reset()                    { *m = Item{} }
@@ -114,11 +161,23 @@ func (m *Item) GetValue() []byte {
 	return nil
 }
 
+func (m *Item) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
 // A Node can be either an item or a bucket.
+// The path and the depth are only set when nodes are streamed.
 type Node struct {
 	Key      string  `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
 	Value    []byte  `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
 	Children []*Node `protobuf:"bytes,3,rep,name=children" json:"children,omitempty"`
+	Path     string  `protobuf:"bytes,4,opt,name=path" json:"path,omitempty"`
+	Depth    int32   `protobuf:"varint,5,opt,name=depth" json:"depth,omitempty"`
+	// unix time in nanoseconds of the last modification of an item, 0 if unknown.
+	UpdatedAt int64 `protobuf:"varint,6,opt,name=updated_at,json=updatedAt" json:"updated_at,omitempty"`
 }
 
 func (m *Node) Reset()                    { *m = Node{} }
@@ -147,6 +206,27 @@ func (m *Node) GetChildren() []*Node {
 	return nil
 }
 
+func (m *Node) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Node) GetDepth() int32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *Node) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
 // Tree of Nodes.
 type Tree struct {
 	Children []*Node `protobuf:"bytes,1,rep,name=children" json:"children,omitempty"`
@@ -164,6 +244,73 @@ func (m *Tree) GetChildren() []*Node {
 	return nil
 }
 
+// Summary of a bulk put.
+type BulkSummary struct {
+	Received int64          `protobuf:"varint,1,opt,name=received" json:"received,omitempty"`
+	Saved    int64          `protobuf:"varint,2,opt,name=saved" json:"saved,omitempty"`
+	Failures []*BulkFailure `protobuf:"bytes,3,rep,name=failures" json:"failures,omitempty"`
+}
+
+func (m *BulkSummary) Reset()                    { *m = BulkSummary{} }
+func (m *BulkSummary) String() string            { return proto1.CompactTextString(m) }
+func (*BulkSummary) ProtoMessage()               {}
+func (*BulkSummary) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *BulkSummary) GetReceived() int64 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *BulkSummary) GetSaved() int64 {
+	if m != nil {
+		return m.Saved
+	}
+	return 0
+}
+
+func (m *BulkSummary) GetFailures() []*BulkFailure {
+	if m != nil {
+		return m.Failures
+	}
+	return nil
+}
+
+// Item that couldn't be saved during a bulk put.
+// The reason is the same as the one used in the error details.
+type BulkFailure struct {
+	Path    string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+	Reason  string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *BulkFailure) Reset()                    { *m = BulkFailure{} }
+func (m *BulkFailure) String() string            { return proto1.CompactTextString(m) }
+func (*BulkFailure) ProtoMessage()               {}
+func (*BulkFailure) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{8} }
+
+func (m *BulkFailure) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *BulkFailure) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *BulkFailure) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 func init() {
 	proto1.RegisterType((*Empty)(nil), "proto.Empty")
 	proto1.RegisterType((*Selector)(nil), "proto.Selector")
@@ -172,24 +319,37 @@ func init() {
 	proto1.RegisterType((*Item)(nil), "proto.Item")
 	proto1.RegisterType((*Node)(nil), "proto.Node")
 	proto1.RegisterType((*Tree)(nil), "proto.Tree")
+	proto1.RegisterType((*BulkSummary)(nil), "proto.BulkSummary")
+	proto1.RegisterType((*BulkFailure)(nil), "proto.BulkFailure")
 }
 
 func init() { proto1.RegisterFile("types.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 215 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0xa9, 0x2c, 0x48,
-	0x2d, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x05, 0x53, 0x4a, 0xec, 0x5c, 0xac, 0xae,
-	0xb9, 0x05, 0x25, 0x95, 0x4a, 0x36, 0x5c, 0x1c, 0xc1, 0xa9, 0x39, 0xa9, 0xc9, 0x25, 0xf9, 0x45,
-	0x42, 0x42, 0x5c, 0x2c, 0x05, 0x89, 0x25, 0x19, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0x9c, 0x41, 0x60,
-	0xb6, 0x90, 0x0c, 0x17, 0x67, 0x51, 0x6a, 0x72, 0x69, 0x51, 0x71, 0x66, 0x59, 0xaa, 0x04, 0x93,
-	0x02, 0xa3, 0x06, 0x47, 0x10, 0x42, 0x40, 0x49, 0x9e, 0x8b, 0xd3, 0x2f, 0xb5, 0xdc, 0xa9, 0x34,
-	0x39, 0x3b, 0xb5, 0x04, 0x9b, 0x76, 0x25, 0x63, 0x2e, 0x76, 0xbf, 0xd4, 0x72, 0xcf, 0x92, 0xd4,
-	0x5c, 0xac, 0xa6, 0x8b, 0x70, 0xb1, 0x96, 0x25, 0xe6, 0x94, 0x42, 0x4c, 0xe6, 0x09, 0x82, 0x70,
-	0x94, 0xf4, 0xb8, 0x58, 0xc0, 0x3a, 0x04, 0xb8, 0x98, 0xb3, 0x53, 0x2b, 0xa1, 0x1a, 0x40, 0x4c,
-	0x1c, 0xea, 0xc3, 0xb9, 0x58, 0xfc, 0xf2, 0x53, 0x52, 0x89, 0x55, 0x2f, 0xa4, 0xce, 0xc5, 0x91,
-	0x9c, 0x91, 0x99, 0x93, 0x52, 0x94, 0x9a, 0x27, 0xc1, 0xac, 0xc0, 0xac, 0xc1, 0x6d, 0xc4, 0x0d,
-	0x09, 0x1d, 0x3d, 0x90, 0x31, 0x41, 0x70, 0x49, 0x25, 0x7d, 0x2e, 0x96, 0x90, 0xa2, 0x54, 0x54,
-	0x0d, 0x8c, 0x78, 0x34, 0x24, 0xb1, 0x81, 0x45, 0x8d, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x6c,
-	0x69, 0x3b, 0x88, 0x73, 0x01, 0x00, 0x00,
+	// 398 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x52, 0xd1, 0xce, 0x93, 0x30,
+	0x18, 0x0d, 0x3f, 0x30, 0xe0, 0xe3, 0xbf, 0x30, 0xcd, 0x1f, 0xd3, 0x18, 0x8d, 0xc8, 0x8d, 0x5c,
+	0xcd, 0x44, 0x9f, 0xc0, 0x3f, 0xd1, 0xc4, 0x0b, 0x67, 0xd2, 0x79, 0xbf, 0x74, 0xf0, 0xe9, 0xc8,
+	0x0a, 0x25, 0x6d, 0xd9, 0xc2, 0x53, 0xf8, 0x0a, 0x3e, 0xaa, 0xa1, 0x74, 0xb8, 0xcd, 0x69, 0xbc,
+	0xe2, 0x3b, 0xdf, 0x69, 0xce, 0x39, 0x3d, 0x05, 0x52, 0x33, 0x74, 0xa8, 0x97, 0x9d, 0x92, 0x46,
+	0x92, 0xd0, 0x7e, 0xf2, 0x08, 0xc2, 0x0f, 0x4d, 0x67, 0x86, 0xfc, 0x87, 0x07, 0xf1, 0x1a, 0x05,
+	0x96, 0x46, 0x2a, 0x42, 0x20, 0xe8, 0xb8, 0xd9, 0x51, 0x2f, 0xf3, 0x8a, 0x84, 0xd9, 0x99, 0x3c,
+	0x87, 0x44, 0x61, 0xd9, 0x2b, 0x5d, 0x1f, 0x90, 0xde, 0x65, 0x5e, 0x11, 0xb3, 0xdf, 0x0b, 0x42,
+	0x21, 0xda, 0xf6, 0xe5, 0x1e, 0x8d, 0xa6, 0xbe, 0xe5, 0x4e, 0x90, 0xbc, 0x82, 0x7b, 0x37, 0x6e,
+	0x64, 0x2b, 0x06, 0x1a, 0x58, 0x3a, 0x75, 0xbb, 0x2f, 0xad, 0x18, 0xc8, 0x03, 0x84, 0x15, 0x76,
+	0x66, 0x47, 0xc3, 0xcc, 0x2b, 0x42, 0x36, 0x81, 0xfc, 0x25, 0x24, 0x2b, 0x3c, 0x3e, 0xda, 0x73,
+	0xb7, 0x12, 0xe5, 0x06, 0xa2, 0x15, 0x1e, 0x3f, 0x19, 0x6c, 0x6e, 0x06, 0x7e, 0x80, 0xf0, 0xc0,
+	0x45, 0x3f, 0x85, 0xbd, 0x67, 0x13, 0x20, 0x19, 0xa4, 0xa5, 0x6c, 0xab, 0xda, 0xd4, 0xb2, 0xe5,
+	0xc2, 0x85, 0x3d, 0x5f, 0x91, 0x17, 0x00, 0x7d, 0x57, 0x71, 0x83, 0xd5, 0x86, 0x1b, 0x1b, 0xd7,
+	0x67, 0x89, 0xdb, 0xbc, 0x37, 0xf9, 0x67, 0x08, 0xac, 0xe5, 0x13, 0xf0, 0xf7, 0x38, 0x38, 0xc7,
+	0x71, 0xfc, 0x8b, 0xe1, 0xa5, 0x9c, 0x7f, 0x2d, 0xf7, 0xd3, 0x83, 0x60, 0x25, 0x2b, 0xfc, 0x6f,
+	0xbd, 0xd7, 0x10, 0x97, 0xbb, 0x5a, 0x54, 0x0a, 0x5b, 0xea, 0x67, 0x7e, 0x91, 0xbe, 0x4d, 0xa7,
+	0x27, 0x5d, 0x8e, 0x32, 0x6c, 0x26, 0xe7, 0x4e, 0x82, 0xcb, 0x4e, 0xfe, 0x6c, 0xfa, 0x2a, 0xe2,
+	0xe2, 0x3a, 0xe2, 0x1b, 0x08, 0xbe, 0x2a, 0xbc, 0x74, 0xf6, 0xfe, 0xe1, 0x9c, 0x4b, 0x48, 0x1f,
+	0x7b, 0xb1, 0x5f, 0xf7, 0x4d, 0xc3, 0xd5, 0x40, 0x9e, 0x41, 0xac, 0xb0, 0xc4, 0xfa, 0x80, 0x95,
+	0xbd, 0x9e, 0xcf, 0x66, 0x3c, 0x06, 0xd2, 0x7c, 0x24, 0xee, 0x2c, 0x31, 0x01, 0xb2, 0x84, 0xf8,
+	0x1b, 0xaf, 0x45, 0xaf, 0x50, 0xbb, 0x3b, 0x12, 0xe7, 0x34, 0xea, 0x7e, 0x9c, 0x28, 0x36, 0x9f,
+	0xc9, 0xd7, 0x90, 0x9e, 0x11, 0x37, 0xff, 0x06, 0x0a, 0x51, 0x83, 0x5a, 0xf3, 0xef, 0x53, 0x9d,
+	0x09, 0x3b, 0x41, 0xf2, 0x14, 0x16, 0x0a, 0xb9, 0x96, 0xad, 0x7d, 0x9c, 0x84, 0x39, 0xb4, 0x5d,
+	0x58, 0xc7, 0x77, 0xbf, 0x06, 0x00, 0x02, 0x7b, 0xe3, 0xda, 0x37, 0x03, 0x00, 0x00,
 }
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"io"
 	"net"
 	"time"
 
@@ -10,18 +11,65 @@ import (
 	"github.com/asdine/brazier/store"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
-// NewServer returns a configured gRPC server
-func NewServer(s *store.Store) brazier.Server {
-	g := grpc.NewServer()
+// NewServer returns a configured gRPC server.
+// The standard gRPC health service is registered, it reports NOT_SERVING
+// until the returned server is marked as ready using the brazier.Readiness interface.
+// gRPC server reflection is registered as well.
+func NewServer(s *store.Store, opts ...ServerOption) brazier.Server {
+	var cfg serverConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	g := grpc.NewServer(append(cfg.grpcOpts, cfg.interceptors()...)...)
 	srv := Server{Store: s}
 	proto.RegisterBucketServer(g, &srv)
-	return &serverWrapper{srv: g}
+	if cfg.admin != nil {
+		proto.RegisterAdminServer(g, cfg.admin)
+	}
+	h := newHealthServer(s)
+	healthpb.RegisterHealthServer(g, h)
+	reflection.Register(g)
+	return &serverWrapper{srv: g, health: h}
+}
+
+// A ServerOption configures the gRPC server.
+type ServerOption func(*serverConfig)
+
+// WithGRPCOptions passes options to the underlying gRPC server,
+// e.g. grpc.Creds to enable TLS.
+func WithGRPCOptions(opts ...grpc.ServerOption) ServerOption {
+	return func(c *serverConfig) {
+		c.grpcOpts = append(c.grpcOpts, opts...)
+	}
+}
+
+// WithAdmin registers the Admin service.
+func WithAdmin(a *Admin) ServerOption {
+	return func(c *serverConfig) {
+		c.admin = a
+	}
+}
+
+type serverConfig struct {
+	grpcOpts []grpc.ServerOption
+	admin    *Admin
+	token    string
+	unary    []grpc.UnaryServerInterceptor
+	stream   []grpc.StreamServerInterceptor
 }
 
 type serverWrapper struct {
-	srv *grpc.Server
+	srv    *grpc.Server
+	health *healthServer
+}
+
+func (s *serverWrapper) SetReady(ready bool) {
+	s.health.setReady(ready)
 }
 
 func (s *serverWrapper) Serve(l net.Listener) error {
@@ -29,6 +77,7 @@ func (s *serverWrapper) Serve(l net.Listener) error {
 }
 
 func (s *serverWrapper) Stop(time.Duration) {
+	s.health.stop()
 	s.srv.GracefulStop()
 }
 
@@ -39,21 +88,27 @@ type Server struct {
 
 // Create a bucket.
 func (s *Server) Create(ctx context.Context, in *proto.Selector) (*proto.Empty, error) {
-	err := s.Store.CreateBucket(in.Path)
+	err := s.Store.CreateBucketContext(ctx, in.Path)
 	if err != nil {
-		return nil, err
+		return nil, newError(err, in.Path)
 	}
 
 	return &proto.Empty{}, nil
 }
 
-// Put an item in the bucket.
+// Put an item in the bucket. Conditional puts fail with store.ErrConflict if the item
+// was modified since the given time.
 func (s *Server) Put(ctx context.Context, in *proto.NewItem) (*proto.Empty, error) {
 	data := json.ToValidJSON(in.Value)
 
-	_, err := s.Store.Put(in.Path, data)
+	var err error
+	if in.Conditional {
+		_, err = s.Store.PutIfContext(ctx, in.Path, data, unixTime(in.UpdatedAt))
+	} else {
+		_, err = s.Store.PutContext(ctx, in.Path, data)
+	}
 	if err != nil {
-		return nil, err
+		return nil, newError(err, in.Path)
 	}
 
 	return &proto.Empty{}, nil
@@ -61,14 +116,15 @@ func (s *Server) Put(ctx context.Context, in *proto.NewItem) (*proto.Empty, erro
 
 // Get an item from the bucket.
 func (s *Server) Get(ctx context.Context, in *proto.Selector) (*proto.Item, error) {
-	item, err := s.Store.Get(in.Path)
+	item, err := s.Store.GetContext(ctx, in.Path)
 	if err != nil {
-		return nil, err
+		return nil, newError(err, in.Path)
 	}
 
 	r := proto.Item{
-		Key:   item.Key,
-		Value: item.Data,
+		Key:       item.Key,
+		Value:     item.Data,
+		UpdatedAt: unixNano(item.UpdatedAt),
 	}
 
 	return &r, nil
@@ -76,9 +132,9 @@ func (s *Server) Get(ctx context.Context, in *proto.Selector) (*proto.Item, erro
 
 // Delete an item from the bucket.
 func (s *Server) Delete(ctx context.Context, in *proto.Selector) (*proto.Empty, error) {
-	err := s.Store.Delete(in.Path)
+	err := s.Store.DeleteContext(ctx, in.Path)
 	if err != nil {
-		return nil, err
+		return nil, newError(err, in.Path)
 	}
 
 	return &proto.Empty{}, nil
@@ -89,24 +145,96 @@ func (s *Server) List(ctx context.Context, in *proto.Selector) (*proto.Tree, err
 	var items []brazier.Item
 	var err error
 
-	if in.Recursive {
-		items, err = s.Store.Tree(in.Path)
-	} else {
-		items, err = s.Store.List(in.Path, 1, -1)
+	switch {
+	case in.BucketsOnly:
+		items, err = s.Store.ChildrenContext(ctx, in.Path)
+		if !in.Recursive {
+			items = topLevel(items)
+		}
+	case in.Recursive:
+		items, err = s.Store.TreeContext(ctx, in.Path)
+	default:
+		items, err = s.Store.ListContext(ctx, in.Path, 1, -1)
+		if err == nil && in.Buckets {
+			var buckets []brazier.Item
+			buckets, err = s.Store.ChildrenContext(ctx, in.Path)
+			items = append(items, topLevel(buckets)...)
+		}
 	}
 	if err != nil {
-		return nil, err
+		return nil, newError(err, in.Path)
 	}
 
 	return &proto.Tree{Children: s.tree(items)}, nil
 }
 
+// StreamList sends the content of a bucket, one node at a time.
+// The children buckets and their content are sent if the selector is recursive.
+// With a depth, the content of the buckets is only sent up to that number of levels.
+func (s *Server) StreamList(in *proto.Selector, stream proto.Bucket_StreamListServer) error {
+	return s.stream(in.Path, in.Recursive, int(in.Depth), stream)
+}
+
+// StreamTree sends the content of a bucket and of all its children, one node at a time.
+func (s *Server) StreamTree(in *proto.Selector, stream proto.Bucket_StreamTreeServer) error {
+	return s.stream(in.Path, true, int(in.Depth), stream)
+}
+
+// BulkPut saves the received items in large batches and returns a summary
+// once the client closes the stream. Items that can't be saved don't stop the stream,
+// they are reported in the summary.
+func (s *Server) BulkPut(stream proto.Bucket_BulkPutServer) error {
+	w := s.Store.NewBulkWriter(stream.Context(), store.DefaultBulkSize)
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		err = w.Put(in.Path, json.ToValidJSON(in.Value))
+		if err != nil {
+			return newError(err, in.Path)
+		}
+	}
+
+	res, err := w.Close()
+	if err != nil {
+		return newError(err, "")
+	}
+
+	return stream.SendAndClose(bulkSummary(res))
+}
+
+type nodeSender interface {
+	Send(*proto.Node) error
+	Context() context.Context
+}
+
+func (s *Server) stream(path string, recursive bool, maxDepth int, stream nodeSender) error {
+	err := s.Store.WalkContext(stream.Context(), path, recursive, store.LimitDepth(maxDepth, func(p string, depth int, item *brazier.Item) error {
+		return stream.Send(&proto.Node{
+			Key:       item.Key,
+			Value:     item.Data,
+			Path:      p,
+			Depth:     int32(depth),
+			UpdatedAt: unixNano(item.UpdatedAt),
+		})
+	}))
+
+	return newError(err, path)
+}
+
 func (s *Server) tree(items []brazier.Item) []*proto.Node {
 	list := make([]*proto.Node, len(items))
 	for i := range items {
 		list[i] = &proto.Node{
-			Key:   items[i].Key,
-			Value: items[i].Data,
+			Key:       items[i].Key,
+			Value:     items[i].Data,
+			UpdatedAt: unixNano(items[i].UpdatedAt),
 		}
 
 		if items[i].Children != nil {
@@ -116,3 +244,28 @@ func (s *Server) tree(items []brazier.Item) []*proto.Node {
 
 	return list
 }
+
+// topLevel removes the children of the given buckets.
+func topLevel(buckets []brazier.Item) []brazier.Item {
+	for i := range buckets {
+		buckets[i].Children = nil
+	}
+
+	return buckets
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+func unixTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, n)
+}
@@ -1,18 +1,31 @@
 package rpc_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/mock"
 	"github.com/asdine/brazier/rpc"
 	"github.com/asdine/brazier/rpc/proto"
 	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/tlsutil"
+	"github.com/asdine/brazier/tlsutil/tlstest"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 func newServer(t *testing.T, s *store.Store) (*grpc.ClientConn, func()) {
@@ -73,6 +86,32 @@ func TestPut(t *testing.T) {
 	require.Equal(t, []byte(`"data"`), item.Data)
 }
 
+func TestPutIf(t *testing.T) {
+	r := mock.NewRegistry(mock.NewBackend())
+	s := store.NewStore(r)
+	conn, cleanup := newServer(t, s)
+	defer cleanup()
+
+	c := proto.NewBucketClient(conn)
+
+	_, err := c.Put(context.Background(), &proto.NewItem{Path: "a/b", Value: []byte("data"), Conditional: true})
+	require.NoError(t, err)
+
+	item, err := c.Get(context.Background(), &proto.Selector{Path: "a/b"})
+	require.NoError(t, err)
+	require.NotZero(t, item.UpdatedAt)
+
+	_, err = c.Put(context.Background(), &proto.NewItem{Path: "a/b", Value: []byte("other"), Conditional: true})
+	require.Equal(t, store.ErrConflict, rpc.StoreError(err))
+
+	_, err = c.Put(context.Background(), &proto.NewItem{Path: "a/b", Value: []byte("other"), Conditional: true, UpdatedAt: item.UpdatedAt})
+	require.NoError(t, err)
+
+	item, err = c.Get(context.Background(), &proto.Selector{Path: "a/b"})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`"other"`), item.Value)
+}
+
 func TestList(t *testing.T) {
 	r := mock.NewRegistry(mock.NewBackend())
 	s := store.NewStore(r)
@@ -111,6 +150,7 @@ func TestList(t *testing.T) {
 		require.NoError(t, err)
 		for i := 0; i < 20; i++ {
 			require.Equal(t, list[i], resp.Children[i].Value)
+			require.NotZero(t, resp.Children[i].UpdatedAt)
 		}
 		require.True(t, r.BucketInvoked)
 		require.True(t, b.PageInvoked)
@@ -129,6 +169,26 @@ func TestList(t *testing.T) {
 		require.Len(t, resp.Children[0].Children[0].Children, 20)
 		require.Equal(t, "key0", resp.Children[0].Children[0].Children[0].Key)
 	})
+
+	t.Run("buckets", func(t *testing.T) {
+		resp, err := c.List(context.Background(), &proto.Selector{Path: "a/b/", Buckets: true})
+		require.NoError(t, err)
+		require.Len(t, resp.Children, 1)
+		require.Equal(t, "c/", resp.Children[0].Key)
+		require.Len(t, resp.Children[0].Children, 0)
+
+		resp, err = c.List(context.Background(), &proto.Selector{Path: "a/", Recursive: true, BucketsOnly: true})
+		require.NoError(t, err)
+		require.Len(t, resp.Children, 1)
+		require.Equal(t, "b/", resp.Children[0].Key)
+		require.Len(t, resp.Children[0].Children, 1)
+		require.Equal(t, "c/", resp.Children[0].Children[0].Key)
+
+		resp, err = c.List(context.Background(), &proto.Selector{Path: "a/", BucketsOnly: true})
+		require.NoError(t, err)
+		require.Len(t, resp.Children, 1)
+		require.Len(t, resp.Children[0].Children, 0)
+	})
 }
 
 func TestGet(t *testing.T) {
@@ -189,3 +249,308 @@ func TestDelete(t *testing.T) {
 	_, err = c.Delete(context.Background(), &proto.Selector{Path: "a/b/d"})
 	require.Error(t, err)
 }
+
+func TestBulkPut(t *testing.T) {
+	r := mock.NewRegistry(mock.NewBackend())
+	s := store.NewStore(r)
+	conn, cleanup := newServer(t, s)
+	defer cleanup()
+
+	c := proto.NewBucketClient(conn)
+
+	stream, err := c.BulkPut(context.Background())
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = stream.Send(&proto.NewItem{Path: fmt.Sprintf("a/b/key%d", i), Value: []byte("data")})
+		require.NoError(t, err)
+	}
+	err = stream.Send(&proto.NewItem{Path: "a/", Value: []byte("data")})
+	require.NoError(t, err)
+
+	summary, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, int64(4), summary.Received)
+	require.Equal(t, int64(3), summary.Saved)
+	require.Len(t, summary.Failures, 1)
+	require.Equal(t, "a/", summary.Failures[0].Path)
+	require.Equal(t, "FORBIDDEN", summary.Failures[0].Reason)
+
+	res := rpc.BulkResult(summary)
+	require.Equal(t, 3, res.Saved)
+	require.Equal(t, store.ErrForbidden, res.Failures[0].Err)
+
+	item, err := s.Get("a/b/key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(`"data"`), item.Data)
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := tlstest.NewCA(dir)
+	require.NoError(t, err)
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+	clientCertFile, clientKeyFile, err := ca.Issue("client")
+	require.NoError(t, err)
+
+	kp, err := tlsutil.NewKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	serverCfg, err := tlsutil.ServerConfig(kp, ca.CertFile)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	r := mock.NewRegistry(mock.NewBackend())
+	srv := rpc.NewServer(store.NewStore(r), rpc.WithGRPCOptions(grpc.Creds(credentials.NewTLS(serverCfg))))
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+
+	clientCfg, err := tlsutil.ClientConfig(ca.CertFile, clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientCfg)))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := proto.NewBucketClient(conn)
+	_, err = c.Create(context.Background(), &proto.Selector{Path: "a/"})
+	require.NoError(t, err)
+	require.True(t, r.CreateInvoked)
+
+	// without client certificate
+	clientCfg, err = tlsutil.ClientConfig(ca.CertFile, "", "")
+	require.NoError(t, err)
+	conn, err = grpc.Dial(l.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientCfg)))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c = proto.NewBucketClient(conn)
+	_, err = c.Create(context.Background(), &proto.Selector{Path: "b/"})
+	require.Error(t, err)
+}
+
+type checkedRegistry struct {
+	*mock.Registry
+
+	mu  sync.Mutex
+	err error
+}
+
+func (r *checkedRegistry) Check() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *checkedRegistry) setErr(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+func TestHealth(t *testing.T) {
+	r := checkedRegistry{Registry: mock.NewRegistry(mock.NewBackend())}
+	s := store.NewStore(&r)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(s)
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := healthpb.NewHealthClient(conn)
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := c.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+
+	srv.(brazier.Readiness).SetReady(true)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check("proto.Bucket"))
+
+	r.setErr(errors.New("unavailable"))
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+
+	r.setErr(nil)
+	srv.(brazier.Readiness).SetReady(false)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check("proto.Bucket"))
+}
+
+func TestHealthWatch(t *testing.T) {
+	r := checkedRegistry{Registry: mock.NewRegistry(mock.NewBackend())}
+	s := store.NewStore(&r)
+
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(s)
+	go srv.Serve(l)
+	defer srv.Stop(time.Second)
+	srv.(brazier.Readiness).SetReady(true)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: "proto.Bucket"})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// the store is checked periodically, without any call to Check
+	r.setErr(errors.New("unavailable"))
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	r.setErr(nil)
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestErrors(t *testing.T) {
+	r := mock.NewRegistry(mock.NewBackend())
+	s := store.NewStore(r)
+	conn, cleanup := newServer(t, s)
+	defer cleanup()
+
+	c := proto.NewBucketClient(conn)
+
+	_, err := c.Get(context.Background(), &proto.Selector{Path: "a/b"})
+	require.Equal(t, codes.NotFound, grpc.Code(err))
+	require.Equal(t, store.ErrNotFound, rpc.StoreError(err))
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	require.Equal(t, "NOT_FOUND", info.Reason)
+	require.Equal(t, "a/b", info.Metadata["path"])
+
+	_, err = c.Create(context.Background(), &proto.Selector{Path: "a/"})
+	require.NoError(t, err)
+	_, err = c.Create(context.Background(), &proto.Selector{Path: "a/"})
+	require.Equal(t, codes.AlreadyExists, grpc.Code(err))
+	require.Equal(t, store.ErrAlreadyExists, rpc.StoreError(err))
+
+	_, err = c.Get(context.Background(), &proto.Selector{Path: "/"})
+	require.Equal(t, codes.PermissionDenied, grpc.Code(err))
+	require.Equal(t, store.ErrForbidden, rpc.StoreError(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv := rpc.Server{Store: s}
+	_, err = srv.Get(ctx, &proto.Selector{Path: "a/b"})
+	require.Equal(t, codes.Canceled, grpc.Code(err))
+}
+
+func TestStoreError(t *testing.T) {
+	require.Nil(t, rpc.StoreError(nil))
+
+	err := errors.New("some error")
+	require.Equal(t, err, rpc.StoreError(err))
+
+	// status codes alone aren't translated, the error might not come from the store
+	err = status.Error(codes.NotFound, "unknown database \"a\"")
+	require.Equal(t, err, rpc.StoreError(err))
+	err = status.Error(codes.FailedPrecondition, "is a bucket")
+	require.Equal(t, err, rpc.StoreError(err))
+	require.EqualError(t, rpc.StoreError(status.Error(codes.Internal, "boom")), "boom")
+
+	err = status.Error(codes.Unavailable, "unavailable")
+	require.Equal(t, err, rpc.StoreError(err))
+}
+
+func TestStream(t *testing.T) {
+	r := mock.NewRegistry(mock.NewBackend())
+	s := store.NewStore(r)
+	conn, cleanup := newServer(t, s)
+	defer cleanup()
+
+	c := proto.NewBucketClient(conn)
+
+	for i := 0; i < 3; i++ {
+		_, err := s.Put(fmt.Sprintf("a/b/key%d", i), []byte(`"data"`))
+		require.NoError(t, err)
+	}
+	_, err := s.Put("a/key", []byte(`"data"`))
+	require.NoError(t, err)
+
+	recv := func(stream interface {
+		Recv() (*proto.Node, error)
+	}) ([]*proto.Node, error) {
+		var nodes []*proto.Node
+		for {
+			node, err := stream.Recv()
+			if err == io.EOF {
+				return nodes, nil
+			}
+			if err != nil {
+				return nodes, err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	t.Run("list", func(t *testing.T) {
+		stream, err := c.StreamList(context.Background(), &proto.Selector{Path: "a/b/"})
+		require.NoError(t, err)
+		nodes, err := recv(stream)
+		require.NoError(t, err)
+		require.Len(t, nodes, 3)
+		require.Equal(t, "key0", nodes[0].Key)
+		require.Equal(t, "a/b/key0", nodes[0].Path)
+		require.Equal(t, []byte(`"data"`), nodes[0].Value)
+		require.Zero(t, nodes[0].Depth)
+
+		stream, err = c.StreamList(context.Background(), &proto.Selector{Path: "a/", Recursive: true})
+		require.NoError(t, err)
+		nodes, err = recv(stream)
+		require.NoError(t, err)
+		require.Len(t, nodes, 5)
+
+		stream, err = c.StreamList(context.Background(), &proto.Selector{Path: "a/", Recursive: true, Depth: 1})
+		require.NoError(t, err)
+		nodes, err = recv(stream)
+		require.NoError(t, err)
+		require.Len(t, nodes, 2)
+		require.Equal(t, "key", nodes[0].Key)
+		require.Equal(t, "b/", nodes[1].Key)
+
+		stream, err = c.StreamList(context.Background(), &proto.Selector{Path: "a/c/"})
+		require.NoError(t, err)
+		_, err = recv(stream)
+		require.Equal(t, store.ErrNotFound, rpc.StoreError(err))
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		stream, err := c.StreamTree(context.Background(), &proto.Selector{Path: "a/"})
+		require.NoError(t, err)
+		nodes, err := recv(stream)
+		require.NoError(t, err)
+		require.Len(t, nodes, 5)
+		require.Equal(t, "key", nodes[0].Key)
+		require.Equal(t, "b/", nodes[1].Key)
+		require.Equal(t, "a/b/", nodes[1].Path)
+		require.Zero(t, nodes[1].Depth)
+		require.Equal(t, "a/b/key2", nodes[4].Path)
+		require.Equal(t, int32(1), nodes[4].Depth)
+	})
+}
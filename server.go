@@ -13,3 +13,10 @@ type Server interface {
 	// Stop gracefully stops the server.
 	Stop(time.Duration)
 }
+
+// Readiness is implemented by servers able to report whether they are ready
+// to serve requests, e.g. through health checks.
+type Readiness interface {
+	// SetReady marks the server as ready or not ready.
+	SetReady(bool)
+}
@@ -1,6 +1,7 @@
 package boltdb
 
 import (
+	"sync"
 	"time"
 
 	"github.com/asdine/brazier"
@@ -8,21 +9,12 @@ import (
 	"github.com/asdine/storm/codec/protobuf"
 	"github.com/boltdb/bolt"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // NewBackend returns a BoltDB backend.
 func NewBackend(path string) (*Backend, error) {
-	var err error
-
-	db, err := storm.Open(
-		path,
-		storm.AutoIncrement(),
-		storm.Codec(protobuf.Codec),
-		storm.BoltOptions(0644, &bolt.Options{
-			Timeout: time.Duration(50) * time.Millisecond,
-		}),
-	)
-
+	db, err := openDB(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "Can't open database")
 	}
@@ -33,16 +25,43 @@ func NewBackend(path string) (*Backend, error) {
 }
 
 // Backend is a BoltDB backend.
+// Buckets hold a read lock on the database until they are closed,
+// preventing it from being swapped during a compaction.
 type Backend struct {
 	DB *storm.DB
+	mu sync.RWMutex
 }
 
 // Bucket returns the bucket associated with the given id.
 func (s *Backend) Bucket(nodes ...string) (brazier.Bucket, error) {
-	return NewBucket(s.DB.From(nodes...)), nil
+	s.mu.RLock()
+
+	b := NewBucket(s.DB.From(nodes...))
+	b.release = s.mu.RUnlock
+	return b, nil
+}
+
+// BucketContext is like Bucket but returns immediately if the context is done.
+func (s *Backend) BucketContext(ctx context.Context, nodes ...string) (brazier.Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.Bucket(nodes...)
 }
 
 // Close BoltDB connection.
 func (s *Backend) Close() error {
 	return s.DB.Close()
 }
+
+func openDB(path string) (*storm.DB, error) {
+	return storm.Open(
+		path,
+		storm.AutoIncrement(),
+		storm.Codec(protobuf.Codec),
+		storm.BoltOptions(0644, &bolt.Options{
+			Timeout: time.Duration(50) * time.Millisecond,
+		}),
+	)
+}
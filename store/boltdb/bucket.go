@@ -1,11 +1,15 @@
 package boltdb
 
 import (
+	"sync"
+	"time"
+
 	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/store"
 	"github.com/asdine/brazier/store/boltdb/internal"
 	"github.com/asdine/storm"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // NewBucket returns a Bucket
@@ -17,11 +21,29 @@ func NewBucket(node storm.Node) *Bucket {
 
 // Bucket is a BoltDB implementation a bucket
 type Bucket struct {
-	node storm.Node
+	node    storm.Node
+	release func()
+	once    sync.Once
 }
 
 // Save user data to the bucket. Returns an Iten
 func (b *Bucket) Save(key string, data []byte) (*brazier.Item, error) {
+	return b.save(key, data, nil)
+}
+
+// SaveIf saves user data to the bucket if the item was last modified at updatedAt,
+// zero if it doesn't exist. Returns store.ErrConflict otherwise.
+func (b *Bucket) SaveIf(key string, data []byte, updatedAt time.Time) (*brazier.Item, error) {
+	var expected int64
+	if !updatedAt.IsZero() {
+		expected = updatedAt.UnixNano()
+	}
+
+	return b.save(key, data, &expected)
+}
+
+// save the item in a transaction, checking its modification time first if expected is set.
+func (b *Bucket) save(key string, data []byte, expected *int64) (*brazier.Item, error) {
 	var i internal.Item
 
 	tx, err := b.node.Begin(true)
@@ -44,15 +66,56 @@ func (b *Bucket) Save(key string, data []byte) (*brazier.Item, error) {
 		i.Data = data
 	}
 
+	if expected != nil && i.UpdatedAt != *expected {
+		return nil, store.ErrConflict
+	}
+
+	i.UpdatedAt = time.Now().UnixNano()
 	err = tx.Save(&i)
 	if err != nil {
 		return nil, err
 	}
 
-	return &brazier.Item{
-		Key:  i.Key,
-		Data: i.Data,
-	}, tx.Commit()
+	return newItem(&i), tx.Commit()
+}
+
+// SaveBatch saves all the items in a single transaction.
+func (b *Bucket) SaveBatch(items []brazier.Item) error {
+	tx, err := b.node.Begin(true)
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	for _, item := range items {
+		var i internal.Item
+
+		err = tx.One("Key", item.Key, &i)
+		if err != nil {
+			if err != storm.ErrNotFound {
+				return errors.Wrap(err, "failed to fetch item")
+			}
+
+			i = internal.Item{
+				Key: item.Key,
+			}
+		}
+
+		i.Data = item.Data
+		i.UpdatedAt = now
+		err = tx.Save(&i)
+		if err != nil {
+			return errors.Wrap(err, "failed to save item")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "failed to commit")
+	}
+
+	return nil
 }
 
 // Get an item by id
@@ -67,10 +130,7 @@ func (b *Bucket) Get(key string) (*brazier.Item, error) {
 		return nil, errors.Wrap(err, "failed to fetch item")
 	}
 
-	return &brazier.Item{
-		Key:  i.Key,
-		Data: i.Data,
-	}, nil
+	return newItem(&i), nil
 }
 
 // Delete item from the bucket
@@ -124,13 +184,97 @@ func (b *Bucket) Page(page int, perPage int) ([]brazier.Item, error) {
 
 	items := make([]brazier.Item, len(list))
 	for i := range list {
-		items[i].Key = list[i].Key
-		items[i].Data = list[i].Data
+		items[i] = *newItem(&list[i])
 	}
 	return items, nil
 }
 
+// Iterate calls fn for every item of the bucket, one item at a time.
+func (b *Bucket) Iterate(fn func(*brazier.Item) error) error {
+	return b.IterateContext(context.Background(), fn)
+}
+
+// IterateContext calls fn for every item of the bucket, one item at a time.
+// The context is checked before reading each item.
+func (b *Bucket) IterateContext(ctx context.Context, fn func(*brazier.Item) error) error {
+	var ferr error
+
+	err := b.node.Select().Each(new(internal.Item), func(record interface{}) error {
+		ferr = ctx.Err()
+		if ferr != nil {
+			return ferr
+		}
+
+		ferr = fn(newItem(record.(*internal.Item)))
+		return ferr
+	})
+	if ferr != nil {
+		return ferr
+	}
+
+	if err != nil && err != storm.ErrNotFound {
+		return errors.Wrap(err, "boltdb.bucket.Iterate failed to fetch items")
+	}
+
+	return nil
+}
+
+// newItem converts a stored item, the modification time being unknown for the items
+// saved by older versions.
+func newItem(i *internal.Item) *brazier.Item {
+	item := brazier.Item{
+		Key:  i.Key,
+		Data: i.Data,
+	}
+
+	if i.UpdatedAt != 0 {
+		item.UpdatedAt = time.Unix(0, i.UpdatedAt)
+	}
+
+	return &item
+}
+
+// SaveContext is like Save but returns immediately if the context is done.
+func (b *Bucket) SaveContext(ctx context.Context, key string, data []byte) (*brazier.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Save(key, data)
+}
+
+// GetContext is like Get but returns immediately if the context is done.
+func (b *Bucket) GetContext(ctx context.Context, key string) (*brazier.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Get(key)
+}
+
+// DeleteContext is like Delete but returns immediately if the context is done.
+func (b *Bucket) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.Delete(key)
+}
+
+// PageContext is like Page but returns immediately if the context is done.
+func (b *Bucket) PageContext(ctx context.Context, page int, perPage int) ([]brazier.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.Page(page, perPage)
+}
+
 // Close the bucket session
 func (b *Bucket) Close() error {
+	if b.release != nil {
+		b.once.Do(b.release)
+	}
+
 	return nil
 }
@@ -1,12 +1,15 @@
 package boltdb_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/store"
 	"github.com/asdine/brazier/store/boltdb"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func TestBucketSave(t *testing.T) {
@@ -23,6 +26,7 @@ func TestBucketSave(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "2a", i1.Key)
 	require.Equal(t, []byte("Data"), i1.Data)
+	require.False(t, i1.UpdatedAt.IsZero())
 
 	i2, err := b.Get("2a")
 	require.NoError(t, err)
@@ -31,11 +35,42 @@ func TestBucketSave(t *testing.T) {
 	j, err := b.Save("2a", []byte("New Data"))
 	require.NoError(t, err)
 	require.Equal(t, []byte("New Data"), j.Data)
+	require.False(t, j.UpdatedAt.Before(i1.UpdatedAt))
 
 	err = b.Close()
 	require.NoError(t, err)
 }
 
+func TestBucketSaveBatch(t *testing.T) {
+	path, cleanup := preparePath(t, "store.db")
+	defer cleanup()
+
+	s, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+
+	b, err := s.Bucket("a", "b")
+	require.NoError(t, err)
+	defer b.Close()
+
+	_, err = b.Save("id", []byte("Data"))
+	require.NoError(t, err)
+
+	err = b.(*boltdb.Bucket).SaveBatch([]brazier.Item{
+		{Key: "id", Data: []byte("New data")},
+		{Key: "other", Data: []byte("Other")},
+		{Key: "other", Data: []byte("Other data")},
+	})
+	require.NoError(t, err)
+
+	items, err := b.Page(1, -1)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, []byte("New data"), items[0].Data)
+	require.Equal(t, "other", items[1].Key)
+	require.Equal(t, []byte("Other data"), items[1].Data)
+	require.Equal(t, items[0].UpdatedAt, items[1].UpdatedAt)
+}
+
 func TestBucketGet(t *testing.T) {
 	path, cleanup := preparePath(t, "store.db")
 	defer cleanup()
@@ -146,3 +181,54 @@ func TestBucketPage(t *testing.T) {
 	require.Equal(t, "0", list[0].Key)
 	require.Equal(t, "19", list[19].Key)
 }
+
+func TestBucketIterate(t *testing.T) {
+	path, cleanup := preparePath(t, "store.db")
+	defer cleanup()
+
+	s, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+
+	b, err := s.Bucket("a", "b", "c")
+	require.NoError(t, err)
+	defer b.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := b.Save(fmt.Sprintf("%d", i), []byte("Data"))
+		require.NoError(t, err)
+	}
+
+	var keys []string
+	err = b.(*boltdb.Bucket).Iterate(func(item *brazier.Item) error {
+		keys = append(keys, item.Key)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, keys, 20)
+	require.Equal(t, "0", keys[0])
+	require.Equal(t, "19", keys[19])
+
+	stop := errors.New("stop")
+	keys = nil
+	err = b.(*boltdb.Bucket).Iterate(func(item *brazier.Item) error {
+		keys = append(keys, item.Key)
+		if len(keys) == 5 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, stop, err)
+	require.Len(t, keys, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys = nil
+	err = b.(*boltdb.Bucket).IterateContext(ctx, func(item *brazier.Item) error {
+		keys = append(keys, item.Key)
+		if len(keys) == 5 {
+			cancel()
+		}
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Len(t, keys, 5)
+}
@@ -0,0 +1,39 @@
+package boltdb
+
+import (
+	"github.com/asdine/brazier/store"
+	"github.com/asdine/storm"
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// Check makes sure the backend database can serve a read transaction.
+func (s *Backend) Check() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return errors.Wrap(dbCheck(s.DB), "backend is unavailable")
+}
+
+// Check makes sure the registry database can serve a read transaction,
+// then checks the backend if possible.
+func (r *Registry) Check() error {
+	r.mu.RLock()
+	err := dbCheck(r.DB)
+	r.mu.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "registry is unavailable")
+	}
+
+	if c, ok := r.Backend.(store.Checker); ok {
+		return c.Check()
+	}
+
+	return nil
+}
+
+func dbCheck(db *storm.DB) error {
+	return db.Bolt.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package boltdb_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	path, cleanup := preparePath(t, "backend.db")
+	defer cleanup()
+
+	b, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+
+	r, err := boltdb.NewRegistry(filepath.Join(filepath.Dir(path), "registry.db"), b)
+	require.NoError(t, err)
+
+	err = r.Check()
+	require.NoError(t, err)
+
+	err = b.Close()
+	require.NoError(t, err)
+
+	err = b.Check()
+	require.Error(t, err)
+
+	err = r.Check()
+	require.Error(t, err)
+
+	err = r.Close()
+	require.NoError(t, err)
+
+	err = r.Check()
+	require.Error(t, err)
+}
@@ -36,6 +36,8 @@ type Item struct {
 	// @inject_tag: storm:"unique"
 	Key  string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty" storm:"unique"`
 	Data []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// unix time in nanoseconds
+	UpdatedAt int64 `protobuf:"varint,4,opt,name=updated_at,json=updatedAt" json:"updated_at,omitempty"`
 }
 
 func (m *Item) Reset()                    { *m = Item{} }
@@ -64,6 +66,13 @@ func (m *Item) GetData() []byte {
 	return nil
 }
 
+func (m *Item) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Item)(nil), "internal.Item")
 }
@@ -71,12 +80,13 @@ func init() {
 func init() { proto.RegisterFile("item.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 108 bytes of a gzipped FileDescriptorProto
+	// 127 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0xca, 0x2c, 0x49, 0xcd,
 	0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0xc8, 0xcc, 0x2b, 0x49, 0x2d, 0xca, 0x4b, 0xcc,
-	0x51, 0xb2, 0xe1, 0x62, 0xf1, 0x2c, 0x49, 0xcd, 0x15, 0xe2, 0xe3, 0x62, 0xca, 0x4c, 0x91, 0x60,
+	0x51, 0x8a, 0xe6, 0x62, 0xf1, 0x2c, 0x49, 0xcd, 0x15, 0xe2, 0xe3, 0x62, 0xca, 0x4c, 0x91, 0x60,
 	0x54, 0x60, 0xd4, 0x60, 0x0e, 0x62, 0xca, 0x4c, 0x11, 0x12, 0xe0, 0x62, 0xce, 0x4e, 0xad, 0x94,
 	0x60, 0x52, 0x60, 0xd4, 0xe0, 0x0c, 0x02, 0x31, 0x85, 0x84, 0xb8, 0x58, 0x52, 0x12, 0x4b, 0x12,
-	0x25, 0x98, 0x15, 0x18, 0x35, 0x78, 0x82, 0xc0, 0xec, 0x24, 0x36, 0xb0, 0x71, 0xc6, 0x80, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x30, 0x7f, 0x44, 0xba, 0x5c, 0x00, 0x00, 0x00,
+	0x25, 0x98, 0x15, 0x18, 0x35, 0x78, 0x82, 0xc0, 0x6c, 0x21, 0x59, 0x2e, 0xae, 0xd2, 0x82, 0x94,
+	0xc4, 0x92, 0xd4, 0x94, 0xf8, 0xc4, 0x12, 0x09, 0x16, 0xb0, 0x6e, 0x4e, 0xa8, 0x88, 0x63, 0x49,
+	0x12, 0x1b, 0xd8, 0x36, 0x63, 0xc0, 0x00, 0x9f, 0xbf, 0x60, 0xb7, 0x7b, 0x00, 0x00, 0x00,
 }
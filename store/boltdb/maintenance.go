@@ -0,0 +1,323 @@
+package boltdb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// CompactTimeout is how long a compaction waits for the opened buckets to be closed
+// before giving up. Requests are not blocked while it waits.
+var CompactTimeout = 5 * time.Second
+
+// ErrBusy is returned by Compact when buckets are still opened after CompactTimeout,
+// for example by a client slowly reading a large bucket.
+var ErrBusy = errors.New("database is busy, try again later")
+
+// Path of the backend database file.
+func (s *Backend) Path() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.DB.Bolt.Path()
+}
+
+// Size of the backend database file, in bytes.
+func (s *Backend) Size() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return dbSize(s.DB)
+}
+
+// Backup writes a consistent copy of the backend database to w.
+// Writes are not blocked during the backup.
+func (s *Backend) Backup(w io.Writer) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return backupDB(s.DB, w)
+}
+
+// Compact rewrites the backend database file to reclaim the unused space.
+// Requests are blocked until the compaction is over, and opened buckets must be
+// closed for the compaction to start. It returns ErrBusy if they are not closed
+// within CompactTimeout.
+func (s *Backend) Compact() error {
+	if !tryLock(&s.mu, CompactTimeout) {
+		return ErrBusy
+	}
+	defer s.mu.Unlock()
+
+	db, err := compactDB(s.DB)
+	if db != nil {
+		s.DB = db
+	}
+
+	return err
+}
+
+// Path of the registry database file.
+func (r *Registry) Path() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.DB.Bolt.Path()
+}
+
+// Size of the registry database file, in bytes.
+func (r *Registry) Size() (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return dbSize(r.DB)
+}
+
+// Backup writes a consistent copy of the registry database to w.
+// Writes are not blocked during the backup.
+func (r *Registry) Backup(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return backupDB(r.DB, w)
+}
+
+// Compact rewrites the registry database file to reclaim the unused space.
+// The backend is not compacted. Requests are blocked until the compaction is over.
+// It returns ErrBusy if the registry is still in use after CompactTimeout.
+func (r *Registry) Compact() error {
+	if !tryLock(&r.mu, CompactTimeout) {
+		return ErrBusy
+	}
+	defer r.mu.Unlock()
+
+	db, err := compactDB(r.DB)
+	if db != nil {
+		r.DB = db
+	}
+
+	return err
+}
+
+// tryLock acquires the write lock of mu, waiting at most for the given duration.
+// Unlike mu.Lock, new readers are not blocked while it waits.
+func tryLock(mu *sync.RWMutex, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !mu.TryLock() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return true
+}
+
+func backupDB(db *storm.DB, w io.Writer) (int64, error) {
+	var n int64
+
+	err := db.Bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return n, errors.Wrap(err, "failed to backup database")
+	}
+
+	return n, nil
+}
+
+// compactDB copies the database to a new file, replaces the original file with it
+// once its content is verified and reopens the database.
+// It returns the database to use from now on, or nil if it couldn't be reopened.
+func compactDB(db *storm.DB) (*storm.DB, error) {
+	path := db.Bolt.Path()
+	tmp := path + ".compact"
+
+	err := copyDB(db.Bolt, tmp)
+	if err == nil {
+		err = verifyCopy(db.Bolt, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return db, errors.Wrap(err, "failed to compact database")
+	}
+
+	err = db.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return db, errors.Wrap(err, "failed to close database")
+	}
+
+	renameErr := os.Rename(tmp, path)
+	if renameErr != nil {
+		os.Remove(tmp)
+	}
+
+	db, err = openDB(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reopen database")
+	}
+
+	if renameErr != nil {
+		return db, errors.Wrap(renameErr, "failed to replace database")
+	}
+
+	return db, nil
+}
+
+// CompactFile compacts the database file at the given path, which must not be opened.
+// The database is copied to a new file which replaces the original one once its content
+// is verified. It returns the size of the file before and after the compaction, in bytes.
+func CompactFile(path string) (int64, int64, error) {
+	src, err := bolt.Open(path, 0644, &bolt.Options{
+		Timeout: time.Duration(50) * time.Millisecond,
+	})
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to open database")
+	}
+	defer src.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to read database size")
+	}
+	before := fi.Size()
+
+	tmp := path + ".compact"
+	err = copyDB(src, tmp)
+	if err == nil {
+		err = verifyCopy(src, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return before, 0, errors.Wrap(err, "failed to compact database")
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return before, 0, errors.Wrap(err, "failed to replace database")
+	}
+
+	fi, err = os.Stat(path)
+	if err != nil {
+		return before, 0, errors.Wrap(err, "failed to read database size")
+	}
+
+	return before, fi.Size(), nil
+}
+
+// copyDB copies all the buckets of src to a new database created at the given path.
+// A file left at this path by an interrupted compaction is removed first.
+func copyDB(src *bolt.DB, path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	dst, err := bolt.Open(path, 0644, &bolt.Options{
+		Timeout: time.Duration(50) * time.Millisecond,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = src.View(func(stx *bolt.Tx) error {
+		return dst.Update(func(dtx *bolt.Tx) error {
+			return stx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				nb, err := dtx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+
+				return copyBucket(b, nb)
+			})
+		})
+	})
+
+	cerr := dst.Close()
+	if err != nil {
+		return err
+	}
+
+	return cerr
+}
+
+func copyBucket(src, dst *bolt.Bucket) error {
+	// buckets are written sequentially, pages can be filled entirely.
+	dst.FillPercent = 1.0
+
+	err := dst.SetSequence(src.Sequence())
+	if err != nil {
+		return err
+	}
+
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+
+		nb, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+
+		return copyBucket(src.Bucket(k), nb)
+	})
+}
+
+// verifyCopy checks that the database created at the given path has the same content as src.
+func verifyCopy(src *bolt.DB, path string) error {
+	dst, err := bolt.Open(path, 0644, &bolt.Options{
+		ReadOnly: true,
+		Timeout:  time.Duration(50) * time.Millisecond,
+	})
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	return src.View(func(stx *bolt.Tx) error {
+		return dst.View(func(dtx *bolt.Tx) error {
+			return compareCursors(stx.Cursor(), dtx.Cursor(), stx.Bucket, dtx.Bucket)
+		})
+	})
+}
+
+// compareCursors checks that both cursors iterate over the same keys and values.
+// Nested buckets are returned by the src and dst functions and compared recursively.
+func compareCursors(sc, dc *bolt.Cursor, src, dst func([]byte) *bolt.Bucket) error {
+	sk, sv := sc.First()
+	dk, dv := dc.First()
+
+	for sk != nil || dk != nil {
+		if !bytes.Equal(sk, dk) || !bytes.Equal(sv, dv) || (sv == nil) != (dv == nil) {
+			return errors.Errorf("copy differs at key %q", sk)
+		}
+
+		if sv == nil {
+			sb, db := src(sk), dst(dk)
+			if sb.Sequence() != db.Sequence() {
+				return errors.Errorf("copy of bucket %q has a different sequence", sk)
+			}
+
+			err := compareCursors(sb.Cursor(), db.Cursor(), sb.Bucket, db.Bucket)
+			if err != nil {
+				return err
+			}
+		}
+
+		sk, sv = sc.Next()
+		dk, dv = dc.Next()
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package boltdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	path, cleanup := preparePath(t, "backend.db")
+	defer cleanup()
+
+	b, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+	defer b.Close()
+
+	bucket, err := b.Bucket("a")
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		_, err = bucket.Save(fmt.Sprintf("%d", i), bytes.Repeat([]byte("a"), 100))
+		require.NoError(t, err)
+	}
+	for i := 0; i < 900; i++ {
+		err = bucket.Delete(fmt.Sprintf("%d", i))
+		require.NoError(t, err)
+	}
+	err = bucket.Close()
+	require.NoError(t, err)
+
+	before, err := b.Size()
+	require.NoError(t, err)
+
+	// a file left by an interrupted compaction is replaced
+	err = ioutil.WriteFile(path+".compact", []byte("garbage"), 0644)
+	require.NoError(t, err)
+
+	err = b.Compact()
+	require.NoError(t, err)
+
+	after, err := b.Size()
+	require.NoError(t, err)
+	require.True(t, after < before)
+	require.Equal(t, path, b.Path())
+
+	bucket, err = b.Bucket("a")
+	require.NoError(t, err)
+	defer bucket.Close()
+
+	list, err := bucket.Page(1, -1)
+	require.NoError(t, err)
+	require.Len(t, list, 100)
+	require.Equal(t, "900", list[0].Key)
+
+	// the sequence is preserved
+	item, err := bucket.Save("new", []byte("data"))
+	require.NoError(t, err)
+	require.Equal(t, "new", item.Key)
+	list, err = bucket.Page(1, -1)
+	require.NoError(t, err)
+	require.Len(t, list, 101)
+	require.Equal(t, "new", list[100].Key)
+}
+
+func TestBackup(t *testing.T) {
+	path, cleanup := preparePath(t, "backend.db")
+	defer cleanup()
+
+	b, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+
+	r, err := boltdb.NewRegistry(filepath.Join(filepath.Dir(path), "registry.db"), b)
+	require.NoError(t, err)
+	defer r.Close()
+
+	err = r.Create("a", "b")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := r.Backup(&buf)
+	require.NoError(t, err)
+	require.NotZero(t, n)
+	require.Equal(t, int64(buf.Len()), n)
+}
+
+func TestCompactFile(t *testing.T) {
+	path, cleanup := preparePath(t, "backend.db")
+	defer cleanup()
+
+	db, err := bolt.Open(path, 0644, nil)
+	require.NoError(t, err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("a"))
+		if err != nil {
+			return err
+		}
+
+		nested, err := b.CreateBucket([]byte("index"))
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < 1000; i++ {
+			k := []byte(fmt.Sprintf("%04d", i))
+			err = b.Put(k, bytes.Repeat([]byte("a"), 100))
+			if err != nil {
+				return err
+			}
+
+			err = nested.Put(k, k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return b.SetSequence(1000)
+	})
+	require.NoError(t, err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("a"))
+		for i := 0; i < 900; i++ {
+			err := b.Delete([]byte(fmt.Sprintf("%04d", i)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the database can't be compacted while it is opened
+	_, _, err = boltdb.CompactFile(path)
+	require.Error(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path+".compact", []byte("garbage"), 0644)
+	require.NoError(t, err)
+
+	before, after, err := boltdb.CompactFile(path)
+	require.NoError(t, err)
+	require.True(t, after < before)
+
+	_, err = os.Stat(path + ".compact")
+	require.True(t, os.IsNotExist(err))
+
+	db, err = bolt.Open(path, 0644, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("a"))
+		require.NotNil(t, b)
+		require.Equal(t, uint64(1000), b.Sequence())
+		var n int
+		b.ForEach(func(k, v []byte) error {
+			if v != nil {
+				n++
+			}
+			return nil
+		})
+		require.Equal(t, 100, n)
+		require.Equal(t, bytes.Repeat([]byte("a"), 100), b.Get([]byte("0900")))
+		require.Equal(t, []byte("0001"), b.Bucket([]byte("index")).Get([]byte("0001")))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCompactBusy(t *testing.T) {
+	path, cleanup := preparePath(t, "backend.db")
+	defer cleanup()
+
+	b, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+	defer b.Close()
+
+	timeout := boltdb.CompactTimeout
+	boltdb.CompactTimeout = 100 * time.Millisecond
+	defer func() { boltdb.CompactTimeout = timeout }()
+
+	bucket, err := b.Bucket("a")
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- b.Compact()
+	}()
+
+	// other requests are not blocked while the compaction waits
+	other, err := b.Bucket("b")
+	require.NoError(t, err)
+	_, err = other.Save("key", []byte("data"))
+	require.NoError(t, err)
+	err = other.Close()
+	require.NoError(t, err)
+
+	err = <-done
+	require.Equal(t, boltdb.ErrBusy, err)
+
+	err = bucket.Close()
+	require.NoError(t, err)
+
+	err = b.Compact()
+	require.NoError(t, err)
+}
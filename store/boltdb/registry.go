@@ -3,31 +3,20 @@ package boltdb
 import (
 	"path"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/store"
 	"github.com/asdine/brazier/store/boltdb/internal"
 	"github.com/asdine/storm"
-	"github.com/asdine/storm/codec/protobuf"
 	"github.com/asdine/storm/q"
-	"github.com/boltdb/bolt"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // NewRegistry returns a BoltDB Registry.
 func NewRegistry(path string, b brazier.Backend) (*Registry, error) {
-	var err error
-
-	db, err := storm.Open(
-		path,
-		storm.AutoIncrement(),
-		storm.Codec(protobuf.Codec),
-		storm.BoltOptions(0644, &bolt.Options{
-			Timeout: time.Duration(50) * time.Millisecond,
-		}),
-	)
-
+	db, err := openDB(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "Can't open database")
 	}
@@ -50,10 +39,23 @@ func NewRegistry(path string, b brazier.Backend) (*Registry, error) {
 type Registry struct {
 	DB      *storm.DB
 	Backend brazier.Backend
+	mu      sync.RWMutex
 }
 
 // Create a bucket in the registry.
 func (r *Registry) Create(nodes ...string) error {
+	return r.CreateContext(context.Background(), nodes...)
+}
+
+// CreateContext is like Create but returns immediately if the context is done.
+func (r *Registry) CreateContext(ctx context.Context, nodes ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	tx, err := r.DB.Begin(true)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create bucket at path %s", strings.Join(nodes, "/"))
@@ -87,6 +89,18 @@ func (r *Registry) Create(nodes ...string) error {
 
 // Bucket returns the selected bucket from the Backend.
 func (r *Registry) Bucket(nodes ...string) (brazier.Bucket, error) {
+	return r.BucketContext(context.Background(), nodes...)
+}
+
+// BucketContext is like Bucket but returns immediately if the context is done.
+func (r *Registry) BucketContext(ctx context.Context, nodes ...string) (brazier.Bucket, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var meta internal.Meta
 	key := "/"
 
@@ -103,11 +117,23 @@ func (r *Registry) Bucket(nodes ...string) (brazier.Bucket, error) {
 		return nil, errors.Wrapf(err, "failed to fetch bucket at path %s", key)
 	}
 
-	return r.Backend.Bucket(nodes...)
+	return brazier.BackendWithContext(r.Backend).BucketContext(ctx, nodes...)
 }
 
 // Children buckets of the specified path.
 func (r *Registry) Children(nodes ...string) ([]brazier.Item, error) {
+	return r.ChildrenContext(context.Background(), nodes...)
+}
+
+// ChildrenContext is like Children but returns immediately if the context is done.
+func (r *Registry) ChildrenContext(ctx context.Context, nodes ...string) ([]brazier.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var metas []internal.Meta
 
 	prefix := path.Join("/", strings.Join(nodes, "/"))
@@ -0,0 +1,149 @@
+package boltdb
+
+import (
+	"os"
+
+	"github.com/asdine/storm"
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// Stats of a BoltDB database.
+type Stats struct {
+	bolt.Stats
+
+	// Size of the database file, in bytes.
+	Size int64
+}
+
+// Stats returns the statistics of the backend database.
+func (s *Backend) Stats() (*Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return dbStats(s.DB)
+}
+
+// Stats returns the statistics of the registry database.
+func (r *Registry) Stats() (*Stats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return dbStats(r.DB)
+}
+
+// Names of the bolt buckets used by storm to save the items of a bucket and the buckets of the registry.
+const (
+	itemBucket     = "Item"
+	metaBucket     = "Meta"
+	keyIndexBucket = "__storm_index_Key"
+)
+
+// CountItems returns the number of items of the backend. The items aren't read,
+// they are counted from the unique index of their keys.
+func (s *Backend) CountItems() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	err := s.DB.Bolt.View(func(tx *bolt.Tx) error {
+		n = countItems(tx)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count items")
+	}
+
+	return n, nil
+}
+
+// Count returns the number of buckets, the root bucket excluded, and the number of items
+// of the backend if it is able to count them. They are counted from the unique index of their keys,
+// without reading them. It implements the store.Counter interface.
+func (r *Registry) Count() (int, int, error) {
+	r.mu.RLock()
+	var buckets int
+	err := r.DB.Bolt.View(func(tx *bolt.Tx) error {
+		buckets = indexSize(tx.Bucket([]byte(metaBucket))) - 1
+		return nil
+	})
+	r.mu.RUnlock()
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to count buckets")
+	}
+
+	c, ok := r.Backend.(interface {
+		CountItems() (int, error)
+	})
+	if !ok {
+		return 0, 0, errors.New("backend can't count its items")
+	}
+
+	items, err := c.CountItems()
+	return buckets, items, err
+}
+
+// bucketContainer is implemented by bolt.Tx and bolt.Bucket.
+type bucketContainer interface {
+	Cursor() *bolt.Cursor
+	Bucket(name []byte) *bolt.Bucket
+}
+
+// countItems returns the number of items saved in the bolt bucket and its sub-buckets.
+// Only the names of the sub-buckets are read, the items bucket isn't browsed.
+func countItems(b bucketContainer) int {
+	var n int
+
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		// values are nil for sub-buckets only
+		if v != nil {
+			continue
+		}
+
+		if string(k) == itemBucket {
+			n += indexSize(b.Bucket(k))
+			continue
+		}
+
+		n += countItems(b.Bucket(k))
+	}
+
+	return n
+}
+
+// indexSize returns the number of entries of the unique index of the keys of the bucket,
+// using the statistics of the index pages.
+func indexSize(b *bolt.Bucket) int {
+	if b == nil {
+		return 0
+	}
+
+	idx := b.Bucket([]byte(keyIndexBucket))
+	if idx == nil {
+		return 0
+	}
+
+	return idx.Stats().KeyN
+}
+
+func dbStats(db *storm.DB) (*Stats, error) {
+	size, err := dbSize(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stats{
+		Stats: db.Bolt.Stats(),
+		Size:  size,
+	}, nil
+}
+
+func dbSize(db *storm.DB) (int64, error) {
+	fi, err := os.Stat(db.Bolt.Path())
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read database file size")
+	}
+
+	return fi.Size(), nil
+}
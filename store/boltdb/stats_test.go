@@ -0,0 +1,47 @@
+package boltdb_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/brazier/store"
+	"github.com/asdine/brazier/store/boltdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	path, cleanup := preparePath(t, "backend.db")
+	defer cleanup()
+
+	b, err := boltdb.NewBackend(path)
+	require.NoError(t, err)
+
+	r, err := boltdb.NewRegistry(filepath.Join(filepath.Dir(path), "registry.db"), b)
+	require.NoError(t, err)
+	defer r.Close()
+
+	stats, err := b.Stats()
+	require.NoError(t, err)
+	require.NotZero(t, stats.Size)
+
+	err = r.Create("a", "b")
+	require.NoError(t, err)
+
+	stats, err = r.Stats()
+	require.NoError(t, err)
+	require.NotZero(t, stats.Size)
+	require.NotZero(t, stats.TxN)
+
+	s := store.NewStore(r)
+	for _, p := range []string{"a/k1", "a/k2", "a/b/k3", "c/k4"} {
+		_, err = s.Put(p, []byte(`"data"`))
+		require.NoError(t, err)
+	}
+	err = s.Delete("a/k2")
+	require.NoError(t, err)
+
+	buckets, items, err := r.Count()
+	require.NoError(t, err)
+	require.Equal(t, 3, buckets)
+	require.Equal(t, 3, items)
+}
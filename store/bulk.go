@@ -0,0 +1,178 @@
+package store
+
+import (
+	"path"
+	"time"
+
+	"github.com/asdine/brazier"
+	"golang.org/x/net/context"
+)
+
+// DefaultBulkSize is the default number of items buffered by a BulkWriter before being saved.
+const DefaultBulkSize = 1000
+
+// A BulkFailure describes an item that couldn't be saved during a bulk write.
+type BulkFailure struct {
+	Path string
+	Err  error
+}
+
+// A BulkResult summarizes a bulk write.
+type BulkResult struct {
+	// Received is the number of items given to the BulkWriter.
+	Received int
+	// Saved is the number of items successfully saved.
+	Saved int
+	// Failures lists the items that couldn't be saved.
+	Failures []BulkFailure
+}
+
+// NewBulkWriter returns a BulkWriter buffering up to size items before saving them.
+// If size is zero or negative, DefaultBulkSize is used.
+func (s *Store) NewBulkWriter(ctx context.Context, size int) *BulkWriter {
+	if size <= 0 {
+		size = DefaultBulkSize
+	}
+
+	return &BulkWriter{
+		store: s,
+		ctx:   ctx,
+		size:  size,
+	}
+}
+
+// A BulkWriter saves large amounts of items efficiently.
+// Items are buffered and grouped by bucket, missing buckets are created once per flush
+// and the items of a bucket are saved in a single write transaction
+// if the bucket implements brazier.Batcher.
+// Items that can't be saved are reported in the result instead of stopping the writer.
+type BulkWriter struct {
+	store   *Store
+	ctx     context.Context
+	size    int
+	pending int
+	batches map[string]*bulkBatch
+	order   []string
+	result  BulkResult
+}
+
+type bulkBatch struct {
+	nodes []string
+	items []brazier.Item
+}
+
+// Put adds an item to the buffer, saving the buffered items if it is full.
+// It only returns an error if the context is done.
+func (w *BulkWriter) Put(rawPath string, value []byte) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	w.result.Received++
+
+	nodes, key := SplitPathKey(rawPath)
+	if key == "" {
+		w.fail(rawPath, ErrForbidden)
+		return nil
+	}
+
+	if w.batches == nil {
+		w.batches = make(map[string]*bulkBatch)
+	}
+
+	p := path.Join(nodes...)
+	b, ok := w.batches[p]
+	if !ok {
+		b = &bulkBatch{nodes: nodes}
+		w.batches[p] = b
+		w.order = append(w.order, p)
+	}
+
+	b.items = append(b.items, brazier.Item{Key: key, Data: value})
+	w.pending++
+
+	if w.pending >= w.size {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// Flush saves the buffered items.
+func (w *BulkWriter) Flush() error {
+	for _, p := range w.order {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+
+		w.save(w.batches[p])
+	}
+
+	w.batches = nil
+	w.order = nil
+	w.pending = 0
+	return w.ctx.Err()
+}
+
+// Close saves the remaining items and returns the summary of the bulk write.
+func (w *BulkWriter) Close() (*BulkResult, error) {
+	err := w.Flush()
+	return &w.result, err
+}
+
+// Abort drops the buffered items without saving them.
+// The items already saved by a previous flush are kept.
+func (w *BulkWriter) Abort() {
+	w.batches = nil
+	w.order = nil
+	w.pending = 0
+}
+
+func (w *BulkWriter) save(b *bulkBatch) {
+	var err error
+	defer w.store.observe(OpBulkPut, time.Now(), &err)
+
+	bucket, err := GetBucketOrCreateContext(w.ctx, w.store.Registry, b.nodes...)
+	if err != nil {
+		w.failBatch(b, err)
+		return
+	}
+	defer bucket.Close()
+
+	if batcher, ok := bucket.(brazier.Batcher); ok {
+		err = batcher.SaveBatch(b.items)
+		if err != nil {
+			w.failBatch(b, err)
+			return
+		}
+
+		w.result.Saved += len(b.items)
+		return
+	}
+
+	cb := brazier.BucketWithContext(bucket)
+	for _, item := range b.items {
+		_, serr := cb.SaveContext(w.ctx, item.Key, item.Data)
+		if serr != nil {
+			err = serr
+			w.fail(itemPath(b.nodes, item.Key), serr)
+			continue
+		}
+
+		w.result.Saved++
+	}
+}
+
+func (w *BulkWriter) fail(rawPath string, err error) {
+	w.result.Failures = append(w.result.Failures, BulkFailure{Path: rawPath, Err: err})
+}
+
+func (w *BulkWriter) failBatch(b *bulkBatch, err error) {
+	for _, item := range b.items {
+		w.fail(itemPath(b.nodes, item.Key), err)
+	}
+}
+
+func itemPath(nodes []string, key string) string {
+	return path.Join(append(nodes, key)...)
+}
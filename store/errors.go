@@ -8,4 +8,5 @@ var (
 	ErrAlreadyExists = errors.New("already exists")
 	ErrForbidden     = errors.New("forbidden")
 	ErrIsBucket      = errors.New("is a bucket")
+	ErrConflict      = errors.New("modified in the meantime")
 )
@@ -1,10 +1,13 @@
 package store
 
 import (
+	"errors"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/asdine/brazier"
+	"golang.org/x/net/context"
 )
 
 // NewStore instantiates a new Store with the given Registry.
@@ -17,10 +20,50 @@ func NewStore(r brazier.Registry) *Store {
 // A Store manages items from various backends.
 type Store struct {
 	Registry brazier.Registry
+
+	// Observer is notified after every operation, it is optional.
+	Observer Observer
+}
+
+// An Observer is notified of the outcome and the duration of Store operations.
+type Observer interface {
+	Observe(op string, d time.Duration, err error)
+}
+
+// Operations reported to the Observer.
+const (
+	OpCreateBucket = "create_bucket"
+	OpPut          = "put"
+	OpPutIf        = "put_if"
+	OpGet          = "get"
+	OpDelete       = "delete"
+	OpList         = "list"
+	OpTree         = "tree"
+	OpChildren     = "children"
+	OpWalk         = "walk"
+	OpBulkPut      = "bulk_put"
+)
+
+// registry returns the registry, adapted to support contexts if necessary.
+func (s *Store) registry() brazier.ContextRegistry {
+	return brazier.RegistryWithContext(s.Registry)
+}
+
+func (s *Store) observe(op string, start time.Time, err *error) {
+	if s.Observer != nil {
+		s.Observer.Observe(op, time.Since(start), *err)
+	}
 }
 
 // CreateBucket creates a bucket at the given path.
 func (s *Store) CreateBucket(rawPath string) error {
+	return s.CreateBucketContext(context.Background(), rawPath)
+}
+
+// CreateBucketContext is like CreateBucket but stops if the context is done.
+func (s *Store) CreateBucketContext(ctx context.Context, rawPath string) (err error) {
+	defer s.observe(OpCreateBucket, time.Now(), &err)
+
 	if len(rawPath) == 0 {
 		return ErrForbidden
 	}
@@ -34,102 +77,207 @@ func (s *Store) CreateBucket(rawPath string) error {
 		return ErrAlreadyExists
 	}
 
-	return s.Registry.Create(nodes...)
+	return s.registry().CreateContext(ctx, nodes...)
 }
 
-// Save the value at the given path.
+// Put saves the value at the given path.
 func (s *Store) Put(rawPath string, value []byte) (*brazier.Item, error) {
+	return s.PutContext(context.Background(), rawPath, value)
+}
+
+// PutContext is like Put but stops if the context is done.
+func (s *Store) PutContext(ctx context.Context, rawPath string, value []byte) (item *brazier.Item, err error) {
+	defer s.observe(OpPut, time.Now(), &err)
+
 	nodes, key := SplitPathKey(rawPath)
 	if key == "" {
 		return nil, ErrForbidden
 	}
 
-	bucket, err := GetBucketOrCreate(s.Registry, nodes...)
+	bucket, err := GetBucketOrCreateContext(ctx, s.Registry, nodes...)
 	if err != nil {
 		return nil, err
 	}
 
-	i, err := bucket.Save(key, value)
+	i, err := brazier.BucketWithContext(bucket).SaveContext(ctx, key, value)
 	bucket.Close()
 	return i, err
 }
 
+// PutIf saves the value at the given path if the item was last modified at updatedAt,
+// zero meaning that it must not exist. It returns ErrConflict otherwise, and ErrForbidden
+// if the backend doesn't implement brazier.ConditionalSaver.
+func (s *Store) PutIf(rawPath string, value []byte, updatedAt time.Time) (*brazier.Item, error) {
+	return s.PutIfContext(context.Background(), rawPath, value, updatedAt)
+}
+
+// PutIfContext is like PutIf but stops if the context is done.
+func (s *Store) PutIfContext(ctx context.Context, rawPath string, value []byte, updatedAt time.Time) (item *brazier.Item, err error) {
+	defer s.observe(OpPutIf, time.Now(), &err)
+
+	nodes, key := SplitPathKey(rawPath)
+	if key == "" {
+		return nil, ErrForbidden
+	}
+
+	bucket, err := GetBucketOrCreateContext(ctx, s.Registry, nodes...)
+	if err != nil {
+		return nil, err
+	}
+	defer bucket.Close()
+
+	saver, ok := bucket.(brazier.ConditionalSaver)
+	if !ok {
+		return nil, ErrForbidden
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return saver.SaveIf(key, value, updatedAt)
+}
+
 // Get returns the item saved at the given path.
 func (s *Store) Get(rawPath string) (*brazier.Item, error) {
+	return s.GetContext(context.Background(), rawPath)
+}
+
+// GetContext is like Get but stops if the context is done.
+func (s *Store) GetContext(ctx context.Context, rawPath string) (item *brazier.Item, err error) {
+	defer s.observe(OpGet, time.Now(), &err)
+
 	nodes, key := SplitPathKey(rawPath)
 	if key == "" {
 		return nil, ErrForbidden
 	}
 
-	bucket, err := s.Registry.Bucket(nodes...)
+	bucket, err := s.registry().BucketContext(ctx, nodes...)
 	if err != nil {
 		return nil, err
 	}
 
-	i, err := bucket.Get(key)
+	i, err := brazier.BucketWithContext(bucket).GetContext(ctx, key)
 	bucket.Close()
 	return i, err
 }
 
 // Delete the key from the bucket.
 func (s *Store) Delete(rawPath string) error {
+	return s.DeleteContext(context.Background(), rawPath)
+}
+
+// DeleteContext is like Delete but stops if the context is done.
+func (s *Store) DeleteContext(ctx context.Context, rawPath string) (err error) {
+	defer s.observe(OpDelete, time.Now(), &err)
+
 	nodes, key := SplitPathKey(rawPath)
 	if key == "" {
 		return ErrForbidden
 	}
 
-	bucket, err := s.Registry.Bucket(nodes...)
+	bucket, err := s.registry().BucketContext(ctx, nodes...)
 	if err != nil {
 		return err
 	}
 
-	err = bucket.Delete(key)
+	err = brazier.BucketWithContext(bucket).DeleteContext(ctx, key)
 	bucket.Close()
 	return err
 }
 
 // List the content of the bucket.
 func (s *Store) List(rawPath string, page int, perPage int) ([]brazier.Item, error) {
+	return s.ListContext(context.Background(), rawPath, page, perPage)
+}
+
+// ListContext is like List but stops if the context is done.
+func (s *Store) ListContext(ctx context.Context, rawPath string, page int, perPage int) (items []brazier.Item, err error) {
+	defer s.observe(OpList, time.Now(), &err)
+
 	nodes, key := SplitPathKey(rawPath)
 	if key != "" {
 		return nil, ErrForbidden
 	}
 
-	bucket, err := s.Registry.Bucket(nodes...)
+	bucket, err := s.registry().BucketContext(ctx, nodes...)
 	if err != nil {
 		return nil, err
 	}
 
-	list, err := bucket.Page(page, perPage)
+	list, err := brazier.BucketWithContext(bucket).PageContext(ctx, page, perPage)
 	bucket.Close()
 	return list, err
 }
 
+// Children returns the buckets under the bucket, as items with a key ending with a '/'.
+// The buckets under each of them are returned in their Children field.
+func (s *Store) Children(rawPath string) ([]brazier.Item, error) {
+	return s.ChildrenContext(context.Background(), rawPath)
+}
+
+// ChildrenContext is like Children but stops if the context is done.
+func (s *Store) ChildrenContext(ctx context.Context, rawPath string) (items []brazier.Item, err error) {
+	defer s.observe(OpChildren, time.Now(), &err)
+
+	nodes, key := SplitPathKey(rawPath)
+	if key != "" {
+		return nil, ErrForbidden
+	}
+
+	buckets, err := s.registry().ChildrenContext(ctx, nodes...)
+	if err != nil {
+		return nil, err
+	}
+
+	return bucketItems(buckets), nil
+}
+
+func bucketItems(buckets []brazier.Item) []brazier.Item {
+	items := make([]brazier.Item, len(buckets))
+	for i := range buckets {
+		items[i].Key = buckets[i].Key + "/"
+		if len(buckets[i].Children) > 0 {
+			items[i].Children = bucketItems(buckets[i].Children)
+		}
+	}
+
+	return items
+}
+
 // Tree returns the content of the bucket and of all its children.
 func (s *Store) Tree(rawPath string) ([]brazier.Item, error) {
+	return s.TreeContext(context.Background(), rawPath)
+}
+
+// TreeContext is like Tree but stops if the context is done,
+// the context being checked before reading each bucket.
+func (s *Store) TreeContext(ctx context.Context, rawPath string) (items []brazier.Item, err error) {
+	defer s.observe(OpTree, time.Now(), &err)
+
 	nodes, key := SplitPathKey(rawPath)
 	if key != "" {
 		return nil, ErrForbidden
 	}
 
-	buckets, err := s.Registry.Children(nodes...)
+	buckets, err := s.registry().ChildrenContext(ctx, nodes...)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.tree(buckets, nodes...)
+	return s.tree(ctx, buckets, nodes...)
 }
 
-func (s *Store) tree(buckets []brazier.Item, nodes ...string) ([]brazier.Item, error) {
+func (s *Store) tree(ctx context.Context, buckets []brazier.Item, nodes ...string) ([]brazier.Item, error) {
 	var items []brazier.Item
 	var err error
 
-	bucket, err := s.Registry.Bucket(nodes...)
+	bucket, err := s.registry().BucketContext(ctx, nodes...)
 	if err != nil {
 		return nil, err
 	}
 
-	items, err = bucket.Page(1, -1)
+	items, err = brazier.BucketWithContext(bucket).PageContext(ctx, 1, -1)
 	bucket.Close()
 	if err != nil {
 		return nil, err
@@ -140,7 +288,7 @@ func (s *Store) tree(buckets []brazier.Item, nodes ...string) ([]brazier.Item, e
 			Key: b.Key + "/",
 		}
 
-		i.Children, err = s.tree(b.Children, append(nodes, b.Key)...)
+		i.Children, err = s.tree(ctx, b.Children, append(nodes, b.Key)...)
 		if err != nil {
 			return nil, err
 		}
@@ -151,6 +299,149 @@ func (s *Store) tree(buckets []brazier.Item, nodes ...string) ([]brazier.Item, e
 	return items, nil
 }
 
+// A WalkFunc is called by Walk for every visited node.
+// The path is the full path of the node and the depth is 0 for the content
+// of the walked bucket. Buckets have a key ending with a '/' and no data,
+// they are visited before their content.
+type WalkFunc func(path string, depth int, item *brazier.Item) error
+
+// SkipBucket is returned by a WalkFunc visiting a bucket to skip its content.
+// It is not returned by Walk and has no effect when returned for an item.
+var SkipBucket = errors.New("skip this bucket")
+
+// LimitDepth returns a WalkFunc calling fn for the nodes of the given number of levels,
+// the content of the buckets beyond being skipped. A zero depth means no limit.
+func LimitDepth(maxDepth int, fn WalkFunc) WalkFunc {
+	if maxDepth <= 0 {
+		return fn
+	}
+
+	return func(path string, depth int, item *brazier.Item) error {
+		err := fn(path, depth, item)
+		if err == nil && depth >= maxDepth-1 {
+			return SkipBucket
+		}
+		return err
+	}
+}
+
+// Walk visits the items of the bucket, one at a time, then, if recursive,
+// the children buckets and their content, in the same order as Tree.
+// Items are read using an iterator if the bucket implements brazier.Iterator.
+// Walk stops at the first error returned by fn and returns it.
+func (s *Store) Walk(rawPath string, recursive bool, fn WalkFunc) error {
+	return s.WalkContext(context.Background(), rawPath, recursive, fn)
+}
+
+// WalkContext is like Walk but stops if the context is done,
+// the context being checked between every visited node.
+func (s *Store) WalkContext(ctx context.Context, rawPath string, recursive bool, fn WalkFunc) (err error) {
+	defer s.observe(OpWalk, time.Now(), &err)
+
+	nodes, key := SplitPathKey(rawPath)
+	if key != "" {
+		return ErrForbidden
+	}
+
+	var buckets []brazier.Item
+	if recursive {
+		buckets, err = s.registry().ChildrenContext(ctx, nodes...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.walk(ctx, buckets, 0, fn, nodes...)
+}
+
+func (s *Store) walk(ctx context.Context, buckets []brazier.Item, depth int, fn WalkFunc, nodes ...string) error {
+	bucket, err := s.registry().BucketContext(ctx, nodes...)
+	if err != nil {
+		return err
+	}
+
+	prefix := path.Join(nodes...)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	err = brazier.BucketWithContext(bucket).IterateContext(ctx, func(i *brazier.Item) error {
+		err := fn(prefix+i.Key, depth, i)
+		if err == SkipBucket {
+			return nil
+		}
+		return err
+	})
+	bucket.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		i := brazier.Item{
+			Key: b.Key + "/",
+		}
+
+		err = fn(prefix+i.Key, depth, &i)
+		if err == SkipBucket {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = s.walk(ctx, b.Children, depth+1, fn, append(nodes, b.Key)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A Checker verifies it is able to serve requests.
+type Checker interface {
+	Check() error
+}
+
+// Check makes sure the registry and the backend are able to serve requests.
+// Registries that don't implement the Checker interface are considered healthy.
+func (s *Store) Check() error {
+	if c, ok := s.Registry.(Checker); ok {
+		return c.Check()
+	}
+
+	return nil
+}
+
+// A Counter counts the buckets and the items without reading them.
+type Counter interface {
+	Count() (buckets, items int, err error)
+}
+
+// Count returns the number of buckets, the root bucket excluded, and the number of items.
+// The store is walked if the registry doesn't implement the Counter interface.
+func (s *Store) Count(ctx context.Context) (buckets, items int, err error) {
+	if c, ok := s.Registry.(Counter); ok {
+		return c.Count()
+	}
+
+	err = s.WalkContext(ctx, "/", true, func(path string, depth int, item *brazier.Item) error {
+		if strings.HasSuffix(item.Key, "/") {
+			buckets++
+		} else {
+			items++
+		}
+		return nil
+	})
+
+	return buckets, items, err
+}
+
 // Close the registry and the backend connection.
 func (s *Store) Close() error {
 	return s.Registry.Close()
@@ -158,16 +449,23 @@ func (s *Store) Close() error {
 
 // GetBucketOrCreate returns an existing bucket or creates it if it doesn't exist.
 func GetBucketOrCreate(r brazier.Registry, nodes ...string) (brazier.Bucket, error) {
-	bucket, err := r.Bucket(nodes...)
+	return GetBucketOrCreateContext(context.Background(), r, nodes...)
+}
+
+// GetBucketOrCreateContext is like GetBucketOrCreate but stops if the context is done.
+func GetBucketOrCreateContext(ctx context.Context, r brazier.Registry, nodes ...string) (brazier.Bucket, error) {
+	cr := brazier.RegistryWithContext(r)
+
+	bucket, err := cr.BucketContext(ctx, nodes...)
 	if err != nil {
 		if err != ErrNotFound {
 			return nil, err
 		}
-		err = r.Create(nodes...)
+		err = cr.CreateContext(ctx, nodes...)
 		if err != nil {
 			return nil, err
 		}
-		bucket, err = r.Bucket(nodes...)
+		bucket, err = cr.BucketContext(ctx, nodes...)
 		if err != nil {
 			return nil, err
 		}
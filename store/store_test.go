@@ -7,12 +7,14 @@ import (
 	"path"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/asdine/brazier"
 	"github.com/asdine/brazier/mock"
 	"github.com/asdine/brazier/store"
 	"github.com/asdine/brazier/store/boltdb"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func TestSplitPathKey(t *testing.T) {
@@ -129,6 +131,37 @@ func testStore(t *testing.T, backendType string) {
 		require.Equal(t, []byte("Value"), item.Data)
 	})
 
+	t.Run("PutIf", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		_, err := s.PutIf("/a/", []byte("Value"), time.Time{})
+		require.Equal(t, store.ErrForbidden, err)
+
+		// a missing item is expected with a zero time
+		item, err := s.PutIf("/a/b", []byte("Value"), time.Time{})
+		require.NoError(t, err)
+		require.False(t, item.UpdatedAt.IsZero())
+
+		_, err = s.PutIf("/a/b", []byte("Other"), time.Time{})
+		require.Equal(t, store.ErrConflict, err)
+
+		item, err = s.Get("/a/b")
+		require.NoError(t, err)
+		updatedAt := item.UpdatedAt
+
+		_, err = s.PutIf("/a/b", []byte("Other"), updatedAt)
+		require.NoError(t, err)
+
+		_, err = s.PutIf("/a/b", []byte("Again"), updatedAt)
+		require.Equal(t, store.ErrConflict, err)
+
+		item, err = s.Get("/a/b")
+		require.NoError(t, err)
+		require.Equal(t, []byte("Other"), item.Data)
+	})
+
 	t.Run("Get", func(t *testing.T) {
 		r, cleanup := getRegistryHelper(t, backendType)
 		defer cleanup()
@@ -206,6 +239,237 @@ func testStore(t *testing.T, backendType string) {
 		require.Equal(t, store.ErrForbidden, err)
 	})
 
+	t.Run("Children", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		_, err := s.Put("/a/b/c/k", []byte("Value"))
+		require.NoError(t, err)
+		_, err = s.Put("/a/d/k", []byte("Value"))
+		require.NoError(t, err)
+
+		items, err := s.Children("/a/")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "b/", items[0].Key)
+		require.Len(t, items[0].Children, 1)
+		require.Equal(t, "c/", items[0].Children[0].Key)
+		require.Nil(t, items[0].Children[0].Children)
+		require.Equal(t, "d/", items[1].Key)
+
+		items, err = s.Children("/a/d/")
+		require.NoError(t, err)
+		require.Len(t, items, 0)
+
+		_, err = s.Children("/z/")
+		require.Equal(t, store.ErrNotFound, err)
+
+		_, err = s.Children("/a")
+		require.Equal(t, store.ErrForbidden, err)
+	})
+
+	t.Run("Walk", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 3; j++ {
+				_, err := s.Put(fmt.Sprintf("/a/b%d/k%d", i, j), []byte("Value"+strconv.Itoa(j)))
+				require.NoError(t, err)
+			}
+		}
+		_, err := s.Put("/a/k", []byte("Value"))
+		require.NoError(t, err)
+
+		var visited []string
+		walk := func(path string, depth int, item *brazier.Item) error {
+			visited = append(visited, fmt.Sprintf("%d %s %s", depth, path, item.Data))
+			return nil
+		}
+
+		err = s.Walk("/a/", true, walk)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"0 a/k Value",
+			"0 a/b0/ ",
+			"1 a/b0/k0 Value0",
+			"1 a/b0/k1 Value1",
+			"1 a/b0/k2 Value2",
+			"0 a/b1/ ",
+			"1 a/b1/k0 Value0",
+			"1 a/b1/k1 Value1",
+			"1 a/b1/k2 Value2",
+		}, visited)
+
+		visited = nil
+		err = s.Walk("/a/b1/", false, walk)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"0 a/b1/k0 Value0",
+			"0 a/b1/k1 Value1",
+			"0 a/b1/k2 Value2",
+		}, visited)
+
+		visited = nil
+		err = s.Walk("/a/", true, func(path string, depth int, item *brazier.Item) error {
+			walk(path, depth, item)
+			if path == "a/b0/" || path == "a/k" {
+				return store.SkipBucket
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"0 a/k Value",
+			"0 a/b0/ ",
+			"0 a/b1/ ",
+			"1 a/b1/k0 Value0",
+			"1 a/b1/k1 Value1",
+			"1 a/b1/k2 Value2",
+		}, visited)
+
+		visited = nil
+		err = s.Walk("/a/", true, store.LimitDepth(1, walk))
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"0 a/k Value",
+			"0 a/b0/ ",
+			"0 a/b1/ ",
+		}, visited)
+
+		visited = nil
+		err = s.Walk("/", true, walk)
+		require.NoError(t, err)
+		require.Len(t, visited, 10)
+		require.Equal(t, "0 a/ ", visited[0])
+
+		err = s.Walk("/z/", true, walk)
+		require.Equal(t, store.ErrNotFound, err)
+
+		err = s.Walk("/a", true, walk)
+		require.Equal(t, store.ErrForbidden, err)
+	})
+
+	t.Run("BulkWriter", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		_, err := s.Put("/a/b/c", []byte("Value"))
+		require.NoError(t, err)
+
+		w := s.NewBulkWriter(context.Background(), 2)
+		for i := 0; i < 5; i++ {
+			err = w.Put(fmt.Sprintf("/a/k%d", i), []byte("Value"+strconv.Itoa(i)))
+			require.NoError(t, err)
+		}
+		err = w.Put("/a/", []byte("Value"))
+		require.NoError(t, err)
+		err = w.Put("", []byte("Value"))
+		require.NoError(t, err)
+		err = w.Put("/d/e", []byte("Value"))
+		require.NoError(t, err)
+
+		res, err := w.Close()
+		require.NoError(t, err)
+		require.Equal(t, 8, res.Received)
+		require.Equal(t, 6, res.Saved)
+		require.Len(t, res.Failures, 2)
+		require.Equal(t, "/a/", res.Failures[0].Path)
+		require.Equal(t, store.ErrForbidden, res.Failures[0].Err)
+		require.Equal(t, "", res.Failures[1].Path)
+
+		items, err := s.List("/a/", 1, -1)
+		require.NoError(t, err)
+		require.Len(t, items, 5)
+		require.Equal(t, "k4", items[4].Key)
+		item, err := s.Get("/d/e")
+		require.NoError(t, err)
+		require.Equal(t, []byte("Value"), item.Data)
+
+		w = s.NewBulkWriter(context.Background(), 0)
+		err = w.Put("/aborted/k", []byte("Value"))
+		require.NoError(t, err)
+		w.Abort()
+		res, err = w.Close()
+		require.NoError(t, err)
+		require.Equal(t, 0, res.Saved)
+		_, err = s.Get("/aborted/k")
+		require.Equal(t, store.ErrNotFound, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w = s.NewBulkWriter(ctx, 0)
+		err = w.Put("/a/k", []byte("Value"))
+		require.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		for _, p := range []string{"/a/k1", "/a/b/k2", "/a/b/k3"} {
+			_, err := s.Put(p, []byte("Value"))
+			require.NoError(t, err)
+		}
+		err := s.CreateBucket("/c/d/")
+		require.NoError(t, err)
+
+		buckets, items, err := s.Count(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 4, buckets)
+		require.Equal(t, 3, items)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		for i := 0; i < 3; i++ {
+			_, err := s.Put(fmt.Sprintf("/a/b/k%d", i), []byte("Value"))
+			require.NoError(t, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := s.PutContext(ctx, "/a/k", []byte("Value"))
+		require.Equal(t, context.Canceled, err)
+		_, err = s.GetContext(ctx, "/a/b/k0")
+		require.Equal(t, context.Canceled, err)
+		_, err = s.TreeContext(ctx, "/a/")
+		require.Equal(t, context.Canceled, err)
+		err = s.DeleteContext(ctx, "/a/b/k0")
+		require.Equal(t, context.Canceled, err)
+
+		// cancelled during the walk
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		var visited int
+		err = s.WalkContext(ctx, "/a/", true, func(path string, depth int, item *brazier.Item) error {
+			visited++
+			if visited == 2 {
+				cancel()
+			}
+			return nil
+		})
+		require.Equal(t, context.Canceled, err)
+		require.Equal(t, 2, visited)
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		r, cleanup := getRegistryHelper(t, backendType)
+		defer cleanup()
+		s := store.NewStore(r)
+
+		err := s.Check()
+		require.NoError(t, err)
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		r, cleanup := getRegistryHelper(t, backendType)
 		defer cleanup()
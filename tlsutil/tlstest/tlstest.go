@@ -0,0 +1,111 @@
+// Package tlstest generates certificates for testing purposes.
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// NewCA creates a self-signed certificate authority.
+// The certificate and every certificate issued by the authority are written to dir.
+func NewCA(dir string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Brazier Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	ca := CA{
+		CertFile: filepath.Join(dir, "ca.pem"),
+		dir:      dir,
+		cert:     cert,
+		key:      key,
+		serial:   1,
+	}
+
+	return &ca, writePEM(ca.CertFile, "CERTIFICATE", der)
+}
+
+// A CA is a certificate authority used to issue certificates.
+type CA struct {
+	CertFile string
+
+	dir    string
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+// Issue creates a certificate signed by the authority, valid for localhost
+// and usable by both clients and servers. It returns the paths of the
+// certificate and of its private key.
+func (c *CA) Issue(name string) (certFile string, keyFile string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	c.serial++
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(c.serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, c.cert, &key.PublicKey, c.key)
+	if err != nil {
+		return "", "", err
+	}
+
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	certFile = filepath.Join(c.dir, name+".pem")
+	keyFile = filepath.Join(c.dir, name+"-key.pem")
+
+	err = writePEM(certFile, "CERTIFICATE", der)
+	if err != nil {
+		return "", "", err
+	}
+
+	return certFile, keyFile, writePEM(keyFile, "EC PRIVATE KEY", rawKey)
+}
+
+func writePEM(path string, blockType string, data []byte) error {
+	return ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600)
+}
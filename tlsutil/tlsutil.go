@@ -0,0 +1,192 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// NewKeyPair loads a certificate and its private key from the given files.
+func NewKeyPair(certFile, keyFile string) (*KeyPair, error) {
+	k := KeyPair{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+
+	err := k.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// A KeyPair is a certificate and its private key loaded from disk.
+// It can be reloaded at runtime without restarting the servers using it.
+type KeyPair struct {
+	CertFile string
+	KeyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// Reload reads the certificate and the private key from disk.
+// The current certificate is kept if the files are invalid.
+func (k *KeyPair) Reload() error {
+	cert, err := tls.LoadX509KeyPair(k.CertFile, k.KeyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load key pair %s", k.CertFile)
+	}
+
+	k.mu.Lock()
+	k.cert = &cert
+	k.mu.Unlock()
+	return nil
+}
+
+// GetCertificate returns the current certificate.
+// It is meant to be used as tls.Config.GetCertificate.
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.cert, nil
+}
+
+// ServerConfig returns a TLS configuration for servers using the given key pair.
+// If clientCAFile is not empty, clients must present a certificate
+// signed by one of the authorities it contains.
+func ServerConfig(kp *KeyPair, clientCAFile string) (*tls.Config, error) {
+	cfg := tls.Config{
+		GetCertificate: kp.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := CertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &cfg, nil
+}
+
+// NewServerTLS loads the key pair of a server and the authorities verifying the client
+// certificates, if clientCAFile is not empty.
+func NewServerTLS(certFile, keyFile, clientCAFile string) (*ServerTLS, error) {
+	s := ServerTLS{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: clientCAFile,
+	}
+
+	err := s.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// A ServerTLS is the TLS configuration of a server loaded from disk: its key pair and the
+// authorities verifying the client certificates. Both can be reloaded at runtime without
+// restarting the servers using it.
+type ServerTLS struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+
+	mu  sync.RWMutex
+	cfg *tls.Config
+}
+
+// Reload reads the key pair and the client authorities from disk.
+// The current configuration is kept if the files are invalid.
+func (s *ServerTLS) Reload() error {
+	kp, err := NewKeyPair(s.CertFile, s.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := ServerConfig(kp, s.ClientCAFile)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+	return nil
+}
+
+// Config returns a TLS configuration whose handshakes use the configuration loaded last.
+// nextProtos are the protocols negotiated with ALPN, they must be given here because
+// the configuration used by a handshake replaces the returned one entirely.
+func (s *ServerTLS) Config(nextProtos ...string) *tls.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		ClientAuth: s.cfg.ClientAuth,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+
+			cfg := s.cfg.Clone()
+			cfg.NextProtos = nextProtos
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig returns a TLS configuration for clients.
+// The server certificate is verified using the authorities contained in caFile,
+// or using the system pool if caFile is empty. certFile and keyFile are required
+// to connect to servers verifying client certificates.
+func ClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		pool, err := CertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load key pair %s", certFile)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return &cfg, nil
+}
+
+// CertPool returns a pool containing the PEM encoded certificates of the given file.
+func CertPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read certificates from %s", path)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no valid certificate found in %s", path)
+	}
+
+	return pool, nil
+}
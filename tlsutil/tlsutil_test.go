@@ -0,0 +1,221 @@
+package tlsutil_test
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/brazier/tlsutil"
+	"github.com/asdine/brazier/tlsutil/tlstest"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareCA(t *testing.T) (*tlstest.CA, func()) {
+	dir, err := ioutil.TempDir(os.TempDir(), "brazier")
+	require.NoError(t, err)
+
+	ca, err := tlstest.NewCA(dir)
+	require.NoError(t, err)
+
+	return ca, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func handshake(t *testing.T, server, client *tls.Config) error {
+	l, err := tls.Listen("tcp", "127.0.0.1:", server)
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		done <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), client)
+	if err == nil {
+		conn.Close()
+	}
+
+	serverErr := <-done
+	if err != nil {
+		return err
+	}
+	return serverErr
+}
+
+func TestKeyPair(t *testing.T) {
+	ca, cleanup := prepareCA(t)
+	defer cleanup()
+
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+
+	_, err = tlsutil.NewKeyPair(certFile, "some/file")
+	require.Error(t, err)
+
+	kp, err := tlsutil.NewKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	before, err := kp.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotNil(t, before)
+
+	_, _, err = ca.Issue("server")
+	require.NoError(t, err)
+
+	err = kp.Reload()
+	require.NoError(t, err)
+
+	after, err := kp.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotEqual(t, before.Certificate[0], after.Certificate[0])
+
+	// invalid files must not replace the current certificate
+	err = ioutil.WriteFile(certFile, []byte("garbage"), 0600)
+	require.NoError(t, err)
+	err = kp.Reload()
+	require.Error(t, err)
+	current, err := kp.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, after, current)
+}
+
+func TestServerConfig(t *testing.T) {
+	ca, cleanup := prepareCA(t)
+	defer cleanup()
+
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+	clientCertFile, clientKeyFile, err := ca.Issue("client")
+	require.NoError(t, err)
+
+	kp, err := tlsutil.NewKeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	t.Run("TLS", func(t *testing.T) {
+		server, err := tlsutil.ServerConfig(kp, "")
+		require.NoError(t, err)
+
+		client, err := tlsutil.ClientConfig(ca.CertFile, "", "")
+		require.NoError(t, err)
+		client.ServerName = "localhost"
+
+		err = handshake(t, server, client)
+		require.NoError(t, err)
+
+		// unknown authority
+		client, err = tlsutil.ClientConfig("", "", "")
+		require.NoError(t, err)
+		client.ServerName = "localhost"
+
+		err = handshake(t, server, client)
+		require.Error(t, err)
+	})
+
+	t.Run("MutualTLS", func(t *testing.T) {
+		_, err := tlsutil.ServerConfig(kp, "some/file")
+		require.Error(t, err)
+
+		server, err := tlsutil.ServerConfig(kp, ca.CertFile)
+		require.NoError(t, err)
+
+		client, err := tlsutil.ClientConfig(ca.CertFile, clientCertFile, clientKeyFile)
+		require.NoError(t, err)
+		client.ServerName = "localhost"
+
+		err = handshake(t, server, client)
+		require.NoError(t, err)
+
+		// missing client certificate
+		client, err = tlsutil.ClientConfig(ca.CertFile, "", "")
+		require.NoError(t, err)
+		client.ServerName = "localhost"
+
+		err = handshake(t, server, client)
+		require.Error(t, err)
+	})
+}
+
+func TestServerTLS(t *testing.T) {
+	ca, cleanup := prepareCA(t)
+	defer cleanup()
+
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+	clientCertFile, clientKeyFile, err := ca.Issue("client")
+	require.NoError(t, err)
+
+	_, err = tlsutil.NewServerTLS(certFile, keyFile, "some/file")
+	require.Error(t, err)
+
+	s, err := tlsutil.NewServerTLS(certFile, keyFile, ca.CertFile)
+	require.NoError(t, err)
+	server := s.Config("h2")
+	require.Equal(t, tls.RequireAndVerifyClientCert, server.ClientAuth)
+
+	client, err := tlsutil.ClientConfig(ca.CertFile, clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+	client.ServerName = "localhost"
+	client.NextProtos = []string{"h2"}
+
+	err = handshake(t, server, client)
+	require.NoError(t, err)
+
+	// a new authority replaces the previous one, the existing configuration uses it
+	ca, err = tlstest.NewCA(filepath.Dir(ca.CertFile))
+	require.NoError(t, err)
+	certFile, keyFile, err = ca.Issue("server")
+	require.NoError(t, err)
+	err = s.Reload()
+	require.NoError(t, err)
+
+	err = handshake(t, server, client)
+	require.Error(t, err)
+
+	clientCertFile, clientKeyFile, err = ca.Issue("client")
+	require.NoError(t, err)
+	client, err = tlsutil.ClientConfig(ca.CertFile, clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+	client.ServerName = "localhost"
+	client.NextProtos = []string{"h2"}
+
+	err = handshake(t, server, client)
+	require.NoError(t, err)
+
+	// invalid files must not replace the current configuration
+	err = ioutil.WriteFile(ca.CertFile, []byte("garbage"), 0600)
+	require.NoError(t, err)
+	err = s.Reload()
+	require.Error(t, err)
+
+	err = handshake(t, server, client)
+	require.NoError(t, err)
+}
+
+func TestCertPool(t *testing.T) {
+	ca, cleanup := prepareCA(t)
+	defer cleanup()
+
+	_, err := tlsutil.CertPool("some/file")
+	require.Error(t, err)
+
+	pool, err := tlsutil.CertPool(ca.CertFile)
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+
+	certFile, keyFile, err := ca.Issue("server")
+	require.NoError(t, err)
+	_, err = tlsutil.CertPool(keyFile)
+	require.Error(t, err)
+	_, err = tlsutil.CertPool(certFile)
+	require.NoError(t, err)
+}
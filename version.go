@@ -1,3 +1,4 @@
 package brazier
 
-const version = "0.3.0"
+// Version of Brazier.
+const Version = "0.3.0"